@@ -0,0 +1,104 @@
+// Токены хранятся в localStorage, access-токен уходит в заголовке Authorization
+const tokenKeys = { access: 'accessToken', refresh: 'refreshToken' };
+let refreshing = null;
+
+function saveTokens(pair) {
+  localStorage.setItem(tokenKeys.access, pair.access_token);
+  localStorage.setItem(tokenKeys.refresh, pair.refresh_token);
+}
+
+function clearTokens() {
+  localStorage.removeItem(tokenKeys.access);
+  localStorage.removeItem(tokenKeys.refresh);
+}
+
+function showLogin(message) {
+  clearTokens();
+  document.getElementById('loginError').textContent = message || '';
+  document.getElementById('loginSection').classList.remove('hidden');
+  document.getElementById('app').classList.add('hidden');
+}
+
+function hideLogin() {
+  document.getElementById('loginSection').classList.add('hidden');
+  document.getElementById('app').classList.remove('hidden');
+}
+
+// Одно обновление на все запросы, получившие 401 одновременно:
+// старый refresh-токен после обновления отзывается
+function refreshTokens() {
+  const token = localStorage.getItem(tokenKeys.refresh);
+  if (!token) return Promise.resolve(false);
+  if (!refreshing) {
+    refreshing = fetch(`${host}/auth/refresh`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ refresh_token: token })
+    })
+      .then(res => res.json())
+      .then(resp => {
+        if (!resp.success) return false;
+        saveTokens(resp.data);
+        return true;
+      })
+      .catch(() => false)
+      .finally(() => { refreshing = null; });
+  }
+  return refreshing;
+}
+
+// fetch с access-токеном; на 401 токены обновляются и запрос повторяется один раз
+async function apiFetch(url, options = {}) {
+  const send = () => fetch(url, {
+    ...options,
+    headers: { ...options.headers, Authorization: `Bearer ${localStorage.getItem(tokenKeys.access)}` }
+  });
+
+  let res = await send();
+  if (res.status !== 401) return res;
+  if (await refreshTokens()) {
+    res = await send();
+    if (res.status !== 401) return res;
+  }
+  showLogin('Сессия истекла, войдите снова');
+  return res;
+}
+
+// Фото отдаётся только с токеном, поэтому <img> получает его как blob
+function loadPhoto(img, url) {
+  apiFetch(url)
+    .then(res => {
+      if (!res.ok) throw new Error(`Ошибка ${res.status}`);
+      return res.blob();
+    })
+    .then(blob => {
+      img.onload = () => URL.revokeObjectURL(img.src);
+      img.src = URL.createObjectURL(blob);
+    })
+    .catch(() => { img.src = 'no-image.png'; });
+}
+
+function login(loginName, password) {
+  return fetch(`${host}/auth/login`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ login: loginName, password })
+  })
+    .then(res => res.json())
+    .then(resp => {
+      if (!resp.success) throw new Error(resp.error || 'Ошибка входа');
+      saveTokens(resp.data);
+    });
+}
+
+function logout() {
+  const token = localStorage.getItem(tokenKeys.refresh);
+  if (token) {
+    fetch(`${host}/auth/logout`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ refresh_token: token })
+    }).catch(() => {});
+  }
+  showLogin();
+}
@@ -14,7 +14,7 @@ function setupDocDownload(btnClass, host) {
 
       const deptId = btn.dataset.deptId;
       try {
-        const res = await fetch(host + deptId + '/document');
+        const res = await apiFetch(host + deptId + '/document');
         const contentType = res.headers.get('Content-Type') || '';
 
         if (res.ok && contentType.includes('application/json')) {
@@ -49,7 +49,7 @@ function setupDocDownload(btnClass, host) {
 }
 
 function loadDepartments(callback) {
-  fetch(`${host}/departments`)
+  apiFetch(`${host}/departments`)
     .then(res => res.json())
     .then(data => {
       if (!data.success) throw new Error(data.error || 'Ошибка загрузки отделов');
@@ -74,7 +74,7 @@ function loadDepartments(callback) {
 
 function loadEmployeesByDept() {
   const deptId = document.getElementById('deptSelect').value;
-  fetch(`${host}/employeesByDepart/${deptId}`)
+  apiFetch(`${host}/employeesByDepart/${deptId}`)
     .then(res => res.json())
     .then(data => {
       const tbody = document.getElementById('employeeList');
@@ -90,18 +90,19 @@ function loadEmployeesByDept() {
       }
       data.data.forEach(e => {
         const tr = document.createElement('tr');
-        const imgCell = `<td><img src="${host}/employees/${e.id}/photo" alt="" width="50" onerror="this.src='no-image.png'"></td>`;
+        const imgCell = `<td><img alt="" width="50"></td>`;
         tr.innerHTML = `
           ${imgCell}
           <td>${e.name}</td>
           <td>${e.status}</td>
-          <td>${e.salary}₽</td>
+          <td>${e.salary ?? e.salary_range}₽</td>
           <td>
             <div class="tableBtnDiv">
               <button class="table-btn delete" onclick="deleteEmployee(${e.id})">Удалить</button>
               <button class="table-btn edit" onclick="prepChangeForm(${e.id})">Изменить</button>
             </div>
           </td>`;
+        loadPhoto(tr.querySelector('img'), `${host}/employees/${e.id}/photo?size=thumb`);
         tbody.append(tr);
       });
     })
@@ -111,7 +112,7 @@ function loadEmployeesByDept() {
 }
 
 function deleteEmployee(id) {
-  fetch(`${host}/employees/${id}`, { method: 'DELETE' })
+  apiFetch(`${host}/employees/${id}`, { method: 'DELETE' })
     .then(() => loadEmployeesByDept())
     .catch(() => {
       document.getElementById('employeeList').innerHTML = `<tr><td colspan="5" class="error">Ошибка удаления сотудника #${id}</td></tr>`;
@@ -129,7 +130,7 @@ function loadDepartmentList() {
         <span id="boss${d.id}">${d.boss_id.toString().padStart(3, '\u00A0')} : ${d.boss_name}</span>
         <select id="bossSelect${d.id}" class="hidden"></select>
       </td>
-      <td>${d.total_salary} ₽</td>
+      <td>${d.total_salary ?? d.total_salary_range} ₽</td>
       <td>${d.size}</td>
       <td>
         <div class="tableBtnDiv2">
@@ -143,7 +144,7 @@ function loadDepartmentList() {
     tbody.append(tr);
 
     const select = tr.querySelector(`#bossSelect${d.id}`);
-    fetch(`${host}/employeesByDepart/${d.id}`)
+    apiFetch(`${host}/employeesByDepart/${d.id}`)
       .then(res => res.json())
       .then(data => {
         if (data.success) data.data.forEach(emp => {
@@ -174,7 +175,7 @@ function loadDepartmentList() {
 
     tr.querySelector(`#saveBoss${d.id}`).addEventListener('click', () => {
       const newBoss = parseInt(select.value, 10);
-      fetch(`${host}/departments/${d.id}`, {
+      apiFetch(`${host}/departments/${d.id}`, {
       method: 'PUT',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({ boss_id: newBoss })
@@ -204,7 +205,7 @@ function prepChangeForm(id) {
   const imageBtnText = document.getElementById('customFileBtn');
   document.getElementById('addBtn').textContent = "Изменить";
 
-  fetch(`${host}/employees/${id}`)
+  apiFetch(`${host}/employees/${id}`)
     .then(res => res.json())
     .then(data => {
       if (!data.success) {
@@ -262,7 +263,7 @@ function addEmployee() {
   formData.append('dept_id', dept_id);
   if (image) formData.append('image', image);
 
-  fetch(`${host}/employees`, {
+  apiFetch(`${host}/employees`, {
     method: 'POST',
     body: formData
   })
@@ -306,7 +307,7 @@ function updateEmployee(id) {
   salaryInput.disabled = true;
   imageInput.disabled = true;
 
-  fetch(`${host}/employees/${id}`, {
+  apiFetch(`${host}/employees/${id}`, {
     method: 'PUT',
     body: formData
   })
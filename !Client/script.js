@@ -37,6 +37,13 @@ function clearAddForm() {
   editEmployeeId = null;
 }
 
+function startApp() {
+  loadDepartments(() => {
+    showSection('employees');
+    loadEmployeesByDept();
+  });
+}
+
 // Инициализация
 document.addEventListener('DOMContentLoaded', () => {
   document.addEventListener('paste', async (event) => {
@@ -84,7 +91,7 @@ document.addEventListener('DOMContentLoaded', () => {
   });
 
   // Вешаем обработчики на кнопки навигации
-  document.querySelectorAll('.nav button').forEach(btn => {
+  document.querySelectorAll('.nav button[data-action]').forEach(btn => {
     btn.addEventListener('click', () => {
       const action = btn.dataset.action;
       showSection(action);
@@ -115,11 +122,27 @@ document.addEventListener('DOMContentLoaded', () => {
     clearAddForm();
   });
 
-  // Стартовая загрузка
-  loadDepartments(() => {
-    showSection('employees');
-    loadEmployeesByDept();
+  // Вход и выход
+  document.getElementById('loginForm').addEventListener('submit', function(e) {
+    e.preventDefault();
+    const errorDiv = document.getElementById('loginError');
+    errorDiv.textContent = '';
+    login(document.getElementById('loginName').value, document.getElementById('loginPassword').value)
+      .then(() => {
+        this.reset();
+        hideLogin();
+        startApp();
+      })
+      .catch(err => { errorDiv.textContent = err.message; });
   });
+  document.getElementById('logoutBtn').addEventListener('click', logout);
+
+  // Стартовая загрузка
+  if (localStorage.getItem(tokenKeys.refresh)) {
+    startApp();
+  } else {
+    showLogin();
+  }
 
   document.getElementById('customFileBtn').onclick = function() {
     document.getElementById('fileName').classList.remove('warning');
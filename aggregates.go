@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// aggregateStrategy кто поддерживает ОТД_СОТР_ЗАРП и ОТД_РАЗМ.
+// Одновременно работает ровно одна стратегия, иначе суммы считаются дважды.
+type aggregateStrategy string
+
+const (
+	aggregatesAuto    aggregateStrategy = "auto"    // выбрать по установленным триггерам
+	aggregatesTrigger aggregateStrategy = "trigger" // триггеры MySQL на СЛУЖАЩИЕ
+	aggregatesApp     aggregateStrategy = "app"     // код сервера в той же транзакции
+	aggregatesView    aggregateStrategy = "view"    // вычисление при чтении через ОТДЕЛЫ_ИТОГИ
+)
+
+// triggerState состояние триггеров на СЛУЖАЩИЕ
+type triggerState int
+
+const (
+	triggersNone    triggerState = iota // триггеров нет
+	triggersPartial                     // есть, но не поддерживают оба агрегата (схема до миграции 4)
+	triggersFull                        // INSERT/UPDATE/DELETE поддерживают сумму и размер
+)
+
+// detectTriggers проверяет триггеры на СЛУЖАЩИЕ через information_schema
+func detectTriggers(ctx context.Context, db *sql.DB) (triggerState, error) {
+	rows, err := db.QueryContext(ctx, `
+        SELECT EVENT_MANIPULATION, ACTION_STATEMENT
+        FROM information_schema.TRIGGERS
+        WHERE EVENT_OBJECT_SCHEMA = DATABASE() AND EVENT_OBJECT_TABLE = 'СЛУЖАЩИЕ'`)
+	if err != nil {
+		return triggersNone, fmt.Errorf("ошибка чтения триггеров: %v", err)
+	}
+	defer rows.Close()
+
+	full := map[string]bool{}
+	found := 0
+	for rows.Next() {
+		var event, body string
+		if err := rows.Scan(&event, &body); err != nil {
+			return triggersNone, err
+		}
+		found++
+		if strings.Contains(body, "ОТД_СОТР_ЗАРП") && strings.Contains(body, "ОТД_РАЗМ") {
+			full[event] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return triggersNone, err
+	}
+
+	switch {
+	case found == 0:
+		return triggersNone, nil
+	case full["INSERT"] && full["UPDATE"] && full["DELETE"]:
+		return triggersFull, nil
+	default:
+		return triggersPartial, nil
+	}
+}
+
+// resolveAggregateStrategy выбирает стратегию по конфигурации и установленным триггерам
+func resolveAggregateStrategy(ctx context.Context, db *sql.DB, configured aggregateStrategy) (aggregateStrategy, error) {
+	state, err := detectTriggers(ctx, db)
+	if err != nil {
+		return "", err
+	}
+
+	switch configured {
+	case aggregatesAuto:
+		switch state {
+		case triggersFull:
+			return aggregatesTrigger, nil
+		case triggersNone:
+			return aggregatesApp, nil
+		default:
+			// устаревшие триггеры считают только сумму: безопасно лишь вычисление при чтении
+			log.Println("warning: триггеры СЛУЖАЩИЕ не поддерживают ОТД_РАЗМ, агрегаты вычисляются при чтении (выполните migrate up)")
+			return aggregatesView, nil
+		}
+	case aggregatesTrigger:
+		if state != triggersFull {
+			return "", fmt.Errorf("db.aggregates=trigger, но триггеры СЛУЖАЩИЕ не установлены или устарели (выполните migrate up)")
+		}
+	case aggregatesApp:
+		if state != triggersNone {
+			return "", fmt.Errorf("db.aggregates=app, но на СЛУЖАЩИЕ установлены триггеры: суммы будут считаться дважды")
+		}
+	case aggregatesView:
+	default:
+		return "", fmt.Errorf("db.aggregates: неизвестное значение %q", configured)
+	}
+	return configured, nil
+}
+
+// setupAggregates выбирает стратегию и исправляет накопившиеся расхождения
+func setupAggregates(ctx context.Context, db *sql.DB, configured aggregateStrategy) (aggregateStrategy, error) {
+	strategy, err := resolveAggregateStrategy(ctx, db, configured)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Агрегаты отделов поддерживаются стратегией %q", strategy)
+
+	// При вычислении на чтении хранимые значения не используются
+	if strategy == aggregatesView {
+		return strategy, nil
+	}
+	drifts, err := newMySQLRepositories(db, strategy).Departments.Reconcile(ctx, true)
+	if err != nil {
+		return "", fmt.Errorf("ошибка сверки агрегатов отделов: %v", err)
+	}
+	for _, d := range drifts {
+		log.Printf("Исправлены агрегаты отдела %d: зарплата %.2f -> %.2f, размер %d -> %d",
+			d.ID, d.StoredSalary, d.ActualSalary, d.StoredSize, d.ActualSize)
+	}
+	return strategy, nil
+}
@@ -1,13 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
-	"os"
+	"net/url"
 	"path"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +26,7 @@ import (
 type APIResponse struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
+	Meta    interface{} `json:"meta,omitempty"` // например, PageMeta для списков
 	Error   string      `json:"error,omitempty"`
 	Code    int         `json:"code,omitempty"`
 }
@@ -33,87 +44,115 @@ func sendAPIResponse(c *gin.Context, data interface{}, err error, code int) {
 	c.JSON(code, resp)
 }
 
-// Получить список отделов
-func getDepartments(db *sql.DB) gin.HandlerFunc {
+// Функция для JSON ответов со списком и метаданными
+func sendAPIPage(c *gin.Context, data interface{}, meta interface{}) {
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    data,
+		Meta:    meta,
+		Code:    http.StatusOK,
+	})
+}
+
+// Получить список отделов; фонд зарплат скрывается по правам пользователя (см. salaryVisibility)
+func getDepartments(departments DepartmentRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		const q = `
-        SELECT
-            d.ОТД_НОМЕР,
-            d.ОТД_РУК,
-            e.СЛУ_ИМЯ,
-            d.ОТД_СОТР_ЗАРП,
-            d.ОТД_РАЗМ
-        FROM ОТДЕЛЫ d
-        LEFT JOIN СЛУЖАЩИЕ e
-          	ON d.ОТД_РУК = e.СЛУ_НОМЕР`
-		rows, err := db.Query(q)
+		visibility, err := newSalaryVisibility(c, departments)
+		if err != nil {
+			sendAPIResponse(c, nil, err, http.StatusInternalServerError)
+			return
+		}
+		deps, err := departments.List(c.Request.Context())
 		if err != nil {
 			sendAPIResponse(c, nil, fmt.Errorf("ошибка при получении отделов: %v", err), http.StatusInternalServerError)
 			return
 		}
-		defer rows.Close()
-
-		var deps []Department
-		for rows.Next() {
-			var (
-				d      Department
-				bossId sql.NullInt64
-				name   sql.NullString
-			)
-			if err := rows.Scan(&d.ID, &bossId, &name, &d.TotalSalary, &d.Size); err != nil {
-				sendAPIResponse(c, nil, fmt.Errorf("ошибка парсинга данных: %v", err), http.StatusInternalServerError)
-				return
-			}
 
-			// Устанавливаем имя руководителя
-			if bossId.Valid {
-				id := int(bossId.Int64)
-				d.BossID = &id
-			} else {
+		for i := range deps {
+			d := &deps[i]
+			// 0 означает, что руководитель не установлен
+			if d.BossID == nil {
 				id := 0
-				d.BossID = &id // 0 означает, что руководитель не установлен
+				d.BossID = &id
 			}
 			// BossName: если есть имя — ставим его, иначе "Не установлен"
-			if name.Valid && name.String != "" {
-				d.BossName = &name.String
-			} else {
+			if d.BossName == nil || *d.BossName == "" {
 				placeholder := "Не установлен"
 				d.BossName = &placeholder
 			}
-
-			deps = append(deps, d)
 		}
 
-		sendAPIResponse(c, deps, nil, http.StatusOK)
+		sendAPIResponse(c, visibility.departments(deps), nil, http.StatusOK)
 	}
 }
 
-// Получить список сотрудников
-func getEmployees(db *sql.DB) gin.HandlerFunc {
+// Получить список сотрудников: фильтры, сортировка и страница (см. parseEmployeeQuery).
+// Фильтры и сортировка по зарплате доступны только тем, кто видит зарплаты выборки.
+func getEmployees(repos Repositories) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		rows, err := db.Query("SELECT СЛУ_НОМЕР, СЛУ_ИМЯ, СЛУ_СТАТ, СЛУ_ЗАРП, СЛУ_ОТД_НОМЕР FROM СЛУЖАЩИЕ")
+		q, err := parseEmployeeQuery(c)
+		if err != nil {
+			sendAPIResponse(c, nil, err, http.StatusBadRequest)
+			return
+		}
+		visibility, err := newSalaryVisibility(c, repos.Departments)
+		if err != nil {
+			sendAPIResponse(c, nil, err, http.StatusInternalServerError)
+			return
+		}
+		if !visibility.filter(q) {
+			sendAPIResponse(c, nil, fmt.Errorf("недостаточно прав: фильтр и сортировка по зарплате требуют роли hr или dept_id своего отдела"), http.StatusForbidden)
+			return
+		}
+
+		emps, total, err := repos.Employees.List(c.Request.Context(), q)
 		if err != nil {
 			sendAPIResponse(c, nil, fmt.Errorf("ошибка при получении сотрудников: %v", err), http.StatusInternalServerError)
 			return
 		}
-		defer rows.Close()
 
-		var emps []Employee
-		for rows.Next() {
-			var e Employee
-			if err := rows.Scan(&e.ID, &e.Name, &e.Status, &e.Salary, &e.DeptID); err != nil {
-				sendAPIResponse(c, nil, fmt.Errorf("ошибка парсинга данных: %v", err), http.StatusInternalServerError)
+		sendAPIPage(c, visibility.employees(emps), pageMeta(emps, total, q))
+	}
+}
+
+// Поиск сотрудников по имени: ?q=<запрос>&limit=<до 100>.
+// Учитывает опечатки, ё/е и транслитерацию (см. searchEmployees).
+func searchEmployeesAPI(repos Repositories) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := strings.TrimSpace(c.Query("q"))
+		if len(searchTokens(query)) == 0 {
+			sendAPIResponse(c, nil, fmt.Errorf("не задан поисковый запрос q"), http.StatusBadRequest)
+			return
+		}
+		limit := defaultSearchLimit
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxSearchLimit {
+				sendAPIResponse(c, nil, fmt.Errorf("некорректный limit: от 1 до %d", maxSearchLimit), http.StatusBadRequest)
 				return
 			}
-			emps = append(emps, e)
+			limit = n
+		}
+
+		visibility, err := newSalaryVisibility(c, repos.Departments)
+		if err != nil {
+			sendAPIResponse(c, nil, err, http.StatusInternalServerError)
+			return
+		}
+		// БД отбирает ограниченный набор похожих имён, точное ранжирование — на сервере
+		emps, err := repos.Employees.SearchCandidates(c.Request.Context(), query, searchCandidateLimit)
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("ошибка при получении сотрудников: %v", err), http.StatusInternalServerError)
+			return
 		}
 
-		sendAPIResponse(c, emps, nil, http.StatusOK)
+		results := searchEmployees(emps, query, limit)
+		sendAPIResponse(c, visibility.searchResults(results), nil, http.StatusOK)
 	}
 }
 
 // Получить сотрудника по ID
-func getEmployee(db *sql.DB) gin.HandlerFunc {
+func getEmployee(repos Repositories) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		idStr := c.Param("id")
 		id, err := strconv.Atoi(idStr)
@@ -122,61 +161,69 @@ func getEmployee(db *sql.DB) gin.HandlerFunc {
 			return
 		}
 
-		var e Employee
-		row := db.QueryRow("SELECT СЛУ_НОМЕР, СЛУ_ИМЯ, СЛУ_СТАТ, СЛУ_ЗАРП, СЛУ_ОТД_НОМЕР, IMAGE_URL FROM СЛУЖАЩИЕ WHERE СЛУ_НОМЕР = ?", id)
-		if err := row.Scan(&e.ID, &e.Name, &e.Status, &e.Salary, &e.DeptID, &e.ImageURL); err != nil {
-			sendAPIResponse(c, nil, fmt.Errorf("сотрудник не найден: %v", err), http.StatusNotFound)
+		e, err := repos.Employees.Get(c.Request.Context(), id)
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("сотрудник не найден: %v", err), notFoundOr(err, http.StatusInternalServerError))
+			return
+		}
+		visibility, err := newSalaryVisibility(c, repos.Departments)
+		if err != nil {
+			sendAPIResponse(c, nil, err, http.StatusInternalServerError)
 			return
 		}
 
-		sendAPIResponse(c, e, nil, http.StatusOK)
+		sendAPIResponse(c, visibility.employee(e), nil, http.StatusOK)
 	}
 }
 
 // Получить сотрудника по ID отдела
-func getEmployeeByDepartment(db *sql.DB) gin.HandlerFunc {
+func getEmployeeByDepartment(repos Repositories) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		DeptID := c.Param("id")
-		if DeptID == "" {
+		ctx := c.Request.Context()
+
+		var deptID int
+		if idStr := c.Param("id"); idStr == "" {
 			// Получаем первый существующий ID отдела
-			row := db.QueryRow("SELECT ОТД_НОМЕР FROM ОТДЕЛЫ ORDER BY ОТД_НОМЕР LIMIT 1")
-			var firstDeptID int
-			if err := row.Scan(&firstDeptID); err != nil {
-				//showError(c, "Не удалось получить отделы")
+			firstDeptID, err := repos.Departments.FirstID(ctx)
+			if err != nil {
+				sendAPIResponse(c, nil, fmt.Errorf("не удалось получить отделы: %v", err), notFoundOr(err, http.StatusInternalServerError))
+				return
+			}
+			deptID = firstDeptID
+		} else {
+			id, err := strconv.Atoi(idStr)
+			if err != nil {
+				sendAPIResponse(c, nil, fmt.Errorf("некорректный ID отдела"), http.StatusBadRequest)
 				return
 			}
-			DeptID = strconv.Itoa(firstDeptID)
+			deptID = id
 		}
 
-		rows, err := db.Query("SELECT СЛУ_НОМЕР, СЛУ_ИМЯ, СЛУ_СТАТ, СЛУ_ЗАРП, СЛУ_ОТД_НОМЕР FROM СЛУЖАЩИЕ WHERE СЛУ_ОТД_НОМЕР = ?", DeptID)
-		if err != nil {
-			sendAPIResponse(c, nil, fmt.Errorf("ошибка при получении данных о сотрудниках по данному отделу: %v", err), http.StatusNotFound)
+		// 404 только для несуществующего отдела, пустой отдел — пустой список
+		if _, err := repos.Departments.Get(ctx, deptID); err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("отдел с ID %d не найден: %v", deptID, err), notFoundOr(err, http.StatusInternalServerError))
 			return
 		}
-		defer rows.Close()
-
-		var emps []Employee
-		for rows.Next() {
-			var e Employee
-			if err := rows.Scan(&e.ID, &e.Name, &e.Status, &e.Salary, &e.DeptID); err != nil {
-				//showError(c, fmt.Sprintf("Ошибка парсинга данных сотрудника: %v", err))
-				return
-			}
-			emps = append(emps, e)
+		visibility, err := newSalaryVisibility(c, repos.Departments)
+		if err != nil {
+			sendAPIResponse(c, nil, err, http.StatusInternalServerError)
+			return
 		}
 
-		if len(emps) == 0 {
-			sendAPIResponse(c, nil, fmt.Errorf("нет сотрудников в отделе с ID %s", DeptID), http.StatusNotFound)
+		emps, err := repos.Employees.ListByDepartment(ctx, deptID)
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("ошибка при получении данных о сотрудниках по данному отделу: %v", err), http.StatusInternalServerError)
 			return
 		}
 
-		sendAPIResponse(c, emps, nil, http.StatusOK)
+		sendAPIResponse(c, visibility.employees(emps), nil, http.StatusOK)
 	}
 }
 
 // Добавить нового сотрудника
-func createEmployeeAPI(db *sql.DB) gin.HandlerFunc {
+func createEmployeeAPI(employees EmployeeRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		ctx := c.Request.Context()
 
 		name := c.PostForm("name")
 		status := c.PostForm("status")
@@ -198,70 +245,124 @@ func createEmployeeAPI(db *sql.DB) gin.HandlerFunc {
 			sendAPIResponse(c, nil, fmt.Errorf("недостаточно данных"), http.StatusBadRequest)
 			return
 		}
-
-		tx, err := db.Begin()
+		// Фото проверяем до создания сотрудника
+		photo, code, err := processPhotoField(photoHeader)
 		if err != nil {
-			sendAPIResponse(c, nil, fmt.Errorf("ошибка транзакции: %v", err), http.StatusInternalServerError)
+			sendAPIResponse(c, nil, err, code)
 			return
 		}
 
-		// Вставка сотрудника
-		res, err := tx.Exec(
-			`INSERT INTO СЛУЖАЩИЕ (СЛУ_ИМЯ, СЛУ_СТАТ, СЛУ_ЗАРП, СЛУ_ОТД_НОМЕР)
-       VALUES (?, ?, ?, ?)`,
-			name, status, salary, deptID,
-		)
+		// Вставка сотрудника и обновление данных отдела
+		empID, err := employees.Create(ctx, Employee{
+			Name:   name,
+			Status: status,
+			Salary: salary,
+			DeptID: deptID,
+		})
 		if err != nil {
-			tx.Rollback()
-			sendAPIResponse(c, nil, fmt.Errorf("ошибка создания сотрудника: %v", err), http.StatusInternalServerError)
+			sendAPIResponse(c, nil, err, http.StatusInternalServerError)
 			return
 		}
-		lastID, _ := res.LastInsertId()
-		empID := int(lastID)
 
 		// Если есть изображение, сохраняем его
 		var filename *string
-		if photoHeader != nil {
-			fn, err := saveUploadedPhoto(photoHeader, empID)
+		if photo != nil {
+			fn, err := photo.save(ctx, empID)
 			if err != nil {
 				// файл не сохранился, но сам сотрудник уже в БД — просто логируем
 				log.Printf("warning: не удалось сохранить фото для %d: %v", empID, err)
+			} else if err := employees.SetImageURL(ctx, empID, &fn); err != nil {
+				log.Printf("warning: не удалось обновить photo path для %d: %v", empID, err)
 			} else {
 				filename = &fn
-				if _, err := tx.Exec(
-					"UPDATE СЛУЖАЩИЕ SET IMAGE_URL = ? WHERE СЛУ_НОМЕР = ?",
-					fn, empID,
-				); err != nil {
-					log.Printf("warning: не удалось обновить photo path для %d: %v", empID, err)
-				}
 			}
 		}
 
-		// Обновление данных отдела
-		_, err = tx.Exec(
-			`UPDATE ОТДЕЛЫ
-                 SET ОТД_РАЗМ = ОТД_РАЗМ + 1,
-                 ОТД_СОТР_ЗАРП = ОТД_СОТР_ЗАРП + ?
-               WHERE ОТД_НОМЕР = ?`,
-			salary, deptID,
-		)
+		sendAPIResponse(c, map[string]interface{}{
+			"id":        empID,
+			"image_url": filename,
+		}, nil, http.StatusCreated)
+	}
+}
+
+// Импорт сотрудников из CSV или XLSX (поле формы file).
+// ?dry_run=true только проверяет строки; иначе все строки добавляются одной транзакцией,
+// а при ошибках в файле не добавляется ни одна (422 с тем же отчётом).
+func importEmployeesAPI(repos Repositories) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
 		if err != nil {
-			tx.Rollback()
-			sendAPIResponse(c, nil, fmt.Errorf("ошибка обновления отдела: %v", err), http.StatusInternalServerError)
+			sendAPIResponse(c, nil, fmt.Errorf("некорректный dry_run: true или false"), http.StatusBadRequest)
+			return
+		}
+		header, err := c.FormFile("file")
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("не передан файл file"), http.StatusBadRequest)
+			return
+		}
+		if header.Size > maxImportSize {
+			sendAPIResponse(c, nil, fmt.Errorf("файл больше %d МБ", maxImportSize>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+		f, err := header.Open()
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("не удалось открыть загруженный файл: %v", err), http.StatusBadRequest)
+			return
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("ошибка чтения файла: %v", err), http.StatusBadRequest)
 			return
 		}
 
-		tx.Commit()
-		sendAPIResponse(c, map[string]interface{}{
-			"id":        lastID,
-			"image_url": filename,
-		}, nil, http.StatusCreated)
+		rows, err := readImportFile(header.Filename, data)
+		if err != nil {
+			sendAPIResponse(c, nil, err, http.StatusBadRequest)
+			return
+		}
+		deps, err := repos.Departments.List(ctx)
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("ошибка при получении отделов: %v", err), http.StatusInternalServerError)
+			return
+		}
+		existing := map[int]bool{}
+		for _, d := range deps {
+			existing[d.ID] = true
+		}
+
+		emps, rowErrs := validateImportRows(rows, existing)
+		report := ImportReport{DryRun: dryRun, Rows: len(rows), Valid: len(emps), Errors: rowErrs}
+		if report.Errors == nil {
+			report.Errors = []ImportError{}
+		}
+		switch {
+		case dryRun:
+			sendAPIResponse(c, report, nil, http.StatusOK)
+			return
+		case len(rowErrs) > 0:
+			sendAPIResponse(c, report, fmt.Errorf("ошибок в файле: %d, сотрудники не добавлены", len(rowErrs)), http.StatusUnprocessableEntity)
+			return
+		case len(emps) == 0:
+			sendAPIResponse(c, nil, fmt.Errorf("в файле нет строк с данными"), http.StatusBadRequest)
+			return
+		}
+
+		if report.Created, err = repos.Employees.CreateMany(ctx, emps); err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("ошибка импорта: %v", err), http.StatusInternalServerError)
+			return
+		}
+		sendAPIResponse(c, report, nil, http.StatusCreated)
 	}
 }
 
 // Обновить данные сотрудника
-func updateEmployeeAPI(db *sql.DB) gin.HandlerFunc {
+func updateEmployeeAPI(repos Repositories) gin.HandlerFunc {
+	employees := repos.Employees
 	return func(c *gin.Context) {
+		ctx := c.Request.Context()
 
 		empID, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
@@ -290,102 +391,60 @@ func updateEmployeeAPI(db *sql.DB) gin.HandlerFunc {
 
 		photoHeader, _ := c.FormFile("image")
 
-		// Сначала узнаём старую зарплату и отдел
-		var oldSalary float64
-		var oldDeptID int
-		var oldPhoto sql.NullString
-		row := db.QueryRow(`
-            SELECT СЛУ_ЗАРП, СЛУ_ОТД_НОМЕР, IMAGE_URL
-            FROM СЛУЖАЩИЕ WHERE СЛУ_НОМЕР = ?`, empID)
-		if err := row.Scan(&oldSalary, &oldDeptID, &oldPhoto); err != nil {
-			sendAPIResponse(c, nil, fmt.Errorf("сотрудник не найден: %v", err), http.StatusNotFound)
+		// Руководитель изменяет только сотрудников своего отдела и не может изменить себя
+		if code, err := checkEmployeeScope(c, repos, empID, newDeptID); err != nil {
+			sendAPIResponse(c, nil, err, code)
 			return
 		}
-
-		tx, err := db.Begin()
+		photo, code, err := processPhotoField(photoHeader)
 		if err != nil {
-			sendAPIResponse(c, nil, fmt.Errorf("ошибка транзакции: %v", err), http.StatusInternalServerError)
+			sendAPIResponse(c, nil, err, code)
 			return
 		}
 
-		// Обновляем  данные сотрудника
-		_, err = tx.Exec(`
-            UPDATE СЛУЖАЩИЕ
-            SET СЛУ_ИМЯ     = ?,
-                СЛУ_СТАТ    = ?,
-                СЛУ_ЗАРП    = ?,
-                СЛУ_ОТД_НОМЕР = ?
-            WHERE СЛУ_НОМЕР = ?`,
-			name, status, newSalary, newDeptID, empID,
-		)
-		if err != nil {
-			tx.Rollback()
-			sendAPIResponse(c, nil, fmt.Errorf("ошибка обновления сотрудника: %v", err), http.StatusInternalServerError)
+		// Причина попадает в историю зарплаты, если зарплата изменилась
+		reason := strings.TrimSpace(c.PostForm("salary_reason"))
+		if utf8.RuneCountInString(reason) > 255 {
+			sendAPIResponse(c, nil, fmt.Errorf("причина изменения зарплаты длиннее 255 символов"), http.StatusBadRequest)
 			return
 		}
 
-		// Обновляем сумму зарплат отдела
-		deltaSalary := newSalary - oldSalary
-		if oldDeptID == newDeptID {
-			// те же отдел: меняем только сумму зарплат
-			_, err = tx.Exec(`
-                UPDATE ОТДЕЛЫ
-                SET ОТД_СОТР_ЗАРП = ОТД_СОТР_ЗАРП + ?
-                WHERE ОТД_НОМЕР = ?`,
-				deltaSalary, oldDeptID,
-			)
-		} else {
-			// минус из старого
-			_, err = tx.Exec(`
-                UPDATE ОТДЕЛЫ
-                SET ОТД_РАЗМ       = ОТД_РАЗМ - 1,
-                    ОТД_СОТР_ЗАРП = ОТД_СОТР_ЗАРП - ?
-                WHERE ОТД_НОМЕР = ?`,
-				oldSalary, oldDeptID,
-			)
-			if err == nil {
-				// плюс в новый
-				_, err = tx.Exec(`
-                    UPDATE ОТДЕЛЫ
-                    SET ОТД_РАЗМ       = ОТД_РАЗМ + 1,
-                        ОТД_СОТР_ЗАРП = ОТД_СОТР_ЗАРП + ?
-                    WHERE ОТД_НОМЕР = ?`,
-					newSalary, newDeptID,
-				)
-			}
-		}
-		if err != nil {
-			tx.Rollback()
-			sendAPIResponse(c, nil, fmt.Errorf("ошибка обновления отдела: %v", err), http.StatusInternalServerError)
+		// Обновляем данные сотрудника и суммы зарплат отделов; права на отделы
+		// проверяются ещё раз в транзакции, под блокировкой сотрудника
+		old, err := employees.Update(withManagerScope(withReason(ctx, reason), c), Employee{
+			ID:     empID,
+			Name:   name,
+			Status: status,
+			Salary: newSalary,
+			DeptID: newDeptID,
+		})
+		if errors.Is(err, ErrNotFound) {
+			sendAPIResponse(c, nil, fmt.Errorf("сотрудник не найден: %v", err), http.StatusNotFound)
 			return
-		}
-
-		if err := tx.Commit(); err != nil {
-			sendAPIResponse(c, nil, fmt.Errorf("ошибка фиксации транзакции: %v", err), http.StatusInternalServerError)
+		} else if errors.Is(err, errDepartmentScope) {
+			sendAPIResponse(c, nil, err, http.StatusForbidden)
+			return
+		} else if err != nil {
+			sendAPIResponse(c, nil, err, http.StatusInternalServerError)
 			return
 		}
 
-		if photoHeader != nil {
+		if photo != nil {
 			// удаляем старое
-			if oldPhoto.Valid {
-				if err := deletePhoto(oldPhoto.String); err != nil {
+			if old.ImageURL != nil {
+				if err := deletePhoto(ctx, *old.ImageURL); err != nil {
 					sendAPIResponse(c, nil, fmt.Errorf("warning: не удалось удалить старое фото для %d: %v", empID, err), 400)
 					log.Printf("warning: не удалось удалить старое фото для %d: %v", empID, err)
 				}
 			}
 			// сохраняем новое
-			fn, err := saveUploadedPhoto(photoHeader, empID)
+			fn, err := photo.save(ctx, empID)
 			if err != nil {
 				sendAPIResponse(c, nil, fmt.Errorf("warning: не удалось сохранить новое фото для %d: %v", empID, err), 400)
 				log.Printf("warning: не удалось сохранить новое фото для %d: %v", empID, err)
-			} else {
-				if _, err := db.Exec(
-					"UPDATE СЛУЖАЩИЕ SET IMAGE_URL = ? WHERE СЛУ_НОМЕР = ?",
-					fn, empID,
-				); err != nil {
-					sendAPIResponse(c, nil, fmt.Errorf("warning: не удалось обновить PHOTO_PATH для %d: %v", empID, err), 400)
-					log.Printf("warning: не удалось обновить PHOTO_PATH для %d: %v", empID, err)
-				}
+			} else if err := employees.SetImageURL(ctx, empID, &fn); err != nil {
+				sendAPIResponse(c, nil, fmt.Errorf("warning: не удалось обновить PHOTO_PATH для %d: %v", empID, err), 400)
+				log.Printf("warning: не удалось обновить PHOTO_PATH для %d: %v", empID, err)
 			}
 		}
 
@@ -393,8 +452,23 @@ func updateEmployeeAPI(db *sql.DB) gin.HandlerFunc {
 	}
 }
 
+// processPhotoField обрабатывает необязательное поле формы image (см. processUploadedPhoto).
+// nil без ошибки — фото не передано.
+func processPhotoField(header *multipart.FileHeader) (processedPhoto, int, error) {
+	if header == nil {
+		return nil, 0, nil
+	}
+	photo, err := processUploadedPhoto(header)
+	if errors.Is(err, errInvalidPhoto) {
+		return nil, http.StatusBadRequest, err
+	} else if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return photo, 0, nil
+}
+
 // Удалить сотрудника
-func deleteEmployeeAPI(db *sql.DB) gin.HandlerFunc {
+func deleteEmployeeAPI(employees EmployeeRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		idStr := c.Param("id")
 		id, err := strconv.Atoi(idStr)
@@ -403,219 +477,1103 @@ func deleteEmployeeAPI(db *sql.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Нам нужно знать, из какого отдела и с какой зарплатой удаляем
-		var deptID int
-		var salary float64
-		var imageURL sql.NullString
-		row := db.QueryRow(`
-            SELECT СЛУ_ОТД_НОМЕР, СЛУ_ЗАРП, IMAGE_URL
-            FROM СЛУЖАЩИЕ WHERE СЛУ_НОМЕР = ?`, id)
-		if err := row.Scan(&deptID, &salary, &imageURL); err != nil {
+		// Удаляем сотрудника и обновляем данные отдела
+		old, err := employees.Delete(c.Request.Context(), id)
+		if errors.Is(err, ErrNotFound) {
 			sendAPIResponse(c, nil, fmt.Errorf("сотрудник не найден: %v", err), http.StatusNotFound)
 			return
+		} else if err != nil {
+			sendAPIResponse(c, nil, err, http.StatusInternalServerError)
+			return
 		}
 
-		tx, err := db.Begin()
-		if err != nil {
-			sendAPIResponse(c, nil, fmt.Errorf("ошибка транзакции: %v", err), http.StatusInternalServerError)
+		// Удаляем фото, если есть
+		if old.ImageURL != nil && *old.ImageURL != "" {
+			if err := deletePhoto(c.Request.Context(), *old.ImageURL); err != nil {
+				log.Printf("warning: не удалось удалить фото для %d: %v", id, err)
+			}
+		}
+
+		sendAPIResponse(c, nil, nil, http.StatusOK)
+	}
+}
+
+// Создать отдел
+func createDepartmentAPI(departments DepartmentRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Name        string  `json:"name" binding:"required"`
+			Description *string `json:"description"`
+			CostCenter  *string `json:"cost_center"`
+			BossID      *int    `json:"boss_id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("некорректные данные: %v", err), http.StatusBadRequest)
+			return
+		}
+		d := Department{
+			Name:        strings.TrimSpace(req.Name),
+			Description: req.Description,
+			CostCenter:  req.CostCenter,
+			BossID:      req.BossID,
+		}
+		if err := validateDepartment(&d.Name, d.CostCenter); err != nil {
+			sendAPIResponse(c, nil, err, http.StatusBadRequest)
 			return
 		}
 
-		// Удаляем сотрудника
-		_, err = tx.Exec("DELETE FROM СЛУЖАЩИЕ WHERE СЛУ_НОМЕР = ?", id)
+		id, err := departments.Create(c.Request.Context(), d)
 		if err != nil {
-			tx.Rollback()
-			sendAPIResponse(c, nil, fmt.Errorf("ошибка удаления сотрудника: %v", err), http.StatusInternalServerError)
+			sendAPIResponse(c, nil, err, http.StatusInternalServerError)
 			return
 		}
 
-		// Удаляем фото, если есть
-		if imageURL.Valid && imageURL.String != "" {
-			if err := deletePhoto(imageURL.String); err != nil {
-				log.Printf("warning: не удалось удалить фото для %d: %v", id, err)
-			}
-		}
+		sendAPIResponse(c, map[string]interface{}{"id": id}, nil, http.StatusCreated)
+	}
+}
 
-		// Обновляем данные отдела
-		_, err = tx.Exec(`
-            UPDATE ОТДЕЛЫ
-            SET ОТД_РАЗМ = ОТД_РАЗМ - 1,
-            ОТД_СОТР_ЗАРП = ОТД_СОТР_ЗАРП - ?
-            WHERE ОТД_НОМЕР = ?`,
-			salary, deptID,
-		)
+// Обновить данные отдела: руководитель, название, описание, ЦФО.
+// Передаются только изменяемые поля; "boss_id": null сбрасывает руководителя.
+func updateDepartmentAPI(departments DepartmentRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("некорректный ID отдела"), http.StatusBadRequest)
+			return
+		}
 
+		var raw map[string]json.RawMessage
+		if err := c.ShouldBindJSON(&raw); err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("некорректные данные: %v", err), http.StatusBadRequest)
+			return
+		}
+		patch, err := decodeDepartmentPatch(raw)
 		if err != nil {
-			tx.Rollback()
+			sendAPIResponse(c, nil, fmt.Errorf("некорректные данные: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		err = departments.Update(c.Request.Context(), id, patch)
+		if errors.Is(err, ErrNotFound) {
+			sendAPIResponse(c, nil, fmt.Errorf("отдел с ID %d не найден", id), http.StatusNotFound)
+			return
+		} else if err != nil {
 			sendAPIResponse(c, nil, fmt.Errorf("ошибка обновления отдела: %v", err), http.StatusInternalServerError)
 			return
 		}
 
-		tx.Commit()
 		sendAPIResponse(c, nil, nil, http.StatusOK)
 	}
 }
 
-// Обновить данные отдела (руководителя)
-func updateDepartmentAPI(db *sql.DB) gin.HandlerFunc {
+// Удалить отдел.
+// ?policy=refuse (по умолчанию) | reassign&target=<ID> | cascade
+func deleteDepartmentAPI(departments DepartmentRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
 		id, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
 			sendAPIResponse(c, nil, fmt.Errorf("некорректный ID отдела"), http.StatusBadRequest)
 			return
 		}
 
-		// Проверяем, существует ли отдел с таким ID
-		var req struct {
-			BossID *int `json:"boss_id" binding:"required"`
+		policy := DepartmentDeletePolicy(c.DefaultQuery("policy", string(DeleteRefuse)))
+		var targetID int
+		switch policy {
+		case DeleteRefuse, DeleteCascade:
+		case DeleteReassign:
+			targetID, err = strconv.Atoi(c.Query("target"))
+			if err != nil || targetID == id {
+				sendAPIResponse(c, nil, fmt.Errorf("для policy=reassign нужен target — ID другого отдела"), http.StatusBadRequest)
+				return
+			}
+			if _, err := departments.Get(ctx, targetID); err != nil {
+				sendAPIResponse(c, nil, fmt.Errorf("целевой отдел №%d: %v", targetID, err), notFoundOr(err, http.StatusInternalServerError))
+				return
+			}
+		default:
+			sendAPIResponse(c, nil, fmt.Errorf("некорректный policy: %q (refuse, reassign, cascade)", policy), http.StatusBadRequest)
+			return
 		}
-		if err := c.ShouldBindJSON(&req); err != nil {
-			sendAPIResponse(c, nil, fmt.Errorf("некорректные данные: %v", err), http.StatusBadRequest)
+
+		emps, err := departments.Delete(ctx, id, policy, targetID)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			sendAPIResponse(c, nil, fmt.Errorf("отдел с ID %d не найден", id), http.StatusNotFound)
 			return
+		case errors.Is(err, ErrConflict):
+			sendAPIResponse(c, nil, fmt.Errorf("отдел не пуст, укажите policy=reassign или policy=cascade: %v", err), http.StatusConflict)
+			return
+		case err != nil:
+			sendAPIResponse(c, nil, err, http.StatusInternalServerError)
+			return
+		}
+
+		// Фото удалённых сотрудников больше не нужны
+		if policy == DeleteCascade {
+			for _, e := range emps {
+				if e.ImageURL != nil && *e.ImageURL != "" {
+					if err := deletePhoto(ctx, *e.ImageURL); err != nil {
+						log.Printf("warning: не удалось удалить фото для %d: %v", e.ID, err)
+					}
+				}
+			}
 		}
 
-		tx, err := db.Begin()
+		sendAPIResponse(c, map[string]interface{}{
+			"policy":    policy,
+			"employees": len(emps),
+		}, nil, http.StatusOK)
+	}
+}
+
+// decodeDepartmentPatch разбирает частичное изменение отдела, отличая null от отсутствия поля
+func decodeDepartmentPatch(raw map[string]json.RawMessage) (DepartmentPatch, error) {
+	var patch DepartmentPatch
+	for field, value := range raw {
+		var err error
+		switch field {
+		case "boss_id":
+			patch.SetBoss = true
+			err = json.Unmarshal(value, &patch.BossID)
+		case "name":
+			err = json.Unmarshal(value, &patch.Name)
+			if err == nil && patch.Name != nil {
+				name := strings.TrimSpace(*patch.Name)
+				patch.Name = &name
+			}
+		case "description":
+			err = json.Unmarshal(value, &patch.Description)
+			if err == nil && patch.Description == nil {
+				patch.Description = new(string) // null очищает описание
+			}
+		case "cost_center":
+			err = json.Unmarshal(value, &patch.CostCenter)
+			if err == nil && patch.CostCenter == nil {
+				patch.CostCenter = new(string)
+			}
+		default:
+			err = fmt.Errorf("неизвестное поле")
+		}
 		if err != nil {
-			sendAPIResponse(c, nil, fmt.Errorf("ошибка транзакции: %v", err), http.StatusInternalServerError)
-			return
+			return patch, fmt.Errorf("%s: %v", field, err)
 		}
-		var res sql.Result
-		if req.BossID == nil {
-			// Сброс руководителя в NULL
-			res, err = tx.Exec("UPDATE ОТДЕЛЫ SET ОТД_РУК = NULL WHERE ОТД_НОМЕР = ?", id)
-		} else {
-			// Установка нового ID руководителя
-			res, err = tx.Exec("UPDATE ОТДЕЛЫ SET ОТД_РУК = ? WHERE ОТД_НОМЕР = ?", *req.BossID, id)
+	}
+	if !patch.SetBoss && patch.Name == nil && patch.Description == nil && patch.CostCenter == nil {
+		return patch, fmt.Errorf("нет полей для изменения")
+	}
+	if patch.Name != nil || patch.CostCenter != nil {
+		if err := validateDepartment(patch.Name, patch.CostCenter); err != nil {
+			return patch, err
+		}
+	}
+	return patch, nil
+}
+
+// validateDepartment проверяет название и ЦФО по ограничениям колонок ОТДЕЛЫ
+func validateDepartment(name, costCenter *string) error {
+	if name != nil {
+		if *name == "" {
+			return fmt.Errorf("название отдела не может быть пустым")
 		}
+		if utf8.RuneCountInString(*name) > 100 {
+			return fmt.Errorf("название отдела длиннее 100 символов")
+		}
+	}
+	if costCenter != nil && utf8.RuneCountInString(*costCenter) > 32 {
+		return fmt.Errorf("код ЦФО длиннее 32 символов")
+	}
+	return nil
+}
+
+// Сверка агрегатов отделов с СЛУЖАЩИЕ.
+// GET — только отчёт; POST ?fix=true — перезаписать расходящиеся значения.
+func reconcileDepartmentsAPI(departments DepartmentRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fix, err := strconv.ParseBool(c.DefaultQuery("fix", "false"))
 		if err != nil {
-			tx.Rollback()
-			sendAPIResponse(c, nil, fmt.Errorf("ошибка обновления отдела: %v", err), http.StatusInternalServerError)
+			sendAPIResponse(c, nil, fmt.Errorf("некорректный параметр fix"), http.StatusBadRequest)
 			return
 		}
-		if n, _ := res.RowsAffected(); n == 0 {
-			tx.Rollback()
-			sendAPIResponse(c, nil, fmt.Errorf("отдел с ID %d не найден", id), http.StatusNotFound)
+		if fix && c.Request.Method != http.MethodPost {
+			sendAPIResponse(c, nil, fmt.Errorf("исправление выполняется только через POST"), http.StatusMethodNotAllowed)
 			return
 		}
 
-		tx.Commit()
-		sendAPIResponse(c, nil, nil, http.StatusOK)
+		drifts, err := departments.Reconcile(c.Request.Context(), fix)
+		if err != nil {
+			sendAPIResponse(c, nil, err, http.StatusInternalServerError)
+			return
+		}
+		if drifts == nil {
+			drifts = []DepartmentDrift{}
+		}
+
+		sendAPIResponse(c, gin.H{
+			"fixed":       fix && len(drifts) > 0,
+			"departments": drifts,
+		}, nil, http.StatusOK)
 	}
 }
 
-// GET /employees/:id/photo
-func getEmployeePhotoHandler() gin.HandlerFunc {
+// История зарплаты сотрудника, включая запланированные изменения
+func getSalaryHistoryAPI(salaries SalaryRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 1) Парсим ID сотрудника
 		empID, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный ID"})
+			sendAPIResponse(c, nil, fmt.Errorf("некорректный ID сотрудника"), http.StatusBadRequest)
 			return
 		}
 
-		// Проверяем все возможные расширения
-		extensions := []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp"}
-		var fullPath string
-		var foundExt string
-		for _, ext := range extensions {
-			filename := fmt.Sprintf("emp_%d%s", empID, ext)
-			tryPath := path.Join(storageEmployeeImages, filename)
-			if _, err := os.Stat(tryPath); err == nil {
-				fullPath = tryPath
-				foundExt = ext
-				break
-			}
+		history, err := salaries.History(c.Request.Context(), empID)
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("ошибка при получении истории зарплаты: %v", err), notFoundOr(err, http.StatusInternalServerError))
+			return
 		}
-
-		if fullPath == "" {
-			//c.JSON(http.StatusNotFound, gin.H{"error": "does not exist"})
-			//return
-			fullPath = path.Join(storageEmployeeImages, "default.png")
+		if history == nil {
+			history = []SalaryChange{}
 		}
 
-		// Определяем Content-Type по расширению
-		contentTypes := map[string]string{
-			".jpg":  "image/jpeg",
-			".jpeg": "image/jpeg",
-			".png":  "image/png",
-			".gif":  "image/gif",
-			".bmp":  "image/bmp",
-			".webp": "image/webp",
-		}
-		contentType := contentTypes[foundExt]
-		if contentType == "" {
-			contentType = "application/octet-stream"
-		}
-		c.Header("Content-Type", contentType)
-		c.File(fullPath)
+		sendAPIResponse(c, history, nil, http.StatusOK)
 	}
 }
 
-// getEmployeeByDepartDocumentHandler возвращает DOCX со списком сотрудников отдела.
-func getEmployeeByDepartDocumentHandler(db *sql.DB) gin.HandlerFunc {
+// Запланировать изменение зарплаты: {"salary": 120000, "effective_date": "YYYY-MM-DD", "reason": "..."}.
+// Изменение применяется автоматически в день effective_date (см. runSalaryScheduler).
+func scheduleSalaryChangeAPI(salaries SalaryRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 1) Парсим ID отдела
-		deptID, err := strconv.Atoi(c.Param("id"))
+		empID, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
-			sendAPIResponse(c, nil, fmt.Errorf("некорректный ID отдела"), http.StatusBadRequest)
+			sendAPIResponse(c, nil, fmt.Errorf("некорректный ID сотрудника"), http.StatusBadRequest)
 			return
 		}
 
-		// 2) Берём из ОТДЕЛЫ ожидаемые значения и boss_id
-		var expectedSalary float64
-		var expectedCount int
-		var bossID sql.NullInt64
-		err = db.QueryRow(`
-            SELECT ОТД_СОТР_ЗАРП, ОТД_РАЗМ, ОТД_РУК
-            FROM ОТДЕЛЫ WHERE ОТД_НОМЕР = ?`, deptID,
-		).Scan(&expectedSalary, &expectedCount, &bossID)
-		if err == sql.ErrNoRows {
-			sendAPIResponse(c, nil, fmt.Errorf("отдел №%d не найден", deptID), http.StatusNotFound)
+		var req struct {
+			Salary        *float64 `json:"salary"`
+			EffectiveDate string   `json:"effective_date"`
+			Reason        string   `json:"reason"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.Salary == nil {
+			sendAPIResponse(c, nil, fmt.Errorf("ожидается JSON с salary и effective_date"), http.StatusBadRequest)
 			return
-		} else if err != nil {
-			sendAPIResponse(c, nil, fmt.Errorf("ошибка запроса отдела: %v", err), http.StatusInternalServerError)
+		}
+		ch := SalaryChange{
+			EmployeeID:    empID,
+			EffectiveDate: req.EffectiveDate,
+			NewSalary:     *req.Salary,
+			Reason:        strings.TrimSpace(req.Reason),
+		}
+		if err := validateScheduledChange(ch); err != nil {
+			sendAPIResponse(c, nil, err, http.StatusBadRequest)
 			return
 		}
 
-		// 3) Запрашиваем сотрудников
-		rows, err := db.Query(`
-            SELECT СЛУ_НОМЕР, СЛУ_ИМЯ, СЛУ_СТАТ, СЛУ_ЗАРП
-            FROM СЛУЖАЩИЕ WHERE СЛУ_ОТД_НОМЕР = ?`, deptID,
-		)
+		id, err := salaries.Schedule(c.Request.Context(), ch)
 		if err != nil {
-			sendAPIResponse(c, nil, fmt.Errorf("ошибка запроса сотрудников: %v", err), http.StatusInternalServerError)
+			sendAPIResponse(c, nil, fmt.Errorf("ошибка планирования изменения зарплаты: %v", err), notFoundOr(err, http.StatusInternalServerError))
 			return
 		}
-		defer rows.Close()
 
-		var emps []emp
-		var actualSalary float64
-		for rows.Next() {
-			var e emp
-			if err := rows.Scan(&e.ID, &e.Name, &e.Status, &e.Salary); err != nil {
-				sendAPIResponse(c, nil, fmt.Errorf("ошибка чтения сотрудника: %v", err), http.StatusInternalServerError)
-				return
-			}
-			emps = append(emps, e)
-			actualSalary += e.Salary
+		sendAPIResponse(c, gin.H{"id": id}, nil, http.StatusCreated)
+	}
+}
+
+// Отменить запланированное изменение зарплаты
+func cancelSalaryChangeAPI(salaries SalaryRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		empID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("некорректный ID сотрудника"), http.StatusBadRequest)
+			return
 		}
-		if len(emps) == 0 {
-			sendAPIResponse(c, nil, fmt.Errorf("нет сотрудников в отделе №%d", deptID), http.StatusNotFound)
+		changeID, err := strconv.ParseInt(c.Param("change"), 10, 64)
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("некорректный ID изменения"), http.StatusBadRequest)
 			return
 		}
-		actualCount := len(emps)
 
-		// 8) Сохраняем и отдаем
-		buf, err := setupDocument(deptID, expectedSalary, expectedCount, actualSalary, actualCount, bossID, emps)
-		if err != nil {
-			sendAPIResponse(c, nil, fmt.Errorf("ошибка создания документа: %v", err), http.StatusInternalServerError)
+		err = salaries.Cancel(c.Request.Context(), empID, changeID)
+		switch {
+		case errors.Is(err, ErrConflict):
+			sendAPIResponse(c, nil, err, http.StatusConflict)
+			return
+		case err != nil:
+			sendAPIResponse(c, nil, fmt.Errorf("ошибка отмены изменения зарплаты: %v", err), notFoundOr(err, http.StatusInternalServerError))
 			return
 		}
-		filename := fmt.Sprintf("department_%d_employees.docx", deptID)
-		c.Header("Content-Disposition", "attachment; filename="+filename)
-		c.Data(http.StatusOK,
-			"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
-			buf.Bytes(),
+
+		sendAPIResponse(c, nil, nil, http.StatusOK)
+	}
+}
+
+// Вход: {"login": "...", "password": "..."} -> пара токенов
+func loginAPI(users UserRepository, tokens *tokenIssuer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Login    string `json:"login"`
+			Password string `json:"password"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.Login == "" || req.Password == "" {
+			sendAPIResponse(c, nil, fmt.Errorf("ожидается JSON с login и password"), http.StatusBadRequest)
+			return
+		}
+
+		u, err := users.GetByLogin(c.Request.Context(), req.Login)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			sendAPIResponse(c, nil, fmt.Errorf("ошибка входа: %v", err), http.StatusInternalServerError)
+			return
+		}
+		// Хэш проверяется и для несуществующего логина, чтобы не выдавать его временем ответа
+		if !checkPassword(u.PasswordHash, req.Password) {
+			log.Printf("Неудачный вход %q с %s", req.Login, c.ClientIP())
+			sendAPIResponse(c, nil, fmt.Errorf("неверный логин или пароль"), http.StatusUnauthorized)
+			return
+		}
+
+		sendTokenPair(c, users, tokens, u)
+	}
+}
+
+// Обновление токенов: {"refresh_token": "..."} -> новая пара, старый refresh-токен отзывается
+func refreshTokenAPI(users UserRepository, tokens *tokenIssuer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+			sendAPIResponse(c, nil, fmt.Errorf("ожидается JSON с refresh_token"), http.StatusBadRequest)
+			return
+		}
+
+		u, err := users.UseRefreshToken(c.Request.Context(), hashRefreshToken(req.RefreshToken))
+		if errors.Is(err, ErrNotFound) {
+			sendAPIResponse(c, nil, fmt.Errorf("refresh-токен недействителен"), http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("ошибка обновления токенов: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		sendTokenPair(c, users, tokens, u)
+	}
+}
+
+// Выход: {"refresh_token": "..."} — токен отзывается; access-токен истечёт сам
+func logoutAPI(users UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+			sendAPIResponse(c, nil, fmt.Errorf("ожидается JSON с refresh_token"), http.StatusBadRequest)
+			return
+		}
+		if err := users.RevokeRefreshToken(c.Request.Context(), hashRefreshToken(req.RefreshToken)); err != nil {
+			sendAPIResponse(c, nil, err, http.StatusInternalServerError)
+			return
+		}
+		sendAPIResponse(c, nil, nil, http.StatusOK)
+	}
+}
+
+// Текущий пользователь по access-токену
+func getCurrentUserAPI() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, _ := authUser(c)
+		sendAPIResponse(c, gin.H{
+			"id":         claims.UserID,
+			"login":      claims.Login,
+			"role":       claims.Role,
+			"expires_at": time.Unix(claims.ExpiresAt, 0).UTC(),
+		}, nil, http.StatusOK)
+	}
+}
+
+// Подписанная ссылка: {"path": "/api/employees/1/photo?size=thumb"} -> {url, expires_at}.
+// Ссылка открывается без заголовка Authorization (<img>, <a>) только для этого пути.
+func createSignedLinkAPI(tokens *tokenIssuer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Path string `json:"path"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.Path == "" {
+			sendAPIResponse(c, nil, fmt.Errorf("ожидается JSON с path"), http.StatusBadRequest)
+			return
+		}
+		target, err := url.Parse(req.Path)
+		if err != nil || target.IsAbs() || target.Host != "" || !strings.HasPrefix(target.Path, "/api/") || strings.HasPrefix(target.Path, "/api/auth/") {
+			sendAPIResponse(c, nil, fmt.Errorf("path: ожидается путь вида /api/..., получено %q", req.Path), http.StatusBadRequest)
+			return
+		}
+
+		claims, _ := authUser(c)
+		token, expires, err := tokens.signLink(claims, target.Path)
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("ошибка подписи ссылки: %v", err), http.StatusInternalServerError)
+			return
+		}
+		query := target.Query()
+		query.Set(linkParam, token)
+		target.RawQuery = query.Encode()
+
+		sendAPIResponse(c, gin.H{"url": target.String(), "expires_at": expires}, nil, http.StatusOK)
+	}
+}
+
+// sendTokenPair выпускает токены пользователю и сохраняет refresh-токен
+func sendTokenPair(c *gin.Context, users UserRepository, tokens *tokenIssuer, u User) {
+	pair, refresh, err := tokens.issue(u)
+	if err != nil {
+		sendAPIResponse(c, nil, fmt.Errorf("ошибка выпуска токенов: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := users.SaveRefreshToken(c.Request.Context(), refresh); err != nil {
+		sendAPIResponse(c, nil, err, http.StatusInternalServerError)
+		return
+	}
+	sendAPIResponse(c, pair, nil, http.StatusOK)
+}
+
+// Журнал аудита изменений: фильтры и страница (см. parseAuditQuery)
+func getAuditAPI(audit AuditRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q, err := parseAuditQuery(c)
+		if err != nil {
+			sendAPIResponse(c, nil, err, http.StatusBadRequest)
+			return
+		}
+
+		entries, total, err := audit.List(c.Request.Context(), q)
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("ошибка при получении журнала аудита: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if entries == nil {
+			entries = []AuditEntry{}
+		}
+
+		sendAPIPage(c, entries, PageMeta{Total: total, Limit: q.Limit, Offset: q.Offset})
+	}
+}
+
+// GET /employees/:id/photo?size=thumb|medium|original
+func getEmployeePhotoHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 1) Парсим ID сотрудника
+		empID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный ID"})
+			return
+		}
+
+		// Размер: thumb, medium или original (по умолчанию)
+		size := c.DefaultQuery("size", photoSizeOriginal)
+		if _, ok := photoSizes[size]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный size: thumb, medium или original"})
+			return
+		}
+
+		ctx := c.Request.Context()
+		key, foundExt, err := findEmployeePhoto(ctx, empID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("ошибка чтения фото: %v", err)})
+			return
+		}
+		if key == "" {
+			// Заглушка всегда лежит локально, а не в хранилище фото
+			c.Header("Content-Type", "image/png")
+			c.File(path.Join(storageEmployeeImages, defaultPhotoName))
+			return
+		}
+		if variant := photoVariantKey(key, size); variant != key {
+			// У фото, загруженных до появления размеров, есть только исходный файл
+			if _, err := photoStore.Stat(ctx, variant); err == nil {
+				key, foundExt = variant, ".jpg"
+			}
+		}
+
+		// S3: клиент скачивает фото по подписанной ссылке напрямую из хранилища
+		if photoPresignTTL > 0 {
+			link, err := photoStore.PresignGet(ctx, key, photoPresignTTL)
+			if err == nil {
+				c.Redirect(http.StatusFound, link)
+				return
+			} else if !errors.Is(err, errPresignUnsupported) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("ошибка чтения фото: %v", err)})
+				return
+			}
+		}
+
+		r, info, err := photoStore.Get(ctx, key)
+		if errors.Is(err, ErrNotFound) {
+			// Фото удалили между проверкой и чтением
+			c.JSON(http.StatusNotFound, gin.H{"error": "фото не найдено"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("ошибка чтения фото: %v", err)})
+			return
+		}
+		defer r.Close()
+
+		// Определяем Content-Type по расширению
+		contentTypes := map[string]string{
+			".jpg":  "image/jpeg",
+			".jpeg": "image/jpeg",
+			".png":  "image/png",
+			".gif":  "image/gif",
+			".bmp":  "image/bmp",
+			".webp": "image/webp",
+		}
+		contentType := contentTypes[foundExt]
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		c.DataFromReader(http.StatusOK, info.Size, contentType, r, nil)
+	}
+}
+
+// getEmployeeByDepartDocumentHandler возвращает список сотрудников отдела в DOCX
+// или, при ?format=pdf, в PDF. С ?template=<имя>[&version=<n>] DOCX строится
+// по загруженному шаблону (последняя версия, если version не задана).
+// ?photos=true добавляет в DOCX столбец с фото сотрудников.
+func getEmployeeByDepartDocumentHandler(repos Repositories) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 1) Парсим ID отдела, версию шаблона и режим с фото
+		deptID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("некорректный ID отдела"), http.StatusBadRequest)
+			return
+		}
+		version, err := strconv.Atoi(c.DefaultQuery("version", "0"))
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("некорректная версия шаблона"), http.StatusBadRequest)
+			return
+		}
+		photos, err := strconv.ParseBool(c.DefaultQuery("photos", "false"))
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("некорректный photos: true или false"), http.StatusBadRequest)
+			return
+		}
+
+		// 2) Проверяем параметры и формируем документ
+		task, code, err := departmentReportTask(c, repos, deptID,
+			c.DefaultQuery("format", "docx"), c.Query("template"), version, photos, c.Query("watermark"))
+		if err != nil {
+			sendAPIResponse(c, nil, err, code)
+			return
+		}
+		sendReport(c, repos, task)
+	}
+}
+
+// exportDepartmentsHandler выгружает все отделы в XLSX: лист на отдел и сводный лист
+func exportDepartmentsHandler(repos Repositories) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		task, code, err := exportReportTask(c, repos, c.Query("watermark"))
+		if err != nil {
+			sendAPIResponse(c, nil, err, code)
+			return
+		}
+		sendReport(c, repos, task)
+	}
+}
+
+// getCompanyReportHandler возвращает сводный DOCX-отчёт по всем отделам
+// или по выбранным (?departments=1,2,3): титульная страница, оглавление,
+// раздел на отдел и сводка по компании.
+func getCompanyReportHandler(repos Repositories) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Выбранные отделы; пустой список — все
+		var ids []int
+		if raw := c.Query("departments"); raw != "" {
+			for _, part := range strings.Split(raw, ",") {
+				id, err := strconv.Atoi(strings.TrimSpace(part))
+				if err != nil {
+					sendAPIResponse(c, nil, fmt.Errorf("некорректный ID отдела %q в departments", part), http.StatusBadRequest)
+					return
+				}
+				ids = append(ids, id)
+			}
+		}
+		task, code, err := companyReportTask(c, repos, ids, c.Query("watermark"))
+		if err != nil {
+			sendAPIResponse(c, nil, err, code)
+			return
+		}
+		sendReport(c, repos, task)
+	}
+}
+
+// sendReport формирует документ прямо в запросе и отдаёт его файлом
+// (асинхронно — через POST /api/reports, см. reportJobs)
+func sendReport(c *gin.Context, repos Repositories, task reportTask) {
+	buf, err := task.run(c.Request.Context(), repos.Employees, nil)
+	if err != nil {
+		sendRenderError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename="+task.filename)
+	c.Data(http.StatusOK, task.contentType, buf.Bytes())
+}
+
+// departmentReportTask проверяет параметры отчёта по отделу deptID: формат,
+// шаблон (template, version; 0 — последняя версия), столбец фото и водяной знак.
+// При ошибке возвращает код ответа.
+func departmentReportTask(c *gin.Context, repos Repositories, deptID int, format, template string, version int, photos bool, watermark string) (reportTask, int, error) {
+	ctx := c.Request.Context()
+
+	setup, ok := reportFormats[format]
+	if !ok {
+		return reportTask{}, http.StatusBadRequest, fmt.Errorf("некорректный format: docx, pdf или xlsx")
+	}
+	render := func(reports []departmentReport, meta documentMeta) (bytes.Buffer, error) {
+		return setup.render(documentRenderer, reports, meta)
+	}
+	// Фото встраиваются только во встроенный DOCX-отчёт
+	if photos && (format != "docx" || template != "") {
+		return reportTask{}, http.StatusBadRequest, fmt.Errorf("photos применим только к format=docx без шаблона")
+	}
+
+	// Пользовательский шаблон — только для DOCX
+	if template != "" {
+		if format != "docx" {
+			return reportTask{}, http.StatusBadRequest, fmt.Errorf("шаблон применим только к format=docx")
+		}
+		if version < 0 {
+			return reportTask{}, http.StatusBadRequest, fmt.Errorf("некорректная версия шаблона")
+		}
+		t, err := repos.Templates.Get(ctx, template, version)
+		if errors.Is(err, ErrNotFound) {
+			return reportTask{}, http.StatusNotFound, errTemplateNotFound(template, version)
+		} else if err != nil {
+			return reportTask{}, http.StatusInternalServerError, err
+		}
+		render = func(reports []departmentReport, meta documentMeta) (bytes.Buffer, error) {
+			return documentRenderer.TemplateDOCX(t.Content, reports[0], meta)
+		}
+	}
+
+	// Берём из ОТДЕЛЫ ожидаемые значения и boss_id
+	dept, err := repos.Departments.Get(ctx, deptID)
+	if errors.Is(err, ErrNotFound) {
+		return reportTask{}, http.StatusNotFound, fmt.Errorf("отдел №%d не найден", deptID)
+	} else if err != nil {
+		return reportTask{}, http.StatusInternalServerError, fmt.Errorf("ошибка запроса отдела: %v", err)
+	}
+	// Без прав на отдел зарплаты в документе показываются диапазонами
+	visibility, err := newSalaryVisibility(c, repos.Departments)
+	if err != nil {
+		return reportTask{}, http.StatusInternalServerError, err
+	}
+	meta, err := newDocumentMeta(c, fmt.Sprintf("Отдел №%d «%s»", dept.ID, dept.Name), watermark)
+	if err != nil {
+		return reportTask{}, http.StatusBadRequest, err
+	}
+
+	return reportTask{
+		kind:        reportKindDepartment,
+		filename:    fmt.Sprintf("department_%d_employees.%s", deptID, format),
+		contentType: setup.contentType,
+		departments: []Department{dept},
+		photos:      photos,
+		visibility:  visibility,
+		meta:        meta,
+		render:      render,
+	}, 0, nil
+}
+
+// exportReportTask готовит выгрузку всех отделов в XLSX
+func exportReportTask(c *gin.Context, repos Repositories, watermark string) (reportTask, int, error) {
+	meta, err := newDocumentMeta(c, "Отделы", watermark)
+	if err != nil {
+		return reportTask{}, http.StatusBadRequest, err
+	}
+	visibility, err := newSalaryVisibility(c, repos.Departments)
+	if err != nil {
+		return reportTask{}, http.StatusInternalServerError, err
+	}
+	deps, err := repos.Departments.List(c.Request.Context())
+	if err != nil {
+		return reportTask{}, http.StatusInternalServerError, fmt.Errorf("ошибка при получении отделов: %v", err)
+	}
+
+	return reportTask{
+		kind:        reportKindExport,
+		filename:    "departments.xlsx",
+		contentType: reportFormats["xlsx"].contentType,
+		departments: deps,
+		visibility:  visibility,
+		meta:        meta,
+		render:      documentRenderer.DepartmentsXLSX,
+	}, 0, nil
+}
+
+// companyReportTask готовит сводный отчёт по отделам ids (пустой список — все отделы)
+func companyReportTask(c *gin.Context, repos Repositories, ids []int, watermark string) (reportTask, int, error) {
+	selected := map[int]bool{}
+	for _, id := range ids {
+		if id <= 0 {
+			return reportTask{}, http.StatusBadRequest, fmt.Errorf("некорректный ID отдела %d в departments", id)
+		}
+		selected[id] = true
+	}
+	meta, err := newDocumentMeta(c, companyReportTitle, watermark)
+	if err != nil {
+		return reportTask{}, http.StatusBadRequest, err
+	}
+	visibility, err := newSalaryVisibility(c, repos.Departments)
+	if err != nil {
+		return reportTask{}, http.StatusInternalServerError, err
+	}
+	deps, err := repos.Departments.List(c.Request.Context())
+	if err != nil {
+		return reportTask{}, http.StatusInternalServerError, fmt.Errorf("ошибка при получении отделов: %v", err)
+	}
+
+	// Отделы отчёта в порядке ID
+	chosen := make([]Department, 0, len(deps))
+	for _, d := range deps {
+		if len(selected) > 0 && !selected[d.ID] {
+			continue
+		}
+		delete(selected, d.ID)
+		chosen = append(chosen, d)
+	}
+	if len(selected) > 0 {
+		missing := make([]int, 0, len(selected))
+		for id := range selected {
+			missing = append(missing, id)
+		}
+		sort.Ints(missing)
+		return reportTask{}, http.StatusNotFound, fmt.Errorf("отделы не найдены: %v", missing)
+	}
+
+	return reportTask{
+		kind:        reportKindCompany,
+		filename:    "company_report.docx",
+		contentType: reportFormats["docx"].contentType,
+		departments: chosen,
+		visibility:  visibility,
+		meta:        meta,
+		render:      documentRenderer.CompanyDOCX,
+	}, 0, nil
+}
+
+// createReportJobAPI ставит отчёт в очередь на формирование и сразу отвечает 202
+// с заданием. Тело: {"kind": "department"|"company"|"export", ...} с теми же
+// параметрами, что у синхронных эндпоинтов; они проверяются до постановки в очередь.
+func createReportJobAPI(repos Repositories, jobs *reportJobs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Kind        string `json:"kind" binding:"required"`
+			DeptID      int    `json:"dept_id"`     // department
+			Format      string `json:"format"`      // department: docx (по умолчанию), pdf, xlsx
+			Template    string `json:"template"`    // department, только docx
+			Version     int    `json:"version"`     // версия шаблона, 0 — последняя
+			Photos      bool   `json:"photos"`      // department, только docx без шаблона
+			Departments []int  `json:"departments"` // company; пусто — все отделы
+			Watermark   string `json:"watermark"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("некорректные данные: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		var (
+			task reportTask
+			code int
+			err  error
 		)
+		switch req.Kind {
+		case reportKindDepartment:
+			if req.Format == "" {
+				req.Format = "docx"
+			}
+			task, code, err = departmentReportTask(c, repos, req.DeptID, req.Format, req.Template, req.Version, req.Photos, req.Watermark)
+		case reportKindCompany:
+			task, code, err = companyReportTask(c, repos, req.Departments, req.Watermark)
+		case reportKindExport:
+			task, code, err = exportReportTask(c, repos, req.Watermark)
+		default:
+			code, err = http.StatusBadRequest, fmt.Errorf("некорректный kind: department, company или export")
+		}
+		if err != nil {
+			sendAPIResponse(c, nil, err, code)
+			return
+		}
+
+		job, err := jobs.submit(task)
+		if errors.Is(err, errReportQueueFull) {
+			sendAPIResponse(c, nil, err, http.StatusServiceUnavailable)
+			return
+		} else if err != nil {
+			sendAPIResponse(c, nil, err, http.StatusInternalServerError)
+			return
+		}
+		c.Header("Location", "/api/reports/"+job.ID)
+		sendAPIResponse(c, job, nil, http.StatusAccepted)
+	}
+}
+
+// getReportJobAPI состояние и прогресс задания
+func getReportJobAPI(jobs *reportJobs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, ok := reportJobFor(c, jobs)
+		if !ok {
+			return
+		}
+		sendAPIResponse(c, job, nil, http.StatusOK)
+	}
+}
+
+// downloadReportJobHandler отдаёт готовый отчёт; до завершения задания — 409
+func downloadReportJobHandler(jobs *reportJobs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, ok := reportJobFor(c, jobs)
+		if !ok {
+			return
+		}
+		switch job.Status {
+		case reportJobDone:
+		case reportJobFailed:
+			sendAPIResponse(c, nil, fmt.Errorf("отчёт не сформирован: %s", job.Error), http.StatusConflict)
+			return
+		default:
+			sendAPIResponse(c, nil, fmt.Errorf("отчёт ещё не готов (%s, %d%%)", job.Status, job.Progress), http.StatusConflict)
+			return
+		}
+		r, info, err := jobs.open(c.Request.Context(), job)
+		if errors.Is(err, ErrNotFound) {
+			// Удалён, чтобы уложиться в reports.max_storage_mb
+			sendAPIResponse(c, nil, fmt.Errorf("задание %s не найдено или истекло", job.ID), http.StatusNotFound)
+			return
+		} else if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("ошибка чтения отчёта: %v", err), http.StatusInternalServerError)
+			return
+		}
+		defer r.Close()
+		c.Header("Content-Disposition", "attachment; filename="+job.Filename)
+		c.DataFromReader(http.StatusOK, info.Size, job.contentType, r, nil)
+	}
+}
+
+// reportJobFor задание :id, если оно доступно текущему пользователю: автору или
+// администратору (зарплаты в отчёте замаскированы по правам автора). Иначе отвечает 404.
+func reportJobFor(c *gin.Context, jobs *reportJobs) (ReportJob, bool) {
+	id := c.Param("id")
+	job, ok := jobs.get(id)
+	claims, _ := authUser(c)
+	if !ok || job.CreatedBy != claims.Login && claims.Role != roleAdmin {
+		sendAPIResponse(c, nil, fmt.Errorf("задание %s не найдено или истекло", id), http.StatusNotFound)
+		return ReportJob{}, false
+	}
+	return job, true
+}
+
+// uploadTemplateAPI сохраняет новую версию DOCX-шаблона отчёта:
+// multipart-поля name и file. Шаблон с неизвестными плейсхолдерами не принимается.
+func uploadTemplateAPI(templates TemplateRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.PostForm("name")
+		if !templateNamePattern.MatchString(name) {
+			sendAPIResponse(c, nil, fmt.Errorf("некорректное имя шаблона: латиница в нижнем регистре, цифры, '-' и '_', до 64 символов"), http.StatusBadRequest)
+			return
+		}
+		header, err := c.FormFile("file")
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("не передан файл file"), http.StatusBadRequest)
+			return
+		}
+		if !strings.EqualFold(path.Ext(header.Filename), ".docx") {
+			sendAPIResponse(c, nil, fmt.Errorf("неподдерживаемый формат файла: ожидается .docx"), http.StatusBadRequest)
+			return
+		}
+		if header.Size > maxTemplateSize {
+			sendAPIResponse(c, nil, fmt.Errorf("файл больше %d МБ", maxTemplateSize>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+		f, err := header.Open()
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("не удалось открыть загруженный файл: %v", err), http.StatusBadRequest)
+			return
+		}
+		content, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			sendAPIResponse(c, nil, fmt.Errorf("ошибка чтения файла: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := documentRenderer.ValidateTemplate(content); errors.Is(err, errRenderUnavailable) {
+			sendAPIResponse(c, nil, err, http.StatusServiceUnavailable)
+			return
+		} else if err != nil {
+			sendAPIResponse(c, nil, err, http.StatusUnprocessableEntity)
+			return
+		}
+
+		t, err := templates.Create(c.Request.Context(), ReportTemplate{Name: name, Content: content})
+		if errors.Is(err, ErrConflict) {
+			sendAPIResponse(c, nil, err, http.StatusConflict)
+			return
+		} else if err != nil {
+			sendAPIResponse(c, nil, err, http.StatusInternalServerError)
+			return
+		}
+		sendAPIResponse(c, t, nil, http.StatusCreated)
+	}
+}
+
+// listTemplatesAPI возвращает все версии шаблонов отчётов (новые версии первыми)
+func listTemplatesAPI(templates TemplateRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := templates.List(c.Request.Context())
+		if err != nil {
+			sendAPIResponse(c, nil, err, http.StatusInternalServerError)
+			return
+		}
+		sendAPIResponse(c, list, nil, http.StatusOK)
+	}
+}
+
+// downloadTemplateHandler отдаёт файл шаблона (?version=, по умолчанию последняя)
+func downloadTemplateHandler(templates TemplateRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		version, err := strconv.Atoi(c.DefaultQuery("version", "0"))
+		if err != nil || version < 0 {
+			sendAPIResponse(c, nil, fmt.Errorf("некорректная версия шаблона"), http.StatusBadRequest)
+			return
+		}
+		t, err := templates.Get(c.Request.Context(), name, version)
+		if errors.Is(err, ErrNotFound) {
+			sendAPIResponse(c, nil, errTemplateNotFound(name, version), http.StatusNotFound)
+			return
+		} else if err != nil {
+			sendAPIResponse(c, nil, err, http.StatusInternalServerError)
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s_v%d.docx", t.Name, t.Version))
+		c.Data(http.StatusOK, reportFormats["docx"].contentType, t.Content)
+	}
+}
+
+// watermarks водяные знаки документов (?watermark=, documents.watermark)
+var watermarks = map[string]string{
+	"confidential": "КОНФИДЕНЦИАЛЬНО",
+	"draft":        "ЧЕРНОВИК",
+	"none":         "",
+}
+
+// newDocumentMeta колонтитулы документа с заголовком title от имени текущего пользователя.
+// Водяной знак — ключ watermarks; пустой — documents.watermark.
+func newDocumentMeta(c *gin.Context, title, watermarkKey string) (documentMeta, error) {
+	if watermarkKey == "" {
+		watermarkKey = documentSettings.Watermark
+	}
+	watermark, ok := watermarks[watermarkKey]
+	if !ok {
+		return documentMeta{}, fmt.Errorf("некорректный watermark: confidential, draft или none")
+	}
+	claims, _ := authUser(c)
+	return documentMeta{
+		Company:     documentSettings.CompanyName,
+		Title:       title,
+		GeneratedAt: time.Now(),
+		GeneratedBy: claims.Login,
+		Watermark:   watermark,
+	}, nil
+}
+
+// reportFormats форматы выгрузки отчёта по отделу (?format=)
+var reportFormats = map[string]struct {
+	contentType string
+	render      func(DocumentRenderer, []departmentReport, documentMeta) (bytes.Buffer, error)
+}{
+	"docx": {"application/vnd.openxmlformats-officedocument.wordprocessingml.document", singleReport(DocumentRenderer.DepartmentDOCX)},
+	"pdf":  {"application/pdf", singleReport(DocumentRenderer.DepartmentPDF)},
+	"xlsx": {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", DocumentRenderer.DepartmentsXLSX},
+}
+
+// singleReport адаптер для форматов, в которых один документ — один отдел
+func singleReport(setup func(DocumentRenderer, departmentReport, documentMeta) (bytes.Buffer, error)) func(DocumentRenderer, []departmentReport, documentMeta) (bytes.Buffer, error) {
+	return func(r DocumentRenderer, reports []departmentReport, meta documentMeta) (bytes.Buffer, error) {
+		return setup(r, reports[0], meta)
+	}
+}
+
+// sendRenderError ответ на ошибку формирования документа:
+// 503, если формат недоступен без лицензии unidoc
+func sendRenderError(c *gin.Context, err error) {
+	if errors.Is(err, errRenderUnavailable) {
+		sendAPIResponse(c, nil, err, http.StatusServiceUnavailable)
+		return
+	}
+	sendAPIResponse(c, nil, fmt.Errorf("ошибка создания документа: %v", err), http.StatusInternalServerError)
+}
+
+// buildDepartmentReport собирает содержимое отчёта по отделу d.
+// Пустой отдел допустим: отчёт содержит только шапку таблицы и нулевую статистику.
+func buildDepartmentReport(ctx context.Context, employees EmployeeRepository, d Department, visibility salaryVisibility) (departmentReport, error) {
+	report := departmentReport{
+		DeptID:          d.ID,
+		DeptName:        d.Name,
+		ExpectedSalary:  d.TotalSalary,
+		ExpectedCount:   d.Size,
+		SalariesVisible: visibility.department(d.ID),
+		FormatSalary:    visibility.formatter(d.ID),
+	}
+	if d.BossID != nil {
+		report.BossID = sql.NullInt64{Int64: int64(*d.BossID), Valid: true}
+	}
+
+	emps, err := employees.ListByDepartment(ctx, d.ID)
+	if err != nil {
+		return report, fmt.Errorf("ошибка запроса сотрудников: %v", err)
+	}
+	for _, e := range emps {
+		report.Employees = append(report.Employees, emp{ID: e.ID, Name: e.Name, Status: e.Status, Salary: e.Salary})
+		report.ActualSalary += e.Salary
+	}
+	report.ActualCount = len(report.Employees)
+	return report, nil
+}
+
+// checkEmployeeScope проверяет право изменить сотрудника empID с переводом в отдел newDeptID.
+// Роль hr проходит всегда; руководителю нужны права на текущий и новый отдел.
+// Это ранний ответ с понятной ошибкой: под блокировкой права проверяет Update (withManagerScope).
+func checkEmployeeScope(c *gin.Context, repos Repositories, empID, newDeptID int) (int, error) {
+	claims, _ := authUser(c)
+	if roleLevels[claims.Role] >= roleLevels[roleHR] {
+		return 0, nil
+	}
+	if claims.EmpID != nil && *claims.EmpID == empID {
+		return http.StatusForbidden, fmt.Errorf("недостаточно прав: руководитель не может изменять свои данные")
+	}
+
+	ctx := c.Request.Context()
+	e, err := repos.Employees.Get(ctx, empID)
+	if err != nil {
+		return notFoundOr(err, http.StatusInternalServerError), fmt.Errorf("сотрудник с ID %d не найден: %v", empID, err)
+	}
+	for _, deptID := range []int{e.DeptID, newDeptID} {
+		d, err := repos.Departments.Get(ctx, deptID)
+		if errors.Is(err, ErrNotFound) {
+			return http.StatusForbidden, errDepartmentScope
+		} else if err != nil {
+			return http.StatusInternalServerError, fmt.Errorf("ошибка запроса отдела: %v", err)
+		}
+		if !managesDepartment(c, d) {
+			return http.StatusForbidden, errDepartmentScope
+		}
+	}
+	return 0, nil
+}
+
+// notFoundOr возвращает 404 для ErrNotFound и code для остальных ошибок
+func notFoundOr(err error, code int) int {
+	if errors.Is(err, ErrNotFound) {
+		return http.StatusNotFound
 	}
+	return code
 }
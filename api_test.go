@@ -0,0 +1,572 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testServer API поверх репозиториев в памяти (newMemoryRepositories)
+type testServer struct {
+	t      *testing.T
+	router *gin.Engine
+	repos  Repositories
+	tokens *tokenIssuer
+	jobs   *reportJobs
+}
+
+// testResponse разобранный APIResponse
+type testResponse struct {
+	Code    int
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Meta    json.RawMessage `json:"meta"`
+	Error   string          `json:"error"`
+	Body    []byte          `json:"-"`
+	Header  http.Header     `json:"-"`
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	repos := newMemoryRepositories()
+	tokens := newTokenIssuer(AuthConfig{
+		JWTSecret:  strings.Repeat("k", 32),
+		AccessTTL:  15 * time.Minute,
+		RefreshTTL: time.Hour,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	jobs := newReportJobs(repos.Employees, localBlobStore{dir: t.TempDir()}, ReportsConfig{
+		Workers:      1,
+		Queue:        10,
+		TTL:          time.Hour,
+		MaxStorageMB: 8,
+	})
+	jobs.start(ctx, 1)
+
+	// Фото сотрудников — во временной директории теста
+	prevStore := photoStore
+	photoStore = localBlobStore{dir: t.TempDir()}
+	t.Cleanup(func() { photoStore = prevStore })
+
+	r := gin.New()
+	setupAPIRoutes(r, repos, tokens, jobs)
+	return &testServer{t: t, router: r, repos: repos, tokens: tokens, jobs: jobs}
+}
+
+// token access-токен пользователя login с ролью role (empID — связанный сотрудник)
+func (s *testServer) token(login, role string, empID *int) string {
+	s.t.Helper()
+	now := time.Now()
+	token, err := s.tokens.sign(tokenClaims{
+		UserID:    1,
+		Login:     login,
+		Role:      role,
+		EmpID:     empID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Minute).Unix(),
+	})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return token
+}
+
+// do выполняет запрос; token == "" — без заголовка Authorization
+func (s *testServer) do(method, target, token string, body io.Reader, contentType string) testResponse {
+	s.t.Helper()
+	req := httptest.NewRequest(method, target, body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	resp := testResponse{Code: w.Code, Body: w.Body.Bytes(), Header: w.Header()}
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(resp.Body, &resp); err != nil {
+			s.t.Fatalf("%s %s: некорректный JSON %q: %v", method, target, resp.Body, err)
+		}
+	}
+	return resp
+}
+
+// json выполняет запрос с JSON-телом (nil — без тела)
+func (s *testServer) json(method, target, token string, body interface{}) testResponse {
+	s.t.Helper()
+	if body == nil {
+		return s.do(method, target, token, nil, "")
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return s.do(method, target, token, bytes.NewReader(data), "application/json")
+}
+
+// form выполняет multipart-запрос с полями fields
+func (s *testServer) form(method, target, token string, fields map[string]string) testResponse {
+	s.t.Helper()
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	mw.Close()
+	return s.do(method, target, token, body, mw.FormDataContentType())
+}
+
+// decode разбирает Data ответа в v
+func (r testResponse) decode(t *testing.T, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.Data, v); err != nil {
+		t.Fatalf("некорректные данные ответа %s: %v", r.Data, err)
+	}
+}
+
+// expect проверяет код ответа
+func (r testResponse) expect(t *testing.T, code int) testResponse {
+	t.Helper()
+	if r.Code != code {
+		t.Fatalf("код ответа %d, ожидался %d: %s", r.Code, code, r.Body)
+	}
+	return r
+}
+
+// createDepartment добавляет отдел через API и возвращает его ID
+func (s *testServer) createDepartment(name string) int {
+	s.t.Helper()
+	var d struct {
+		ID int `json:"id"`
+	}
+	s.json(http.MethodPost, "/api/departments", s.token("hr", roleHR, nil), gin.H{"name": name}).
+		expect(s.t, http.StatusCreated).decode(s.t, &d)
+	return d.ID
+}
+
+// createEmployee добавляет сотрудника через API и возвращает его ID
+func (s *testServer) createEmployee(name string, salary float64, deptID int) int {
+	s.t.Helper()
+	var e struct {
+		ID int `json:"id"`
+	}
+	s.form(http.MethodPost, "/api/employees", s.token("hr", roleHR, nil), map[string]string{
+		"name":    name,
+		"status":  "active",
+		"salary":  strconv.FormatFloat(salary, 'f', -1, 64),
+		"dept_id": strconv.Itoa(deptID),
+	}).expect(s.t, http.StatusCreated).decode(s.t, &e)
+	return e.ID
+}
+
+func TestEmployeeLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	hr := s.token("hr", roleHR, nil)
+	dept := s.createDepartment("Бухгалтерия")
+	other := s.createDepartment("Склад")
+
+	id := s.createEmployee("Смирнова Ольга", 100000, dept)
+	s.createEmployee("Кузнецов Иван", 50000, dept)
+
+	var e EmployeeView
+	s.json(http.MethodGet, "/api/employees/"+strconv.Itoa(id), hr, nil).expect(t, http.StatusOK).decode(t, &e)
+	if e.Name != "Смирнова Ольга" || e.DeptID != dept || e.Salary == nil || *e.Salary != 100000 {
+		t.Fatalf("сотрудник %+v", e)
+	}
+
+	// Перевод в другой отдел
+	s.form(http.MethodPut, "/api/employees/"+strconv.Itoa(id), hr, map[string]string{
+		"name": "Смирнова Ольга", "status": "active", "salary": "120000", "dept_id": strconv.Itoa(other),
+	}).expect(t, http.StatusOK)
+	for deptID, want := range map[int]string{dept: "Кузнецов Иван", other: "Смирнова Ольга"} {
+		var emps []EmployeeView
+		s.json(http.MethodGet, "/api/employeesByDepart/"+strconv.Itoa(deptID), hr, nil).expect(t, http.StatusOK).decode(t, &emps)
+		if len(emps) != 1 || emps[0].Name != want {
+			t.Fatalf("отдел %d: %+v, ожидался %s", deptID, emps, want)
+		}
+	}
+
+	s.json(http.MethodDelete, "/api/employees/"+strconv.Itoa(id), hr, nil).expect(t, http.StatusOK)
+	s.json(http.MethodGet, "/api/employees/"+strconv.Itoa(id), hr, nil).expect(t, http.StatusNotFound)
+	s.json(http.MethodDelete, "/api/employees/"+strconv.Itoa(id), hr, nil).expect(t, http.StatusNotFound)
+}
+
+func TestDepartmentsAPI(t *testing.T) {
+	s := newTestServer(t)
+	hr, admin := s.token("hr", roleHR, nil), s.token("admin", roleAdmin, nil)
+	dept := s.createDepartment("Бухгалтерия")
+	other := s.createDepartment("Склад")
+	s.createEmployee("Смирнова Ольга", 100000, dept)
+	s.createEmployee("Кузнецов Иван", 50000, dept)
+
+	s.json(http.MethodPut, "/api/departments/"+strconv.Itoa(dept), hr, gin.H{"description": "Учёт", "cost_center": "ЦФО-1"}).
+		expect(t, http.StatusOK)
+	d, err := s.repos.Departments.Get(context.Background(), dept)
+	if err != nil || d.Name != "Бухгалтерия" || d.Description == nil || *d.Description != "Учёт" {
+		t.Fatalf("отдел %+v: %v", d, err)
+	}
+
+	// refuse по умолчанию: в отделе остались сотрудники
+	s.json(http.MethodDelete, "/api/departments/"+strconv.Itoa(dept), admin, nil).expect(t, http.StatusConflict)
+	s.json(http.MethodDelete, "/api/departments/"+strconv.Itoa(dept)+"?policy=reassign&target="+strconv.Itoa(dept), admin, nil).
+		expect(t, http.StatusBadRequest)
+	s.json(http.MethodDelete, "/api/departments/"+strconv.Itoa(dept)+"?policy=reassign&target="+strconv.Itoa(other), admin, nil).
+		expect(t, http.StatusOK)
+
+	var emps []EmployeeView
+	s.json(http.MethodGet, "/api/employeesByDepart/"+strconv.Itoa(other), hr, nil).expect(t, http.StatusOK).decode(t, &emps)
+	if len(emps) != 2 {
+		t.Fatalf("после перевода в отделе %d сотрудников, ожидалось 2", len(emps))
+	}
+	if _, err := s.repos.Departments.Get(context.Background(), dept); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("отдел не удалён: %v", err)
+	}
+}
+
+func TestEmptyDepartment(t *testing.T) {
+	s := newTestServer(t)
+	hr := s.token("hr", roleHR, nil)
+	dept := s.createDepartment("Новый отдел")
+
+	// Пустой отдел — пустой список, несуществующий — 404
+	resp := s.json(http.MethodGet, "/api/employeesByDepart/"+strconv.Itoa(dept), hr, nil).expect(t, http.StatusOK)
+	if string(resp.Data) != "[]" {
+		t.Fatalf("пустой отдел: %s", resp.Data)
+	}
+	s.json(http.MethodGet, "/api/employeesByDepart/999", hr, nil).expect(t, http.StatusNotFound)
+
+	// Уволенный последний сотрудник оставляет отдел пустым
+	id := s.createEmployee("Единственный", 1000, dept)
+	s.json(http.MethodDelete, "/api/employees/"+strconv.Itoa(id), hr, nil).expect(t, http.StatusOK)
+	d, err := s.repos.Departments.Get(context.Background(), dept)
+	if err != nil || d.Size != 0 || d.TotalSalary != 0 {
+		t.Fatalf("отдел %+v: %v", d, err)
+	}
+}
+
+func TestEmployeesCursorPagination(t *testing.T) {
+	s := newTestServer(t)
+	hr := s.token("hr", roleHR, nil)
+	dept := s.createDepartment("Отдел")
+	for i, name := range []string{"Д", "Б", "А", "Г", "В"} {
+		s.createEmployee(name, float64(1000*(i+1)), dept)
+	}
+
+	var names []string
+	target := "/api/employees?sort=name&limit=2"
+	for range 5 {
+		resp := s.json(http.MethodGet, target, hr, nil).expect(t, http.StatusOK)
+		var page []EmployeeView
+		var meta PageMeta
+		resp.decode(t, &page)
+		if err := json.Unmarshal(resp.Meta, &meta); err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range page {
+			names = append(names, e.Name)
+		}
+		if meta.Total != 5 {
+			t.Fatalf("total = %d", meta.Total)
+		}
+		if meta.NextCursor == "" {
+			break
+		}
+		target = "/api/employees?sort=name&limit=2&cursor=" + meta.NextCursor
+	}
+	if got := strings.Join(names, ""); got != "АБВГД" {
+		t.Fatalf("порядок по страницам %q", got)
+	}
+
+	// Курсор другой сортировки не принимается
+	var meta PageMeta
+	resp := s.json(http.MethodGet, "/api/employees?sort=name&limit=2", hr, nil)
+	json.Unmarshal(resp.Meta, &meta)
+	s.json(http.MethodGet, "/api/employees?sort=-name&limit=2&cursor="+meta.NextCursor, hr, nil).expect(t, http.StatusBadRequest)
+}
+
+func TestSearchEmployeesAPI(t *testing.T) {
+	s := newTestServer(t)
+	viewer := s.token("v", roleViewer, nil)
+	dept := s.createDepartment("Отдел")
+	s.createEmployee("Иванов Пётр", 1000, dept)
+	s.createEmployee("Петрова Анна", 1000, dept)
+
+	var results []EmployeeSearchView
+	s.json(http.MethodGet, "/api/employees/search?q=ivanov", viewer, nil).expect(t, http.StatusOK).decode(t, &results)
+	if len(results) != 1 || results[0].Employee.Name != "Иванов Пётр" {
+		t.Fatalf("результаты %+v", results)
+	}
+	if results[0].Employee.Salary != nil {
+		t.Fatal("зарплата в поиске не скрыта")
+	}
+
+	s.json(http.MethodGet, "/api/employees/search", viewer, nil).expect(t, http.StatusBadRequest)
+	s.json(http.MethodGet, "/api/employees/search?q=a&limit=1000", viewer, nil).expect(t, http.StatusBadRequest)
+}
+
+func TestAuditAPI(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.token("admin", roleAdmin, nil)
+	dept := s.createDepartment("Отдел")
+	id := s.createEmployee("Сотрудник", 1000, dept)
+	s.form(http.MethodPut, "/api/employees/"+strconv.Itoa(id), s.token("anna", roleHR, nil), map[string]string{
+		"name": "Сотрудник", "status": "active", "salary": "1500", "dept_id": strconv.Itoa(dept),
+	}).expect(t, http.StatusOK)
+
+	// Исполнитель — пользователь из токена, изменения — до и после
+	var entries []AuditEntry
+	s.json(http.MethodGet, "/api/audit?entity=employee&entity_id="+strconv.Itoa(id), admin, nil).
+		expect(t, http.StatusOK).decode(t, &entries)
+	if len(entries) != 2 || entries[0].Operation != auditUpdate || entries[0].Actor != "user:anna" || entries[1].Actor != "user:hr" {
+		t.Fatalf("журнал %+v", entries)
+	}
+	if ch := entries[0].Changes["salary"]; string(ch.Old) != "1000" || string(ch.New) != "1500" {
+		t.Fatalf("изменение зарплаты %+v", ch)
+	}
+
+	s.json(http.MethodGet, "/api/audit?actor=user:anna", admin, nil).expect(t, http.StatusOK).decode(t, &entries)
+	if len(entries) != 1 {
+		t.Fatalf("фильтр по исполнителю: %+v", entries)
+	}
+	s.json(http.MethodGet, "/api/audit?entity=salary", admin, nil).expect(t, http.StatusBadRequest)
+}
+
+func TestSalaryScheduleAPI(t *testing.T) {
+	s := newTestServer(t)
+	hr := s.token("hr", roleHR, nil)
+	id := s.createEmployee("Сотрудник", 1000, s.createDepartment("Отдел"))
+	history := "/api/employees/" + strconv.Itoa(id) + "/salary-history"
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(time.DateOnly)
+
+	s.json(http.MethodPost, history, hr, gin.H{"salary": 2000, "effective_date": today()}).expect(t, http.StatusBadRequest)
+	s.json(http.MethodPost, history, hr, gin.H{"salary": -1, "effective_date": tomorrow}).expect(t, http.StatusBadRequest)
+	s.json(http.MethodPost, "/api/employees/999/salary-history", hr, gin.H{"salary": 2000, "effective_date": tomorrow}).
+		expect(t, http.StatusNotFound)
+
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	s.json(http.MethodPost, history, hr, gin.H{"salary": 2000, "effective_date": tomorrow, "reason": "повышение"}).
+		expect(t, http.StatusCreated).decode(t, &created)
+
+	var changes []SalaryChange
+	s.json(http.MethodGet, history, hr, nil).expect(t, http.StatusOK).decode(t, &changes)
+	if len(changes) != 2 || changes[1].AppliedAt != nil || changes[1].Reason != "повышение" {
+		t.Fatalf("история %+v", changes)
+	}
+
+	s.json(http.MethodDelete, history+"/"+strconv.FormatInt(created.ID, 10), hr, nil).expect(t, http.StatusOK)
+	s.json(http.MethodDelete, history+"/"+strconv.FormatInt(created.ID, 10), hr, nil).expect(t, http.StatusConflict)
+	s.json(http.MethodDelete, history+"/999", hr, nil).expect(t, http.StatusNotFound)
+	// Немедленное изменение при приёме уже применено
+	s.json(http.MethodDelete, history+"/"+strconv.FormatInt(changes[0].ID, 10), hr, nil).expect(t, http.StatusConflict)
+
+	// Отменённое изменение остаётся в истории с отметкой
+	s.json(http.MethodGet, history, hr, nil).expect(t, http.StatusOK).decode(t, &changes)
+	if len(changes) != 2 || changes[1].CancelledAt == nil || changes[1].CancelledBy != "user:hr" {
+		t.Fatalf("история после отмены %+v", changes)
+	}
+}
+
+func TestAuthRequired(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		token  string
+		code   int
+	}{
+		{"без токена", http.MethodGet, "/api/departments", "", http.StatusUnauthorized},
+		{"некорректный токен", http.MethodGet, "/api/departments", "abc", http.StatusUnauthorized},
+		{"viewer читает", http.MethodGet, "/api/departments", s.token("v", roleViewer, nil), http.StatusOK},
+		{"viewer не создаёт отдел", http.MethodPost, "/api/departments", s.token("v", roleViewer, nil), http.StatusForbidden},
+		{"hr не удаляет отдел", http.MethodDelete, "/api/departments/1", s.token("h", roleHR, nil), http.StatusForbidden},
+		{"hr не читает аудит", http.MethodGet, "/api/audit", s.token("h", roleHR, nil), http.StatusForbidden},
+		{"admin читает аудит", http.MethodGet, "/api/audit", s.token("a", roleAdmin, nil), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.json(tt.method, tt.target, tt.token, nil).expect(t, tt.code)
+		})
+	}
+}
+
+func TestLoginRefreshLogout(t *testing.T) {
+	s := newTestServer(t)
+	hash, err := hashPassword("correct-horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.repos.Users.Create(context.Background(), User{Login: "anna", PasswordHash: hash, Role: roleHR}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.json(http.MethodPost, "/api/auth/login", "", gin.H{"login": "anna", "password": "wrong-pass"}).expect(t, http.StatusUnauthorized)
+	s.json(http.MethodPost, "/api/auth/login", "", gin.H{"login": "nobody", "password": "correct-horse"}).expect(t, http.StatusUnauthorized)
+
+	var first TokenPair
+	s.json(http.MethodPost, "/api/auth/login", "", gin.H{"login": "anna", "password": "correct-horse"}).
+		expect(t, http.StatusOK).decode(t, &first)
+
+	var me struct {
+		Login string `json:"login"`
+		Role  string `json:"role"`
+	}
+	s.json(http.MethodGet, "/api/auth/me", first.AccessToken, nil).expect(t, http.StatusOK).decode(t, &me)
+	if me.Login != "anna" || me.Role != roleHR {
+		t.Fatalf("auth/me = %+v", me)
+	}
+
+	var second TokenPair
+	s.json(http.MethodPost, "/api/auth/refresh", "", gin.H{"refresh_token": first.RefreshToken}).
+		expect(t, http.StatusOK).decode(t, &second)
+
+	// Повторное предъявление использованного токена отзывает и выданный взамен
+	s.json(http.MethodPost, "/api/auth/refresh", "", gin.H{"refresh_token": first.RefreshToken}).expect(t, http.StatusUnauthorized)
+	s.json(http.MethodPost, "/api/auth/refresh", "", gin.H{"refresh_token": second.RefreshToken}).expect(t, http.StatusUnauthorized)
+
+	var third TokenPair
+	s.json(http.MethodPost, "/api/auth/login", "", gin.H{"login": "anna", "password": "correct-horse"}).
+		expect(t, http.StatusOK).decode(t, &third)
+	s.json(http.MethodPost, "/api/auth/logout", "", gin.H{"refresh_token": third.RefreshToken}).expect(t, http.StatusOK)
+	s.json(http.MethodPost, "/api/auth/refresh", "", gin.H{"refresh_token": third.RefreshToken}).expect(t, http.StatusUnauthorized)
+}
+
+func TestSignedLink(t *testing.T) {
+	s := newTestServer(t)
+	dept := s.createDepartment("Склад")
+	id := s.createEmployee("Иванов Пётр", 90000, dept)
+	viewer := s.token("v", roleViewer, nil)
+	target := "/api/employees/" + strconv.Itoa(id)
+
+	var link struct {
+		URL string `json:"url"`
+	}
+	s.json(http.MethodPost, "/api/auth/link", viewer, gin.H{"path": target}).expect(t, http.StatusOK).decode(t, &link)
+	u, err := url.Parse(link.URL)
+	if err != nil || u.Path != target {
+		t.Fatalf("url = %q", link.URL)
+	}
+	s.json(http.MethodGet, link.URL, "", nil).expect(t, http.StatusOK)
+
+	// Подпись действует только для своего пути и не заменяет access-токен
+	s.json(http.MethodGet, "/api/departments?"+u.RawQuery, "", nil).expect(t, http.StatusUnauthorized)
+	s.json(http.MethodGet, target, u.Query().Get(linkParam), nil).expect(t, http.StatusUnauthorized)
+	// Access-токен в адресе не принимается
+	s.json(http.MethodGet, target+"?"+linkParam+"="+viewer, "", nil).expect(t, http.StatusUnauthorized)
+
+	for _, path := range []string{"/api/auth/me", "https://evil.example/api/employees", "/static/x"} {
+		s.json(http.MethodPost, "/api/auth/link", viewer, gin.H{"path": path}).expect(t, http.StatusBadRequest)
+	}
+}
+
+func TestEmployeeSalaryMasking(t *testing.T) {
+	s := newTestServer(t)
+	dept := s.createDepartment("Продажи")
+	head := s.createEmployee("Руководитель", 300000, dept)
+	id := s.createEmployee("Менеджер", 60000, dept)
+	if err := s.repos.Departments.Update(context.Background(), dept, DepartmentPatch{SetBoss: true, BossID: &head}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		salary *float64
+		bucket string
+	}{
+		{"viewer", s.token("v", roleViewer, nil), nil, "50k–75k"},
+		{"руководитель отдела", s.token("boss", roleViewer, &head), ptr(60000.0), ""},
+		{"hr", s.token("hr", roleHR, nil), ptr(60000.0), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e EmployeeView
+			s.json(http.MethodGet, "/api/employees/"+strconv.Itoa(id), tt.token, nil).expect(t, http.StatusOK).decode(t, &e)
+			if (e.Salary == nil) != (tt.salary == nil) || e.Salary != nil && *e.Salary != *tt.salary || e.SalaryRange != tt.bucket {
+				t.Fatalf("salary=%v salary_range=%q", e.Salary, e.SalaryRange)
+			}
+		})
+	}
+
+	// Фильтр по зарплате раскрыл бы скрытые значения
+	s.json(http.MethodGet, "/api/employees?salary_min=50000", s.token("v", roleViewer, nil), nil).expect(t, http.StatusForbidden)
+	s.json(http.MethodGet, "/api/employees?salary_min=50000&dept_id="+strconv.Itoa(dept), s.token("boss", roleViewer, &head), nil).
+		expect(t, http.StatusOK)
+}
+
+func TestReportJobAPI(t *testing.T) {
+	s := newTestServer(t)
+	hr := s.token("hr", roleHR, nil)
+	s.createEmployee("Сотрудник", 1000, s.createDepartment("Отдел"))
+
+	var job ReportJob
+	resp := s.json(http.MethodPost, "/api/reports", hr, gin.H{"kind": "export"}).expect(t, http.StatusAccepted)
+	resp.decode(t, &job)
+	if resp.Header.Get("Location") != "/api/reports/"+job.ID {
+		t.Fatalf("Location = %q", resp.Header.Get("Location"))
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for job.Status != reportJobDone {
+		if job.Status == reportJobFailed || time.Now().After(deadline) {
+			t.Fatalf("задание %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+		s.json(http.MethodGet, "/api/reports/"+job.ID, hr, nil).expect(t, http.StatusOK).decode(t, &job)
+	}
+
+	download := s.json(http.MethodGet, "/api/reports/"+job.ID+"/download", hr, nil).expect(t, http.StatusOK)
+	if !bytes.HasPrefix(download.Body, []byte("PK")) || len(download.Body) != job.Size {
+		t.Fatalf("отчёт %d байт, ожидалось %d байт XLSX", len(download.Body), job.Size)
+	}
+
+	// Задание видно только автору и администратору
+	s.json(http.MethodGet, "/api/reports/"+job.ID, s.token("other", roleHR, nil), nil).expect(t, http.StatusNotFound)
+	s.json(http.MethodGet, "/api/reports/"+job.ID, s.token("admin", roleAdmin, nil), nil).expect(t, http.StatusOK)
+
+	s.json(http.MethodPost, "/api/reports", hr, gin.H{"kind": "unknown"}).expect(t, http.StatusBadRequest)
+}
+
+func TestCORSAllowsAuthorization(t *testing.T) {
+	r := gin.New()
+	r.Use(corsMiddleware())
+	r.GET("/api/departments", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// Предварительный запрос браузера перед запросом с Authorization: Bearer
+	req := httptest.NewRequest(http.MethodOptions, "/api/departments", nil)
+	req.Header.Set("Origin", "https://client.example")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	req.Header.Set("Access-Control-Request-Headers", "authorization")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("код ответа %d", w.Code)
+	}
+	if allowed := strings.ToLower(w.Header().Get("Access-Control-Allow-Headers")); !strings.Contains(allowed, "authorization") {
+		t.Fatalf("Access-Control-Allow-Headers = %q", allowed)
+	}
+}
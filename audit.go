@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Сущности журнала аудита
+const (
+	auditEmployee   = "employee"
+	auditDepartment = "department"
+)
+
+// Операции журнала аудита
+const (
+	auditCreate    = "create"
+	auditUpdate    = "update"
+	auditDelete    = "delete"
+	auditReconcile = "reconcile" // исправление агрегатов отдела сверкой
+
+	auditSalarySchedule = "salary_schedule" // запланировано изменение зарплаты
+	auditSalaryCancel   = "salary_cancel"   // отменено запланированное изменение зарплаты
+)
+
+// Исполнитель изменений, сделанных самим сервером (сверка при запуске и т.п.)
+const systemActor = "system"
+
+// AuditChange старое и новое значение поля (null — поля не было)
+type AuditChange struct {
+	Old json.RawMessage `json:"old"`
+	New json.RawMessage `json:"new"`
+}
+
+// AuditEntry запись журнала аудита (таблица audit_log)
+type AuditEntry struct {
+	ID        int64                  `json:"id"`
+	CreatedAt time.Time              `json:"created_at"`
+	Actor     string                 `json:"actor"`
+	Entity    string                 `json:"entity"`
+	EntityID  int                    `json:"entity_id"`
+	Operation string                 `json:"operation"`
+	Changes   map[string]AuditChange `json:"changes"`
+}
+
+// AuditQuery фильтры журнала; пустые поля не ограничивают выборку
+type AuditQuery struct {
+	Entity   string
+	EntityID *int
+	Actor    string
+	From     *time.Time // created_at >= From
+	To       *time.Time // created_at < To
+	Limit    int
+	Offset   int
+}
+
+// Поля, которые не пишутся в журнал: вычисляются из других таблиц
+var auditIgnoredFields = map[string]bool{
+	"boss_name": true,
+}
+
+// auditDiff сравнивает JSON-представления old и cur (nil — записи нет)
+// и возвращает только изменившиеся поля
+func auditDiff(old, cur interface{}) (map[string]AuditChange, error) {
+	before, err := auditFields(old)
+	if err != nil {
+		return nil, err
+	}
+	after, err := auditFields(cur)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]AuditChange{}
+	for name, v := range before {
+		if !bytes.Equal(v, after[name]) {
+			changes[name] = AuditChange{Old: v, New: orNull(after[name])}
+		}
+	}
+	for name, v := range after {
+		if _, ok := before[name]; !ok {
+			changes[name] = AuditChange{Old: orNull(nil), New: v}
+		}
+	}
+	return changes, nil
+}
+
+// auditFields поля значения в виде JSON
+func auditFields(v interface{}) (map[string]json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	if v == nil {
+		return fields, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name := range auditIgnoredFields {
+		delete(fields, name)
+	}
+	return fields, nil
+}
+
+func orNull(v json.RawMessage) json.RawMessage {
+	if v == nil {
+		return json.RawMessage("null")
+	}
+	return v
+}
+
+// newAuditEntry запись об изменении сущности; ok == false, если ничего не изменилось
+func newAuditEntry(ctx context.Context, entity string, id int, op string, old, cur interface{}) (AuditEntry, bool, error) {
+	changes, err := auditDiff(old, cur)
+	if err != nil {
+		return AuditEntry{}, false, fmt.Errorf("ошибка записи аудита: %v", err)
+	}
+	entry := AuditEntry{
+		CreatedAt: time.Now().UTC(),
+		Actor:     actorFrom(ctx),
+		Entity:    entity,
+		EntityID:  id,
+		Operation: op,
+		Changes:   changes,
+	}
+	return entry, len(changes) > 0 || op != auditUpdate, nil
+}
+
+// filterAuditEntries применяет AuditQuery к записям в памяти (новые первыми)
+func filterAuditEntries(all []AuditEntry, q AuditQuery) ([]AuditEntry, int) {
+	var matched []AuditEntry
+	for _, e := range all {
+		switch {
+		case q.Entity != "" && e.Entity != q.Entity:
+		case q.EntityID != nil && e.EntityID != *q.EntityID:
+		case q.Actor != "" && e.Actor != q.Actor:
+		case q.From != nil && e.CreatedAt.Before(*q.From):
+		case q.To != nil && !e.CreatedAt.Before(*q.To):
+		default:
+			matched = append(matched, e)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].ID > matched[j].ID })
+	total := len(matched)
+
+	if q.Offset > len(matched) {
+		q.Offset = len(matched)
+	}
+	page := matched[q.Offset:]
+	if q.Limit > 0 && len(page) > q.Limit {
+		page = page[:q.Limit]
+	}
+	return page, total
+}
+
+// actorKey ключ исполнителя в context.Context
+type actorKey struct{}
+
+// withActor сохраняет исполнителя изменений в контексте
+func withActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorFrom исполнитель из контекста; systemActor, если не задан
+func actorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return systemActor
+}
+
+// reasonKey ключ причины изменения в context.Context
+type reasonKey struct{}
+
+// withReason сохраняет причину изменения (например, зарплаты) в контексте
+func withReason(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, reasonKey{}, reason)
+}
+
+// reasonFrom причина изменения из контекста; "" если не задана
+func reasonFrom(ctx context.Context) string {
+	reason, _ := ctx.Value(reasonKey{}).(string)
+	return reason
+}
+
+// actorMiddleware исполнитель запроса по умолчанию — IP клиента.
+// authMiddleware заменяет его на пользователя из токена.
+func actorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(withActor(c.Request.Context(), "ip:"+c.ClientIP()))
+		c.Next()
+	}
+}
+
+// parseAuditQuery читает параметры GET /api/audit:
+// entity, entity_id, actor, from, to (RFC 3339 или YYYY-MM-DD), limit, offset
+func parseAuditQuery(c *gin.Context) (AuditQuery, error) {
+	q := AuditQuery{
+		Entity: c.Query("entity"),
+		Actor:  strings.TrimSpace(c.Query("actor")),
+		Limit:  defaultPageLimit,
+	}
+	switch q.Entity {
+	case "", auditEmployee, auditDepartment:
+	default:
+		return q, fmt.Errorf("некорректный entity: %s или %s", auditEmployee, auditDepartment)
+	}
+	if v := c.Query("entity_id"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return q, fmt.Errorf("некорректный entity_id")
+		}
+		q.EntityID = &n
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return q, fmt.Errorf("некорректный limit: от 1 до %d", maxPageLimit)
+		}
+		q.Limit = n
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return q, fmt.Errorf("некорректный offset")
+		}
+		q.Offset = n
+	}
+
+	for name, dst := range map[string]**time.Time{"from": &q.From, "to": &q.To} {
+		v := c.Query(name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			// Дата без времени: to=2024-05-31 включает весь день
+			day, dayErr := time.Parse(time.DateOnly, v)
+			if dayErr != nil {
+				return q, fmt.Errorf("некорректный %s: RFC 3339 или YYYY-MM-DD", name)
+			}
+			if name == "to" {
+				day = day.AddDate(0, 0, 1)
+			}
+			t = day
+		}
+		t = t.UTC()
+		*dst = &t
+	}
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return q, fmt.Errorf("from должен быть раньше to")
+	}
+	return q, nil
+}
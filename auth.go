@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Роли пользователей по возрастанию прав
+const (
+	roleViewer = "viewer" // чтение
+	roleHR     = "hr"     // изменение сотрудников, отделов и зарплат
+	roleAdmin  = "admin"  // удаление отделов, сверка, журнал аудита
+)
+
+var roleLevels = map[string]int{
+	roleViewer: 1,
+	roleHR:     2,
+	roleAdmin:  3,
+}
+
+// validRole проверяет, что роль известна
+func validRole(role string) bool {
+	_, ok := roleLevels[role]
+	return ok
+}
+
+// Ключ пользователя запроса в gin.Context
+const authUserKey = "auth_user"
+
+// errUnauthorized причина 401 без подробностей для клиента
+var errUnauthorized = errors.New("требуется авторизация")
+
+// tokenClaims полезная нагрузка access-токена (JWT)
+type tokenClaims struct {
+	UserID    int    `json:"sub"`
+	Login     string `json:"login"`
+	Role      string `json:"role"`
+	EmpID     *int   `json:"emp,omitempty"` // связанный сотрудник (см. managesDepartment)
+	Resource  string `json:"res,omitempty"` // путь подписанной ссылки (signLink); у access-токена пуст
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Срок действия подписанной ссылки на фото или документ
+const linkTTL = 5 * time.Minute
+
+// Параметр запроса с подписью ссылки
+const linkParam = "link"
+
+// TokenPair ответ на вход и обновление токенов
+type TokenPair struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int    `json:"expires_in"` // секунды
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresIn int    `json:"refresh_expires_in"`
+}
+
+// tokenIssuer выпускает и проверяет токены.
+// Access — JWT HS256 без состояния; refresh — случайная строка, в БД хранится её SHA-256.
+type tokenIssuer struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func newTokenIssuer(cfg AuthConfig) *tokenIssuer {
+	return &tokenIssuer{secret: []byte(cfg.JWTSecret), accessTTL: cfg.AccessTTL, refreshTTL: cfg.RefreshTTL}
+}
+
+// Заголовок JWT всегда один и тот же
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// issue выпускает пару токенов для пользователя; RefreshToken.Hash нужно сохранить в БД
+func (t *tokenIssuer) issue(u User) (TokenPair, RefreshToken, error) {
+	now := time.Now()
+	access, err := t.sign(tokenClaims{
+		UserID:    u.ID,
+		Login:     u.Login,
+		Role:      u.Role,
+		EmpID:     u.EmpID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(t.accessTTL).Unix(),
+	})
+	if err != nil {
+		return TokenPair{}, RefreshToken{}, err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return TokenPair{}, RefreshToken{}, err
+	}
+	refresh := base64.RawURLEncoding.EncodeToString(raw)
+
+	return TokenPair{
+		AccessToken:      access,
+		TokenType:        "Bearer",
+		ExpiresIn:        int(t.accessTTL.Seconds()),
+		RefreshToken:     refresh,
+		RefreshExpiresIn: int(t.refreshTTL.Seconds()),
+	}, RefreshToken{
+		Hash:      hashRefreshToken(refresh),
+		UserID:    u.ID,
+		ExpiresAt: now.Add(t.refreshTTL).UTC(),
+	}, nil
+}
+
+// signLink подписывает ссылку на GET resource от имени пользователя claims.
+// Ссылка действует linkTTL, но не дольше access-токена, и только для этого пути,
+// поэтому её можно вставлять в <img>/<a>, не раскрывая сам access-токен.
+func (t *tokenIssuer) signLink(claims tokenClaims, resource string) (string, time.Time, error) {
+	now := time.Now()
+	expires := min(now.Add(linkTTL).Unix(), claims.ExpiresAt)
+	claims.Resource, claims.IssuedAt, claims.ExpiresAt = resource, now.Unix(), expires
+	token, err := t.sign(claims)
+	return token, time.Unix(expires, 0).UTC(), err
+}
+
+// hashRefreshToken значение refresh-токена, хранимое в БД
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// sign кодирует claims в JWT HS256
+func (t *tokenIssuer) sign(claims tokenClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + t.signature(unsigned), nil
+}
+
+func (t *tokenIssuer) signature(unsigned string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify проверяет подпись и срок действия access-токена
+func (t *tokenIssuer) verify(token string) (tokenClaims, error) {
+	var claims tokenClaims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return claims, fmt.Errorf("некорректный токен")
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(t.signature(parts[0]+"."+parts[1]))) {
+		return claims, fmt.Errorf("некорректная подпись токена")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return claims, fmt.Errorf("некорректный токен")
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, fmt.Errorf("некорректный токен")
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return claims, fmt.Errorf("срок действия токена истёк")
+	}
+	if !validRole(claims.Role) {
+		return claims, fmt.Errorf("неизвестная роль %q", claims.Role)
+	}
+	return claims, nil
+}
+
+// hashPassword bcrypt-хэш пароля для хранения в users
+func hashPassword(password string) (string, error) {
+	if len(password) < 8 {
+		return "", fmt.Errorf("пароль короче 8 символов")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("ошибка хэширования пароля: %v", err)
+	}
+	return string(hash), nil
+}
+
+// Хэш для сравнения, когда пользователь не найден: время ответа не выдаёт существование логина
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// checkPassword сравнивает пароль с хэшем (hash == "" — пользователя нет)
+func checkPassword(hash, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// authMiddleware проверяет access-токен из заголовка Authorization: Bearer <token>.
+// Для GET вместо него принимается подписанная ссылка ?link= (см. signLink),
+// выданная на этот же путь. Access-токен в адресе не принимается: адреса пишутся в логи.
+// Пользователь становится исполнителем изменений в журнале аудита.
+func authMiddleware(tokens *tokenIssuer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, link := "", false
+		if h := c.GetHeader("Authorization"); strings.HasPrefix(h, "Bearer ") {
+			token = strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+		} else if c.Request.Method == http.MethodGet {
+			token, link = c.Query(linkParam), true
+		}
+		if token == "" {
+			c.Header("WWW-Authenticate", "Bearer")
+			sendAPIResponse(c, nil, errUnauthorized, http.StatusUnauthorized)
+			c.Abort()
+			return
+		}
+
+		claims, err := tokens.verify(token)
+		if err == nil && link && claims.Resource != c.Request.URL.Path {
+			err = fmt.Errorf("ссылка выдана на другой ресурс")
+		} else if err == nil && !link && claims.Resource != "" {
+			err = fmt.Errorf("подписанная ссылка не заменяет access-токен")
+		}
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			sendAPIResponse(c, nil, fmt.Errorf("%v: %v", errUnauthorized, err), http.StatusUnauthorized)
+			c.Abort()
+			return
+		}
+
+		c.Set(authUserKey, claims)
+		c.Request = c.Request.WithContext(withActor(c.Request.Context(), "user:"+claims.Login))
+		c.Next()
+	}
+}
+
+// requireRole пропускает пользователей с ролью не ниже role
+func requireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := authUser(c)
+		if !ok {
+			sendAPIResponse(c, nil, errUnauthorized, http.StatusUnauthorized)
+			c.Abort()
+			return
+		}
+		if roleLevels[claims.Role] < roleLevels[role] {
+			sendAPIResponse(c, nil, fmt.Errorf("недостаточно прав: требуется роль %s", role), http.StatusForbidden)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// errDepartmentScope отказ пользователю без прав на отдел
+var errDepartmentScope = errors.New("недостаточно прав: требуется роль hr или руководство отделом")
+
+// managesDepartment может ли пользователь видеть зарплаты и изменять сотрудников отдела d:
+// роль hr и выше — любой отдел, руководитель (ОТДЕЛЫ.ОТД_РУК) — только свой
+func managesDepartment(c *gin.Context, d Department) bool {
+	claims, ok := authUser(c)
+	if !ok {
+		return false
+	}
+	if roleLevels[claims.Role] >= roleLevels[roleHR] {
+		return true
+	}
+	return claims.EmpID != nil && d.BossID != nil && *d.BossID == *claims.EmpID
+}
+
+// managerKey ключ руководителя, которым ограничено изменение, в context.Context
+type managerKey struct{}
+
+// withManagerScope ограничивает изменения в репозитории отделами, которыми руководит
+// пользователь запроса; роль hr и выше ограничений не получает.
+// Репозиторий проверяет отделы под блокировкой строки, поэтому перевод сотрудника
+// или смена руководителя между проверкой в обработчике и записью не обходят ограничение.
+func withManagerScope(ctx context.Context, c *gin.Context) context.Context {
+	claims, _ := authUser(c)
+	if roleLevels[claims.Role] >= roleLevels[roleHR] || claims.EmpID == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, managerKey{}, *claims.EmpID)
+}
+
+// managerFrom руководитель из контекста (см. withManagerScope); false — без ограничений
+func managerFrom(ctx context.Context) (int, bool) {
+	empID, ok := ctx.Value(managerKey{}).(int)
+	return empID, ok
+}
+
+// authUser пользователь, прошедший authMiddleware
+func authUser(c *gin.Context) (tokenClaims, bool) {
+	v, ok := c.Get(authUserKey)
+	if !ok {
+		return tokenClaims{}, false
+	}
+	claims, ok := v.(tokenClaims)
+	return claims, ok
+}
+
+// bootstrapAdmin создаёт пользователя admin со случайным паролем и записывает пароль
+// в passwordFile (права 0600), а не в лог: логи собираются и хранятся дольше сессии.
+// Нужен хранилищу в памяти, где нельзя заранее выполнить команду useradd.
+func bootstrapAdmin(ctx context.Context, users UserRepository, passwordFile string) error {
+	raw := make([]byte, 12)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	password := base64.RawURLEncoding.EncodeToString(raw)
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := writeSecretFile(passwordFile, password+"\n"); err != nil {
+		return fmt.Errorf("ошибка записи пароля admin: %v", err)
+	}
+	if _, err := users.Create(ctx, User{Login: "admin", PasswordHash: hash, Role: roleAdmin}); err != nil {
+		return err
+	}
+	log.Printf("Создан пользователь admin, пароль записан в %s", passwordFile)
+	return nil
+}
+
+// writeSecretFile перезаписывает файл, доступный только владельцу
+func writeSecretFile(name, content string) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	// Права существующего файла OpenFile не меняет
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testTokenIssuer() *tokenIssuer {
+	return newTokenIssuer(AuthConfig{JWTSecret: strings.Repeat("s", 32), AccessTTL: time.Minute, RefreshTTL: time.Hour})
+}
+
+func TestTokenVerify(t *testing.T) {
+	tokens := testTokenIssuer()
+	now := time.Now()
+	valid := tokenClaims{UserID: 7, Login: "anna", Role: roleHR, IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix()}
+	sign := func(claims tokenClaims) string {
+		token, err := tokens.sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	good := sign(valid)
+
+	expired := valid
+	expired.ExpiresAt = now.Add(-time.Second).Unix()
+	badRole := valid
+	badRole.Role = "root"
+	parts := strings.Split(good, ".")
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"действующий", good, true},
+		{"истёк", sign(expired), false},
+		{"неизвестная роль", sign(badRole), false},
+		{"чужой секрет", func() string {
+			other := newTokenIssuer(AuthConfig{JWTSecret: strings.Repeat("x", 32)})
+			token, _ := other.sign(valid)
+			return token
+		}(), false},
+		{"изменённые данные", parts[0] + "." + strings.TrimSuffix(parts[1], parts[1][len(parts[1])-2:]) + "AA." + parts[2], false},
+		{"другой заголовок", "eyJhbGciOiJub25lIn0." + parts[1] + "." + parts[2], false},
+		{"без подписи", parts[0] + "." + parts[1], false},
+		{"пустой", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tokens.verify(tt.token)
+			if (err == nil) != tt.ok {
+				t.Fatalf("ошибка %v, ожидался успех: %v", err, tt.ok)
+			}
+			if tt.ok && (claims.UserID != 7 || claims.Login != "anna" || claims.Role != roleHR) {
+				t.Fatalf("claims %+v", claims)
+			}
+		})
+	}
+}
+
+func TestTokenIssueRefresh(t *testing.T) {
+	tokens := testTokenIssuer()
+	pair, refresh, err := tokens.issue(User{ID: 3, Login: "anna", Role: roleViewer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refresh.Hash != hashRefreshToken(pair.RefreshToken) || refresh.Hash == pair.RefreshToken {
+		t.Fatal("в БД должен храниться хэш refresh-токена, а не он сам")
+	}
+	if refresh.UserID != 3 || pair.ExpiresIn != 60 || pair.RefreshExpiresIn != 3600 {
+		t.Fatalf("пара %+v, refresh %+v", pair, refresh)
+	}
+	if _, err := tokens.verify(pair.RefreshToken); err == nil {
+		t.Fatal("refresh-токен принят как access-токен")
+	}
+}
+
+func TestSignLinkExpiry(t *testing.T) {
+	tokens := testTokenIssuer()
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		access time.Duration // сколько ещё действует access-токен
+		want   time.Duration
+	}{
+		{"не дольше linkTTL", time.Hour, linkTTL},
+		{"не дольше access-токена", time.Minute, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := tokenClaims{Login: "anna", Role: roleViewer, ExpiresAt: now.Add(tt.access).Unix()}
+			link, expires, err := tokens.signLink(claims, "/api/employees/1/photo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := expires.Sub(now); d < tt.want-2*time.Second || d > tt.want+time.Second {
+				t.Fatalf("ссылка действует %v, ожидалось %v", d, tt.want)
+			}
+			got, err := tokens.verify(link)
+			if err != nil || got.Resource != "/api/employees/1/photo" {
+				t.Fatalf("claims %+v: %v", got, err)
+			}
+		})
+	}
+}
+
+func TestPasswords(t *testing.T) {
+	if _, err := hashPassword("short"); err == nil {
+		t.Fatal("принят пароль короче 8 символов")
+	}
+	hash, err := hashPassword("long-enough")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !checkPassword(hash, "long-enough") || checkPassword(hash, "long-enougH") {
+		t.Fatal("checkPassword сравнивает неверно")
+	}
+	if checkPassword("", "long-enough") {
+		t.Fatal("вход без пользователя")
+	}
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	repos := newMemoryRepositories()
+	file := filepath.Join(t.TempDir(), "admin.password")
+	// Существующий файл с широкими правами перезаписывается с правами 0600
+	if err := os.WriteFile(file, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bootstrapAdmin(context.Background(), repos.Users, file); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("права файла пароля %o, ожидалось 600", perm)
+	}
+	password, _ := os.ReadFile(file)
+	u, err := repos.Users.GetByLogin(context.Background(), "admin")
+	if err != nil || u.Role != roleAdmin {
+		t.Fatalf("admin %+v: %v", u, err)
+	}
+	if !checkPassword(u.PasswordHash, strings.TrimSpace(string(password))) {
+		t.Fatal("пароль в файле не подходит к admin")
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// errPresignUnsupported хранилище не выдаёт прямых ссылок: файл отдаёт сам API
+var errPresignUnsupported = errors.New("хранилище не поддерживает подписанные ссылки")
+
+// BlobInfo сведения об объекте хранилища
+type BlobInfo struct {
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// BlobStore хранилище файлов (фото сотрудников) по ключу вида "emp_1.jpg".
+// Реализации: localBlobStore — директория на диске, s3BlobStore — S3-совместимое
+// хранилище, общее для нескольких экземпляров API. Отсутствующий объект — ErrNotFound.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, BlobInfo, error)
+	Stat(ctx context.Context, key string) (BlobInfo, error)
+	Delete(ctx context.Context, key string) error
+	// PresignGet ссылка на скачивание без авторизации, действующая ttl;
+	// errPresignUnsupported, если хранилище их не выдаёт
+	PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// Хранилища фото (storage.backend)
+const (
+	storageLocal = "local" // директория storage.employee_images
+	storageS3    = "s3"    // бакет storage.s3
+)
+
+// photoStore хранилище фото сотрудников (storage.backend), задаётся при старте
+var photoStore BlobStore = localBlobStore{dir: defaultConfig().Storage.EmployeeImages}
+
+// photoPresignTTL срок ссылок на фото (storage.s3.presign_ttl); 0 — фото отдаёт API
+var photoPresignTTL time.Duration
+
+// newBlobStore хранилище фото по настройкам storage
+func newBlobStore(cfg StorageConfig) (BlobStore, error) {
+	switch cfg.Backend {
+	case storageLocal:
+		return localBlobStore{dir: cfg.EmployeeImages}, nil
+	case storageS3:
+		store, err := newS3BlobStore(cfg.S3)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+	return nil, fmt.Errorf("storage.backend: неизвестное значение %q (local, s3)", cfg.Backend)
+}
+
+// newReportStore хранилище готовых отчётов: директория reports.dir или префикс
+// reports/ в бакете storage.s3. Задания не переживают перезапуск, поэтому
+// оставшиеся от прошлого запуска отчёты в локальной директории удаляются.
+func newReportStore(cfg Config) (BlobStore, error) {
+	if cfg.Storage.Backend == storageS3 {
+		s3cfg := cfg.Storage.S3
+		s3cfg.Prefix = path.Join(s3cfg.Prefix, "reports")
+		store, err := newS3BlobStore(s3cfg)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+	if err := os.MkdirAll(cfg.Reports.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("ошибка создания директории отчётов: %v", err)
+	}
+	stale, err := filepath.Glob(filepath.Join(cfg.Reports.Dir, "report_*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range stale {
+		if err := os.Remove(name); err != nil {
+			return nil, fmt.Errorf("ошибка удаления старого отчёта: %v", err)
+		}
+	}
+	return localBlobStore{dir: cfg.Reports.Dir}, nil
+}
+
+// localBlobStore объекты — файлы в директории dir
+type localBlobStore struct {
+	dir string
+}
+
+// path файл объекта; ключ не может выйти за пределы директории
+func (s localBlobStore) path(key string) (string, error) {
+	clean := path.Clean("/" + key)[1:]
+	if clean == "" || clean != key {
+		return "", fmt.Errorf("некорректный ключ объекта %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(clean)), nil
+}
+
+// Put записывает объект через временный файл, чтобы читатели не увидели его частично
+func (s localBlobStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	fullPath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла на сервере: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("ошибка сохранения файла: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("ошибка сохранения файла: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("ошибка сохранения файла: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), fullPath); err != nil {
+		return fmt.Errorf("ошибка сохранения файла: %v", err)
+	}
+	return nil
+}
+
+func (s localBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, BlobInfo, error) {
+	fullPath, err := s.path(key)
+	if err != nil {
+		return nil, BlobInfo{}, err
+	}
+	f, err := os.Open(fullPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, BlobInfo{}, ErrNotFound
+	} else if err != nil {
+		return nil, BlobInfo{}, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, BlobInfo{}, err
+	}
+	return f, localBlobInfo(key, st), nil
+}
+
+func (s localBlobStore) Stat(ctx context.Context, key string) (BlobInfo, error) {
+	fullPath, err := s.path(key)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	st, err := os.Stat(fullPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return BlobInfo{}, ErrNotFound
+	} else if err != nil {
+		return BlobInfo{}, err
+	}
+	return localBlobInfo(key, st), nil
+}
+
+func (s localBlobStore) Delete(ctx context.Context, key string) error {
+	fullPath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(fullPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s localBlobStore) PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	return "", errPresignUnsupported
+}
+
+// localBlobInfo тип содержимого файла — по расширению ключа
+func localBlobInfo(key string, st os.FileInfo) BlobInfo {
+	contentType := mime.TypeByExtension(strings.ToLower(path.Ext(key)))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return BlobInfo{Size: st.Size(), ContentType: contentType, ModTime: st.ModTime()}
+}
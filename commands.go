@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// runMigrate команда "migrate [флаги] up | down [N] | status"
+func runMigrate(args []string) error {
+	cfg, rest, err := loadConfig("migrate", args)
+	if err != nil {
+		return err
+	}
+	if err := cfg.DB.validate(); err != nil {
+		return err
+	}
+	if cfg.DB.Driver != "mysql" {
+		return fmt.Errorf("миграции применимы только к db.driver=mysql")
+	}
+	if len(rest) == 0 {
+		return fmt.Errorf("использование: migrate [флаги] up | down [N] | status")
+	}
+
+	db, err := setupDatabase(cfg.DB.DSN())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch rest[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Схема актуальна")
+		}
+	case "down":
+		steps := 1
+		if len(rest) > 1 {
+			if steps, err = strconv.Atoi(rest[1]); err != nil || steps < 1 {
+				return fmt.Errorf("некорректное число шагов: %q", rest[1])
+			}
+		}
+		if _, err := m.Down(ctx, steps); err != nil {
+			return err
+		}
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range status {
+			applied := "не применена"
+			if st.AppliedAt != nil {
+				applied = st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", st.Version, st.Name, applied)
+		}
+	default:
+		return fmt.Errorf("неизвестная подкоманда migrate %q (up, down, status)", rest[0])
+	}
+	return nil
+}
+
+// runReconcile команда "reconcile [флаги] [check | fix]".
+// В режиме check завершается с ошибкой, если найдены расхождения.
+func runReconcile(args []string) error {
+	cfg, rest, err := loadConfig("reconcile", args)
+	if err != nil {
+		return err
+	}
+	if err := cfg.DB.validate(); err != nil {
+		return err
+	}
+	if cfg.DB.Driver != "mysql" {
+		return fmt.Errorf("сверка применима только к db.driver=mysql")
+	}
+	mode := "check"
+	if len(rest) > 0 {
+		mode = rest[0]
+	}
+	if mode != "check" && mode != "fix" {
+		return fmt.Errorf("использование: reconcile [флаги] [check | fix]")
+	}
+
+	db, err := setupDatabase(cfg.DB.DSN())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Сверка не зависит от стратегии: сравниваются хранимые и фактические значения
+	drifts, err := newMySQLRepositories(db, aggregatesApp).Departments.Reconcile(withActor(context.Background(), "cli:reconcile"), mode == "fix")
+	if err != nil {
+		return err
+	}
+	if len(drifts) == 0 {
+		fmt.Println("Расхождений нет")
+		return nil
+	}
+
+	fmt.Printf("%-8s %16s %16s %10s %10s\n", "Отдел", "ОТД_СОТР_ЗАРП", "SUM(СЛУ_ЗАРП)", "ОТД_РАЗМ", "COUNT(*)")
+	for _, d := range drifts {
+		fmt.Printf("%-8d %16.2f %16.2f %10d %10d\n", d.ID, d.StoredSalary, d.ActualSalary, d.StoredSize, d.ActualSize)
+	}
+	if mode == "fix" {
+		fmt.Printf("Исправлено отделов: %d\n", len(drifts))
+		return nil
+	}
+	return fmt.Errorf("найдено расхождений: %d (запустите reconcile fix)", len(drifts))
+}
+
+// runUserAdd команда "useradd [флаги] <login> <viewer | hr | admin> [emp_id]".
+// Пароль читается из первой строки стандартного ввода; emp_id связывает
+// пользователя с сотрудником (руководитель получает права на свой отдел).
+func runUserAdd(args []string) error {
+	cfg, rest, err := loadConfig("useradd", args)
+	if err != nil {
+		return err
+	}
+	if err := cfg.DB.validate(); err != nil {
+		return err
+	}
+	if cfg.DB.Driver != "mysql" {
+		return fmt.Errorf("пользователи хранятся только при db.driver=mysql")
+	}
+	if len(rest) != 2 && len(rest) != 3 {
+		return fmt.Errorf("использование: useradd [флаги] <login> <viewer | hr | admin> [emp_id] (пароль — из stdin)")
+	}
+	login, role := strings.TrimSpace(rest[0]), rest[1]
+	if login == "" || len(login) > 64 {
+		return fmt.Errorf("логин должен быть от 1 до 64 символов")
+	}
+	if !validRole(role) {
+		return fmt.Errorf("неизвестная роль %q (viewer, hr, admin)", role)
+	}
+	var empID *int
+	if len(rest) == 3 {
+		id, err := strconv.Atoi(rest[2])
+		if err != nil || id < 1 {
+			return fmt.Errorf("некорректный emp_id: %q", rest[2])
+		}
+		empID = &id
+	}
+
+	fmt.Fprint(os.Stderr, "Пароль: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return fmt.Errorf("ошибка чтения пароля: %v", err)
+	}
+	hash, err := hashPassword(strings.TrimRight(password, "\r\n"))
+	if err != nil {
+		return err
+	}
+
+	db, err := setupDatabase(cfg.DB.DSN())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	id, err := newMySQLRepositories(db, aggregatesApp).Users.Create(context.Background(), User{
+		Login:        login,
+		PasswordHash: hash,
+		Role:         role,
+		EmpID:        empID,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Создан пользователь %s (ID %d, роль %s)\n", login, id, role)
+	return nil
+}
@@ -1,20 +1,425 @@
 package main
 
-// Configure server ip and TLS
-const (
-	listenIP = "192.168.1.3:443"
-	certPath = "certs/server.crt"
-	keyPath  = "certs/server.key"
-
-	unidocKey = "6f5addfcb0e2f0221366807632afb5e224a938a9968b0d8a35baa541eab2fc33"
-
-	host     = "127.127.126.1"
-	dbName   = "WorkDB"
-	user     = "root"
-	password = ""
-	port     = "3306"
-	dsn      = user + ":" + password + "@tcp(" + host + ":" + port + ")/" + dbName + "?parseTime=true&multiStatements=true"
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gopkg.in/yaml.v3"
 )
 
+// Префикс переменных окружения, переопределяющих настройки из файла
+const envPrefix = "APP_"
+
+// Файл конфигурации по умолчанию (используется, только если существует)
+const defaultConfigPath = "config.yaml"
+
+// Config настройки сервера.
+// Порядок применения: значения по умолчанию -> файл -> переменные окружения -> флаги.
+type Config struct {
+	Server    ServerConfig    `yaml:"server"`
+	DB        DBConfig        `yaml:"db"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Unidoc    UnidocConfig    `yaml:"unidoc"`
+	Storage   StorageConfig   `yaml:"storage"`
+	Documents DocumentsConfig `yaml:"documents"`
+	Reports   ReportsConfig   `yaml:"reports"`
+}
+
+// ServerConfig адрес прослушивания и TLS
+type ServerConfig struct {
+	Listen   string `yaml:"listen"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// DBConfig подключение к MySQL
+type DBConfig struct {
+	Driver      string `yaml:"driver"`       // "mysql" или "memory"
+	AutoMigrate bool   `yaml:"auto_migrate"` // применять миграции при старте сервера
+	Aggregates  string `yaml:"aggregates"`   // auto, trigger, app, view (см. aggregates.go)
+	Host        string `yaml:"host"`
+	Port        string `yaml:"port"`
+	Name        string `yaml:"name"`
+	User        string `yaml:"user"`
+	Password    string `yaml:"password"`
+}
+
+// AuthConfig выпуск токенов доступа
+type AuthConfig struct {
+	JWTSecret  string        `yaml:"jwt_secret"`  // ключ подписи access-токенов (HS256), не короче 32 байт
+	AccessTTL  time.Duration `yaml:"access_ttl"`  // срок действия access-токена
+	RefreshTTL time.Duration `yaml:"refresh_ttl"` // срок действия refresh-токена
+	// Файл с паролем admin, созданного при db.driver: memory (права 0600)
+	AdminPasswordFile string `yaml:"admin_password_file"`
+}
+
+// UnidocConfig лицензия unidoc (metered key)
+type UnidocConfig struct {
+	Key string `yaml:"key"`
+}
+
+// StorageConfig расположение файлов приложения
+type StorageConfig struct {
+	EmployeeImages string   `yaml:"employee_images"` // локальные фото и заглушка default.png
+	Backend        string   `yaml:"backend"`         // хранилище фото: local или s3 (см. newBlobStore)
+	S3             S3Config `yaml:"s3"`
+}
+
+// S3Config S3-совместимое хранилище фото (storage.backend: s3)
+type S3Config struct {
+	Endpoint   string        `yaml:"endpoint"`    // https://s3.eu-central-1.amazonaws.com, http://minio:9000
+	Region     string        `yaml:"region"`      // участвует в подписи; MinIO принимает us-east-1
+	Bucket     string        `yaml:"bucket"`      // бакет должен существовать
+	Prefix     string        `yaml:"prefix"`      // префикс ключей внутри бакета
+	AccessKey  string        `yaml:"access_key"`  // лучше задавать через окружение
+	SecretKey  string        `yaml:"secret_key"`  // лучше задавать через окружение
+	PathStyle  bool          `yaml:"path_style"`  // адресация host/bucket/key (MinIO) вместо bucket.host/key
+	PresignTTL time.Duration `yaml:"presign_ttl"` // срок ссылок на фото; 0 — фото отдаёт API
+}
+
+// DocumentsConfig оформление выгружаемых документов
+type DocumentsConfig struct {
+	PDFFont     string `yaml:"pdf_font"`      // TTF-шрифт PDF с кириллицей
+	PDFFontBold string `yaml:"pdf_font_bold"` // полужирное начертание
+	CompanyName string `yaml:"company_name"`  // в верхнем колонтитуле
+	Watermark   string `yaml:"watermark"`     // водяной знак по умолчанию: confidential, draft или none
+	Renderer    string `yaml:"renderer"`      // auto, unidoc или ooxml (см. setupDocumentRenderer)
+}
+
+// ReportsConfig асинхронное формирование отчётов (POST /api/reports)
+type ReportsConfig struct {
+	Workers      int           `yaml:"workers"`        // одновременно формируемых отчётов
+	Queue        int           `yaml:"queue"`          // заданий в очереди, сверх — 503
+	TTL          time.Duration `yaml:"ttl"`            // срок хранения готового отчёта
+	MaxStorageMB int           `yaml:"max_storage_mb"` // объём готовых отчётов; сверх — удаляются самые старые
+	Dir          string        `yaml:"dir"`            // готовые отчёты при storage.backend: local (s3 — префикс reports/)
+}
+
+// defaultConfig значения, пригодные для локального запуска
+func defaultConfig() Config {
+	return Config{
+		Server: ServerConfig{
+			Listen:   "0.0.0.0:443",
+			CertFile: "certs/server.crt",
+			KeyFile:  "certs/server.key",
+		},
+		DB: DBConfig{
+			Driver:      "mysql",
+			AutoMigrate: true,
+			Aggregates:  string(aggregatesAuto),
+			Host:        "127.0.0.1",
+			Port:        "3306",
+			Name:        "WorkDB",
+			User:        "root",
+		},
+		Auth: AuthConfig{
+			AccessTTL:         15 * time.Minute,
+			RefreshTTL:        30 * 24 * time.Hour,
+			AdminPasswordFile: "admin.password",
+		},
+		Storage: StorageConfig{
+			EmployeeImages: "./static/images",
+			Backend:        storageLocal,
+			S3: S3Config{
+				Region:     "us-east-1",
+				PresignTTL: 15 * time.Minute,
+			},
+		},
+		Documents: DocumentsConfig{
+			PDFFont:     "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
+			PDFFontBold: "/usr/share/fonts/truetype/dejavu/DejaVuSans-Bold.ttf",
+			Watermark:   "confidential",
+			Renderer:    rendererAuto,
+		},
+		Reports: ReportsConfig{
+			Workers:      2,
+			Queue:        100,
+			TTL:          time.Hour,
+			MaxStorageMB: 512,
+			Dir:          "./data/reports",
+		},
+	}
+}
+
+// DSN строка подключения для go-sql-driver/mysql
+func (c DBConfig) DSN() string {
+	mc := mysql.NewConfig()
+	mc.User = c.User
+	mc.Passwd = c.Password
+	mc.Net = "tcp"
+	mc.Addr = net.JoinHostPort(c.Host, c.Port)
+	mc.DBName = c.Name
+	mc.ParseTime = true
+	mc.MultiStatements = true
+	return mc.FormatDSN()
+}
+
+// loadConfig собирает конфигурацию из файла, окружения и аргументов командной строки.
+// Возвращает аргументы, оставшиеся после флагов. Проверку выполняет вызывающий код.
+func loadConfig(name string, args []string) (Config, []string, error) {
+	cfg := defaultConfig()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configPath := fs.String("config", "", "путь к YAML-файлу конфигурации (или "+envPrefix+"CONFIG)")
+	listen := fs.String("listen", "", "адрес прослушивания, например 0.0.0.0:443")
+	certFile := fs.String("cert", "", "путь к TLS-сертификату")
+	keyFile := fs.String("key", "", "путь к приватному ключу TLS")
+	dbHost := fs.String("db-host", "", "хост MySQL")
+	dbPort := fs.String("db-port", "", "порт MySQL")
+	dbName := fs.String("db-name", "", "имя базы данных")
+	dbUser := fs.String("db-user", "", "пользователь MySQL")
+	images := fs.String("images", "", "директория для фотографий сотрудников")
+	if err := fs.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+
+	// 1) Файл: явно указанный обязан существовать, файл по умолчанию — необязателен
+	path := *configPath
+	if path == "" {
+		path = os.Getenv(envPrefix + "CONFIG")
+	}
+	required := path != ""
+	if path == "" {
+		path = defaultConfigPath
+	}
+	if err := readConfigFile(path, required, &cfg); err != nil {
+		return cfg, nil, err
+	}
+
+	// 2) Переменные окружения
+	if err := applyEnv(&cfg); err != nil {
+		return cfg, nil, err
+	}
+
+	// 3) Флаги — только те, что заданы явно
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "listen":
+			cfg.Server.Listen = *listen
+		case "cert":
+			cfg.Server.CertFile = *certFile
+		case "key":
+			cfg.Server.KeyFile = *keyFile
+		case "db-host":
+			cfg.DB.Host = *dbHost
+		case "db-port":
+			cfg.DB.Port = *dbPort
+		case "db-name":
+			cfg.DB.Name = *dbName
+		case "db-user":
+			cfg.DB.User = *dbUser
+		case "images":
+			cfg.Storage.EmployeeImages = *images
+		}
+	})
+
+	return cfg, fs.Args(), nil
+}
+
+// readConfigFile накладывает содержимое YAML-файла на cfg
+func readConfigFile(path string, required bool, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка чтения файла конфигурации %s: %v", path, err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("ошибка разбора файла конфигурации %s: %v", path, err)
+	}
+	return nil
+}
+
+// applyEnv переопределяет настройки из переменных окружения APP_*
+func applyEnv(cfg *Config) error {
+	for env, dst := range map[string]*string{
+		"LISTEN":              &cfg.Server.Listen,
+		"CERT_FILE":           &cfg.Server.CertFile,
+		"KEY_FILE":            &cfg.Server.KeyFile,
+		"DB_DRIVER":           &cfg.DB.Driver,
+		"DB_AGGREGATES":       &cfg.DB.Aggregates,
+		"DB_HOST":             &cfg.DB.Host,
+		"DB_PORT":             &cfg.DB.Port,
+		"DB_NAME":             &cfg.DB.Name,
+		"DB_USER":             &cfg.DB.User,
+		"DB_PASSWORD":         &cfg.DB.Password,
+		"JWT_SECRET":          &cfg.Auth.JWTSecret,
+		"ADMIN_PASSWORD_FILE": &cfg.Auth.AdminPasswordFile,
+		"UNIDOC_KEY":          &cfg.Unidoc.Key,
+		"EMPLOYEE_IMAGES":     &cfg.Storage.EmployeeImages,
+		"PDF_FONT":            &cfg.Documents.PDFFont,
+		"PDF_FONT_BOLD":       &cfg.Documents.PDFFontBold,
+		"COMPANY_NAME":        &cfg.Documents.CompanyName,
+		"DOC_WATERMARK":       &cfg.Documents.Watermark,
+		"DOC_RENDERER":        &cfg.Documents.Renderer,
+		"STORAGE_BACKEND":     &cfg.Storage.Backend,
+		"S3_ENDPOINT":         &cfg.Storage.S3.Endpoint,
+		"S3_REGION":           &cfg.Storage.S3.Region,
+		"S3_BUCKET":           &cfg.Storage.S3.Bucket,
+		"S3_PREFIX":           &cfg.Storage.S3.Prefix,
+		"S3_ACCESS_KEY":       &cfg.Storage.S3.AccessKey,
+		"S3_SECRET_KEY":       &cfg.Storage.S3.SecretKey,
+		"REPORT_DIR":          &cfg.Reports.Dir,
+	} {
+		if v, ok := os.LookupEnv(envPrefix + env); ok {
+			*dst = v
+		}
+	}
+	for env, dst := range map[string]*bool{
+		"DB_AUTO_MIGRATE": &cfg.DB.AutoMigrate,
+		"S3_PATH_STYLE":   &cfg.Storage.S3.PathStyle,
+	} {
+		if v, ok := os.LookupEnv(envPrefix + env); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("%s%s: ожидается true/false, получено %q", envPrefix, env, v)
+			}
+			*dst = b
+		}
+	}
+	for env, dst := range map[string]*int{
+		"REPORT_WORKERS":        &cfg.Reports.Workers,
+		"REPORT_QUEUE":          &cfg.Reports.Queue,
+		"REPORT_MAX_STORAGE_MB": &cfg.Reports.MaxStorageMB,
+	} {
+		if v, ok := os.LookupEnv(envPrefix + env); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s%s: ожидается целое число, получено %q", envPrefix, env, v)
+			}
+			*dst = n
+		}
+	}
+	for env, dst := range map[string]*time.Duration{
+		"ACCESS_TTL":     &cfg.Auth.AccessTTL,
+		"REFRESH_TTL":    &cfg.Auth.RefreshTTL,
+		"REPORT_TTL":     &cfg.Reports.TTL,
+		"S3_PRESIGN_TTL": &cfg.Storage.S3.PresignTTL,
+	} {
+		if v, ok := os.LookupEnv(envPrefix + env); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s%s: ожидается длительность (15m, 720h), получено %q", envPrefix, env, v)
+			}
+			*dst = d
+		}
+	}
+	return nil
+}
+
+// validate проверяет настройки до запуска сервера
+func (c Config) validate() error {
+	errs := []error{c.DB.validate()}
+	if _, _, err := net.SplitHostPort(c.Server.Listen); err != nil {
+		errs = append(errs, fmt.Errorf("server.listen: %v", err))
+	}
+	files := []struct{ field, path string }{
+		{"server.cert_file", c.Server.CertFile},
+		{"server.key_file", c.Server.KeyFile},
+	}
+	// Шрифты PDF проверяет setupDocumentRenderer: они нужны, только если активирован unidoc
+	for _, f := range files {
+		if f.path == "" {
+			errs = append(errs, fmt.Errorf("%s: не задан", f.field))
+		} else if _, err := os.Stat(f.path); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", f.field, err))
+		}
+	}
+	if len(c.Auth.JWTSecret) < 32 {
+		errs = append(errs, fmt.Errorf("auth.jwt_secret: нужно не менее 32 байт (используйте %sJWT_SECRET)", envPrefix))
+	}
+	if c.Auth.AccessTTL <= 0 || c.Auth.RefreshTTL <= c.Auth.AccessTTL {
+		errs = append(errs, fmt.Errorf("auth: нужно 0 < access_ttl < refresh_ttl"))
+	}
+	if c.Reports.Workers < 1 || c.Reports.Queue < 1 || c.Reports.TTL <= 0 || c.Reports.MaxStorageMB < 1 {
+		errs = append(errs, fmt.Errorf("reports: workers, queue, ttl и max_storage_mb должны быть больше нуля"))
+	}
+	if c.Storage.Backend != storageS3 && c.Reports.Dir == "" {
+		errs = append(errs, fmt.Errorf("reports.dir: не задан"))
+	}
+	if _, ok := watermarks[c.Documents.Watermark]; !ok {
+		errs = append(errs, fmt.Errorf("documents.watermark: неизвестное значение %q (confidential, draft, none)", c.Documents.Watermark))
+	}
+	switch c.Documents.Renderer {
+	case rendererAuto, rendererOOXML:
+	case rendererUnidoc:
+		if c.Unidoc.Key == "" {
+			errs = append(errs, fmt.Errorf("unidoc.key: не задан (используйте %sUNIDOC_KEY или documents.renderer: auto)", envPrefix))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("documents.renderer: неизвестное значение %q (auto, unidoc, ooxml)", c.Documents.Renderer))
+	}
+	if st, err := os.Stat(c.Storage.EmployeeImages); err != nil {
+		errs = append(errs, fmt.Errorf("storage.employee_images: %v", err))
+	} else if !st.IsDir() {
+		errs = append(errs, fmt.Errorf("storage.employee_images: %s не является директорией", c.Storage.EmployeeImages))
+	}
+	switch c.Storage.Backend {
+	case storageLocal:
+	case storageS3:
+		s3 := c.Storage.S3
+		for _, f := range []struct{ field, value string }{
+			{"storage.s3.endpoint", s3.Endpoint},
+			{"storage.s3.region", s3.Region},
+			{"storage.s3.bucket", s3.Bucket},
+			{"storage.s3.access_key", s3.AccessKey},
+			{"storage.s3.secret_key", s3.SecretKey},
+		} {
+			if f.value == "" {
+				errs = append(errs, fmt.Errorf("%s: не задан", f.field))
+			}
+		}
+		if s3.PresignTTL < 0 || s3.PresignTTL > s3MaxPresignTTL {
+			errs = append(errs, fmt.Errorf("storage.s3.presign_ttl: допустимо от 0 до %v", s3MaxPresignTTL))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("storage.backend: неизвестное значение %q (local, s3)", c.Storage.Backend))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("некорректная конфигурация:\n%v", err)
+	}
+	return nil
+}
+
+// validate проверяет настройки подключения к БД
+func (c DBConfig) validate() error {
+	var errs []error
+	switch c.Driver {
+	case "memory":
+	case "mysql":
+		if c.Host == "" {
+			errs = append(errs, fmt.Errorf("db.host: не задан"))
+		}
+		if p, err := strconv.Atoi(c.Port); err != nil || p <= 0 || p > 65535 {
+			errs = append(errs, fmt.Errorf("db.port: некорректный порт %q", c.Port))
+		}
+		if c.Name == "" {
+			errs = append(errs, fmt.Errorf("db.name: не задан"))
+		}
+		if c.User == "" {
+			errs = append(errs, fmt.Errorf("db.user: не задан"))
+		}
+		switch aggregateStrategy(c.Aggregates) {
+		case aggregatesAuto, aggregatesTrigger, aggregatesApp, aggregatesView:
+		default:
+			errs = append(errs, fmt.Errorf("db.aggregates: неизвестное значение %q (auto, trigger, app, view)", c.Aggregates))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("db.driver: неизвестное значение %q (mysql, memory)", c.Driver))
+	}
+	return errors.Join(errs...)
+}
+
 // Global variable----------------------------------------------
-var storageEmployeeImages = "./static/images"
+// Заполняется из Config.Storage при старте
+var storageEmployeeImages = defaultConfig().Storage.EmployeeImages
+
+// Заполняется из Config.Documents при старте
+var documentSettings = defaultConfig().Documents
@@ -2,11 +2,11 @@ package main
 
 import (
 	"bytes"
-	"database/sql"
 	"fmt"
 	"strconv"
 
 	"github.com/unidoc/unioffice/v2/color"
+	"github.com/unidoc/unioffice/v2/common"
 	"github.com/unidoc/unioffice/v2/document"
 	"github.com/unidoc/unioffice/v2/measurement"
 	"github.com/unidoc/unioffice/v2/schema/soo/wml"
@@ -54,129 +54,213 @@ func Italic(i bool) RunOption {
 
 //------------------------------------------------------------
 
-func setupDocument(deptID int, expectedSalary float64, expectedCount int, actualSalary float64, actualCount int, bossID sql.NullInt64, emps []emp) (bytes.Buffer, error) {
+// Styles constants and other
+const (
+	defaultFontFamily   = "Arial"
+	defaultFontSize     = 10
+	cellMarginSize      = 0.08 * measurement.Centimeter // Отступы таблицы
+	lineSpacing         = 4 * measurement.Point
+	lineSpacingExtended = 5 * measurement.Point
+
+	pageMargin     = 2 * measurement.Centimeter
+	headerMargin   = 1 * measurement.Centimeter
+	textWidth      = 210*measurement.Millimeter - 2*pageMargin // ширина полосы набора A4
+	watermarkColor = "D9D9D9"
+	photoThumbSize = 1.5 // сторона миниатюры фото в таблице, см
+)
 
-	// Styles constants and other
-	const (
-		defaultFontFamily   = "Arial"
-		defaultFontSize     = 10
-		cellMarginSize      = 0.08 * measurement.Centimeter // Отступы таблицы
-		lineSpacing         = 4 * measurement.Point
-		lineSpacingExtended = 5 * measurement.Point
-	)
-	var (
-		backgroundDefault     = color.White
-		alternativeBackground = color.FromHex("E7E6E6")
-		backgroundHighlight   = color.FromHex("E6F0FA") // светло-синий фон для выделения руководителя
-
-		borderColor = color.FromHex("4F81BD")
-
-		textColorHead           = color.White
-		textColorHighlight      = color.FromHex("2E74B5") // цвет текста для выделения руководиителя
-		textColorStat           = color.FromHex("888888")
-		textColorStatHeading    = color.FromHex("4F81BD") // lightblue
-		textColorStatHighlight1 = color.FromHex("4CAF50") // 2E74B5 (Blue)
-		textColorStatHighlight2 = color.FromHex("C00000") // Red
-	)
+var (
+	backgroundDefault     = color.White
+	alternativeBackground = color.FromHex("E7E6E6")
+	backgroundHighlight   = color.FromHex("E6F0FA") // светло-синий фон для выделения руководителя
 
-	// Bullets Style
-	SetupBulletStyle := func(bullets *document.NumberingDefinition) {
+	borderColor = color.FromHex("4F81BD")
 
-		lvl := bullets.AddLevel()
-		lvl.SetFormat(wml.ST_NumberFormatBullet)
-		lvl.SetAlignment(wml.ST_JcLeft)
-		lvl.Properties().SetLeftIndent(0.75 * measurement.Centimeter)
-		lvl.SetText("•")
+	textColorHead           = color.White
+	textColorHighlight      = color.FromHex("2E74B5") // цвет текста для выделения руководиителя
+	textColorStat           = color.FromHex("888888")
+	textColorStatHeading    = color.FromHex("4F81BD") // lightblue
+	textColorStatHighlight1 = color.FromHex("4CAF50") // 2E74B5 (Blue)
+	textColorStatHighlight2 = color.FromHex("C00000") // Red
+)
 
-	}
+// Bullets Style
+func SetupBulletStyle(bullets *document.NumberingDefinition) {
 
-	// Margins in a table
-	SetupMargins := func(properties *document.CellProperties, cellMarginSize measurement.Distance) {
+	lvl := bullets.AddLevel()
+	lvl.SetFormat(wml.ST_NumberFormatBullet)
+	lvl.SetAlignment(wml.ST_JcLeft)
+	lvl.Properties().SetLeftIndent(0.75 * measurement.Centimeter)
+	lvl.SetText("•")
 
-		cellMargins := properties.Margins()
+}
 
-		cellMargins.SetTop(cellMarginSize)
-		cellMargins.SetBottom(cellMarginSize)
-		cellMargins.SetLeft(cellMarginSize)
-		cellMargins.SetRight(cellMarginSize)
-	}
+// Margins in a table
+func SetupMargins(properties *document.CellProperties, cellMarginSize measurement.Distance) {
 
-	// Run Style
-	SetupRunProperties := func(Run document.Run, opts ...RunOption) {
+	cellMargins := properties.Margins()
 
-		properties := Run.Properties()
+	cellMargins.SetTop(cellMarginSize)
+	cellMargins.SetBottom(cellMarginSize)
+	cellMargins.SetLeft(cellMarginSize)
+	cellMargins.SetRight(cellMarginSize)
+}
 
-		options := RunOptions{
-			FontFamily: defaultFontFamily,
-			FontSize:   defaultFontSize,
-			TextColor:  color.Black,
-			Bold:       false,
-			Italic:     false,
-		}
+// Run Style
+func SetupRunProperties(Run document.Run, opts ...RunOption) {
 
-		for _, opt := range opts {
-			opt(&options)
-		}
+	properties := Run.Properties()
 
-		properties.SetFontFamily(options.FontFamily)
-		properties.SetSize(options.FontSize)
-		properties.SetColor(options.TextColor)
-		properties.SetBold(options.Bold)
-		properties.SetItalic(options.Italic)
+	options := RunOptions{
+		FontFamily: defaultFontFamily,
+		FontSize:   defaultFontSize,
+		TextColor:  color.Black,
+		Bold:       false,
+		Italic:     false,
 	}
 
-	// Table Style
-	SetupTableCell := func(cell document.Cell, backgroundColor color.Color, fontFamily string, fontSize measurement.Distance, fontColor color.Color, bold bool, text string) {
-		cprops := cell.Properties()
-		cprops.SetVerticalAlignment(wml.ST_VerticalJcCenter)
-		SetupMargins(&cprops, cellMarginSize)
-		if backgroundColor != backgroundDefault {
-			cprops.SetShading(wml.ST_ShdSolid, backgroundColor, color.Auto)
-		}
-
-		p := cell.AddParagraph()
-		p.SetAlignment(wml.ST_JcCenter)
-		run := p.AddRun()
-		run.AddText(text)
+	for _, opt := range opts {
+		opt(&options)
+	}
 
-		SetupRunProperties(
-			run,
-			FontFamily(fontFamily),
-			FontSize(fontSize),
-			TextColor(fontColor),
-			Bold(bold),
-		)
+	properties.SetFontFamily(options.FontFamily)
+	properties.SetSize(options.FontSize)
+	properties.SetColor(options.TextColor)
+	properties.SetBold(options.Bold)
+	properties.SetItalic(options.Italic)
+}
 
+// Table Style
+func SetupTableCell(cell document.Cell, backgroundColor color.Color, fontFamily string, fontSize measurement.Distance, fontColor color.Color, bold bool, text string) {
+	cprops := cell.Properties()
+	cprops.SetVerticalAlignment(wml.ST_VerticalJcCenter)
+	SetupMargins(&cprops, cellMarginSize)
+	if backgroundColor != backgroundDefault {
+		cprops.SetShading(wml.ST_ShdSolid, backgroundColor, color.Auto)
 	}
 
-	//
-	// 4) Создаём документ и настраиваем единственную секцию
+	p := cell.AddParagraph()
+	p.SetAlignment(wml.ST_JcCenter)
+	run := p.AddRun()
+	run.AddText(text)
+
+	SetupRunProperties(
+		run,
+		FontFamily(fontFamily),
+		FontSize(fontSize),
+		TextColor(fontColor),
+		Bold(bold),
+	)
+
+}
+
+// reportDocument DOCX-документ отчёта: один отдел (setupDocument) или вся компания (setupCompanyDocument)
+type reportDocument struct {
+	doc     *document.Document
+	bullets document.NumberingDefinition
+	photos  map[*reportPhoto]common.ImageRef // одно изображение на все вхождения (default.png)
+}
+
+// newReportDocument создаёт документ A4 с единственной секцией
+func newReportDocument() *reportDocument {
 	doc := document.New()
-	defer doc.Close()
 
 	// Bullets для маркированных списков
 	bullets := doc.Numbering.AddDefinition()
 	SetupBulletStyle(&bullets)
 
-	// Устанавливаем размер страницы и ориентацию
+	// Устанавливаем размер страницы, ориентацию и поля (колонтитулы — в полях)
 	sec := doc.BodySection()
 	sec.SetPageSizeAndOrientation(measurement.Millimeter*210, measurement.Millimeter*297, wml.ST_PageOrientationPortrait)
+	sec.SetPageMargins(pageMargin, pageMargin, pageMargin, pageMargin, headerMargin, headerMargin, 0)
 
-	// 5) Заголовок (Title 1)
-	{
-		p := doc.AddParagraph()
-		p.Properties().SetHeadingLevel(1)
-		p.SetAfterLineSpacing(lineSpacingExtended)
+	return &reportDocument{doc: doc, bullets: bullets, photos: map[*reportPhoto]common.ImageRef{}}
+}
 
+// headerFooter добавляет колонтитулы и водяной знак:
+// сверху компания и название отчёта, снизу время, автор и "Страница X из Y"
+func (d *reportDocument) headerFooter(meta documentMeta) {
+	doc := d.doc
+	sec := doc.BodySection()
+
+	// Текст у правого края — через табуляцию по ширине полосы набора
+	line := func(p document.Paragraph, left string) document.Run {
+		p.Properties().AddTabStop(textWidth, wml.ST_TabJcRight, wml.ST_TabTlcNone)
 		r := p.AddRun()
-		r.AddText(fmt.Sprintf("Сотрудники отдела №%d", deptID))
-		SetupRunProperties(
-			r,
-			Bold(true),
-			FontSize(18),
-			TextColor(textColorHighlight),
-		)
+		r.AddText(left)
+		r.AddTab()
+		SetupRunProperties(r, FontSize(8), TextColor(textColorStat))
+		return r
+	}
+
+	hdr := doc.AddHeader()
+	{
+		p := hdr.AddParagraph()
+		r := line(p, meta.Company)
+		r.AddText(meta.Title)
+	}
+	sec.SetHeader(hdr, wml.ST_HdrFtrDefault)
+
+	ftr := doc.AddFooter()
+	{
+		p := ftr.AddParagraph()
+		r := line(p, fmt.Sprintf("Сформировано %s, %s", meta.GeneratedAt.Format("02.01.2006 15:04"), meta.GeneratedBy))
+		r.AddText("Страница ")
+		for _, part := range []struct{ field, text string }{
+			{document.FieldCurrentPage, " из "},
+			{document.FieldNumberOfPages, ""},
+		} {
+			field := p.AddRun()
+			field.AddField(part.field)
+			SetupRunProperties(field, FontSize(8), TextColor(textColorStat))
+			if part.text != "" {
+				text := p.AddRun()
+				text.AddText(part.text)
+				SetupRunProperties(text, FontSize(8), TextColor(textColorStat))
+			}
+		}
 	}
+	sec.SetFooter(ftr, wml.ST_HdrFtrDefault)
+
+	if meta.Watermark != "" {
+		addWatermark(doc, meta.Watermark)
+	}
+}
+
+// addWatermark полупрозрачная диагональная надпись на каждой странице
+func addWatermark(doc *document.Document, text string) {
+	wm := doc.AddWatermarkText(text)
+	wm.SetFontFamily(defaultFontFamily)
+	wm.SetColor("#" + watermarkColor)
+	wm.SetOpacity(0.5)
+	wm.SetTextStyleItalic(false)
+	wm.EnableDiagonalLayout(true)
+}
+
+// heading добавляет заголовок уровня level; pageBreak — начать с новой страницы
+func (d *reportDocument) heading(text string, level int, size measurement.Distance, pageBreak bool) {
+	p := d.doc.AddParagraph()
+	p.Properties().SetHeadingLevel(level)
+	p.Properties().SetPageBreakBefore(pageBreak)
+	p.SetAfterLineSpacing(lineSpacingExtended)
+
+	r := p.AddRun()
+	r.AddText(text)
+	SetupRunProperties(
+		r,
+		Bold(true),
+		FontSize(size),
+		TextColor(textColorHighlight),
+	)
+}
+
+// departmentSection заголовок, таблица сотрудников и статистика отдела
+func (d *reportDocument) departmentSection(report departmentReport, title string, pageBreak bool) {
+	doc := d.doc
+	formatSalary := report.FormatSalary
+
+	// 5) Заголовок (Title 1)
+	d.heading(title, 1, 18, pageBreak)
 
 	// 6) Таблица со стилями
 	{
@@ -191,7 +275,11 @@ func setupDocument(deptID int, expectedSalary float64, expectedCount int, actual
 		hdr := tbl.AddRow()
 		hdr.Properties().SetHeight(1*measurement.Centimeter, wml.ST_HeightRuleAtLeast)
 
-		for _, txt := range []string{"ID", "Имя", "Статус", "Оклад"} {
+		columns := []string{"ID", "Имя", "Статус", "Оклад"}
+		if report.Photos {
+			columns = append([]string{"Фото"}, columns...)
+		}
+		for _, txt := range columns {
 
 			cell := hdr.AddCell()
 
@@ -207,10 +295,10 @@ func setupDocument(deptID int, expectedSalary float64, expectedCount int, actual
 		}
 
 		// Строки данных: чередуем фон и выделяем босса
-		for i, e := range emps {
+		for i, e := range report.Employees {
 			row := tbl.AddRow()
 			row.Properties().SetHeight(0.9*measurement.Centimeter, wml.ST_HeightRuleAtLeast)
-			isBoss := bossID.Valid && int(bossID.Int64) == e.ID
+			isBoss := report.BossID.Valid && int(report.BossID.Int64) == e.ID
 
 			type CellSpecStyle struct {
 				background color.Color
@@ -233,8 +321,11 @@ func setupDocument(deptID int, expectedSalary float64, expectedCount int, actual
 			} else {
 				cellSpecStyle.background = backgroundDefault // белый фон для нечетных строк
 			}
+			if report.Photos {
+				d.photoCell(row.AddCell(), cellSpecStyle.background, e.Photo)
+			}
 			for _, txt := range []string{
-				strconv.Itoa(e.ID), e.Name, e.Status, fmt.Sprintf("%.2f", e.Salary),
+				strconv.Itoa(e.ID), e.Name, e.Status, formatSalary(e.Salary),
 			} {
 				cell := row.AddCell()
 				SetupTableCell(
@@ -248,6 +339,23 @@ func setupDocument(deptID int, expectedSalary float64, expectedCount int, actual
 				)
 			}
 		}
+
+		// Пустой отдел: одна строка-пояснение на всю ширину таблицы
+		if len(report.Employees) == 0 {
+			row := tbl.AddRow()
+			row.Properties().SetHeight(0.9*measurement.Centimeter, wml.ST_HeightRuleAtLeast)
+			cell := row.AddCell()
+			cell.Properties().SetColumnSpan(len(columns))
+			SetupTableCell(
+				cell,
+				backgroundDefault,
+				defaultFontFamily,
+				10,
+				textColorStat,
+				false,
+				"В отделе нет сотрудников",
+			)
+		}
 	}
 
 	// 7) Статистика (Title 2 + списки)
@@ -292,22 +400,10 @@ func setupDocument(deptID int, expectedSalary float64, expectedCount int, actual
 		text  string
 		color color.Color
 	}{
-		{fmt.Sprintf("Суммарная зарплата: %.2f", expectedSalary), textColorStatHighlight1},
-		{fmt.Sprintf("Количество сотрудников: %d", expectedCount), textColorStatHighlight1},
+		{"Суммарная зарплата: " + formatSalary(report.ExpectedSalary), textColorStatHighlight1},
+		{fmt.Sprintf("Количество сотрудников: %d", report.ExpectedCount), textColorStatHighlight1},
 	} {
-		p := doc.AddParagraph()
-		p.SetAfterLineSpacing(lineSpacing)
-		p.SetNumberingLevel(0)
-		p.SetNumberingDefinition(bullets)
-
-		run := p.AddRun()
-		run.AddText(item.text)
-
-		SetupRunProperties(
-			run,
-			TextColor(item.color),
-		)
-
+		d.bullet(item.text, item.color)
 	}
 	// Фактические
 	{
@@ -321,25 +417,201 @@ func setupDocument(deptID int, expectedSalary float64, expectedCount int, actual
 		text  string
 		color color.Color
 	}{
-		{fmt.Sprintf("Суммарная зарплата: %.2f", actualSalary), textColorStatHighlight2},
-		{fmt.Sprintf("Количество сотрудников: %d", actualCount), textColorStatHighlight2},
+		{"Суммарная зарплата: " + formatSalary(report.ActualSalary), textColorStatHighlight2},
+		{fmt.Sprintf("Количество сотрудников: %d", report.ActualCount), textColorStatHighlight2},
+	} {
+		d.bullet(item.text, item.color)
+	}
+}
+
+// photoCell ячейка с миниатюрой фото; без фото — прочерк
+func (d *reportDocument) photoCell(cell document.Cell, backgroundColor color.Color, photo *reportPhoto) {
+	if photo == nil {
+		SetupTableCell(cell, backgroundColor, defaultFontFamily, 10, textColorStat, false, "—")
+		return
+	}
+	cprops := cell.Properties()
+	cprops.SetVerticalAlignment(wml.ST_VerticalJcCenter)
+	SetupMargins(&cprops, cellMarginSize)
+	if backgroundColor != backgroundDefault {
+		cprops.SetShading(wml.ST_ShdSolid, backgroundColor, color.Auto)
+	}
+	p := cell.AddParagraph()
+	p.SetAlignment(wml.ST_JcCenter)
+
+	ref, ok := d.photos[photo]
+	if !ok {
+		img, err := common.ImageFromBytes(photo.data)
+		if err == nil {
+			ref, err = d.doc.AddImage(img)
+		}
+		if err != nil {
+			p.AddRun().AddText("—")
+			return
+		}
+		d.photos[photo] = ref
+	}
+	inline, err := p.AddRun().AddDrawingInline(ref)
+	if err != nil {
+		return
+	}
+	w, h := photo.fit(photoThumbSize)
+	inline.SetSize(measurement.Distance(w)*measurement.Centimeter, measurement.Distance(h)*measurement.Centimeter)
+}
+
+// bullet пункт маркированного списка
+func (d *reportDocument) bullet(text string, textColor color.Color) {
+	p := d.doc.AddParagraph()
+	p.SetAfterLineSpacing(lineSpacing)
+	p.SetNumberingLevel(0)
+	p.SetNumberingDefinition(d.bullets)
+
+	run := p.AddRun()
+	run.AddText(text)
+	SetupRunProperties(
+		run,
+		TextColor(textColor),
+	)
+}
+
+// save сохраняет документ и освобождает его
+func (d *reportDocument) save() (bytes.Buffer, error) {
+	defer d.doc.Close()
+	buf := &bytes.Buffer{}
+	if err := d.doc.Save(buf); err != nil {
+		return bytes.Buffer{}, fmt.Errorf("ошибка сохранения документа: %v", err)
+	}
+	return *buf, nil
+}
+
+// setupDocument формирует DOCX-отчёт по отделу (PDF — setupPDFDocument)
+func setupDocument(report departmentReport, meta documentMeta) (bytes.Buffer, error) {
+	// 4) Создаём документ и настраиваем единственную секцию
+	d := newReportDocument()
+	d.headerFooter(meta)
+	d.departmentSection(report, fmt.Sprintf("Сотрудники отдела №%d", report.DeptID), false)
+
+	// 8) Сохраняем
+	return d.save()
+}
+
+// companyReportTitle заголовок титульной страницы сводного отчёта
+const companyReportTitle = "Сводный отчёт по отделам"
+
+// setupCompanyDocument формирует сводный DOCX-отчёт по нескольким отделам:
+// титульная страница, оглавление, раздел на каждый отдел и сводка по компании.
+func setupCompanyDocument(reports []departmentReport, meta documentMeta) (bytes.Buffer, error) {
+	d := newReportDocument()
+	d.headerFooter(meta)
+	doc := d.doc
+
+	// 1) Титульная страница
+	for _, line := range []struct {
+		text      string
+		size      measurement.Distance
+		textColor color.Color
+		bold      bool
+		before    measurement.Distance
+	}{
+		{companyReportTitle, 26, textColorHighlight, true, 200 * measurement.Point},
+		{fmt.Sprintf("Отделов в отчёте: %d", len(reports)), 14, textColorStatHeading, false, 12 * measurement.Point},
+		{"Сформирован: " + meta.GeneratedAt.Format("02.01.2006 15:04"), 11, textColorStat, false, 120 * measurement.Point},
+		{"Автор: " + meta.GeneratedBy, 11, textColorStat, false, 0},
 	} {
 		p := doc.AddParagraph()
+		p.SetAlignment(wml.ST_JcCenter)
+		p.SetBeforeSpacing(line.before)
 		p.SetAfterLineSpacing(lineSpacing)
-		p.SetNumberingLevel(0)
-		p.SetNumberingDefinition(bullets)
+		run := p.AddRun()
+		run.AddText(line.text)
+		SetupRunProperties(
+			run,
+			FontSize(line.size),
+			TextColor(line.textColor),
+			Bold(line.bold),
+		)
+	}
 
+	// 2) Оглавление: поле TOC по заголовкам первого уровня.
+	// Номера страниц расставит Word при открытии (UpdateFieldsOnOpen).
+	{
+		p := doc.AddParagraph()
+		p.Properties().SetPageBreakBefore(true)
+		p.SetAfterLineSpacing(lineSpacingExtended)
 		run := p.AddRun()
-		run.AddText(item.text)
+		run.AddText("Содержание")
 		SetupRunProperties(
 			run,
-			TextColor(item.color),
+			Bold(true),
+			FontSize(18),
+			TextColor(textColorHighlight),
 		)
+
+		doc.AddParagraph().AddRun().AddFieldWithFormatting(document.FieldTOC, `\o "1-1" \h \z \u`, true)
+		doc.Settings.SetUpdateFieldsOnOpen(true)
 	}
 
-	buf := &bytes.Buffer{}
-	if err := doc.Save(buf); err != nil {
-		return bytes.Buffer{}, fmt.Errorf("ошибка сохранения документа: %v", err)
+	// 3) Разделы отделов, каждый с новой страницы
+	for _, r := range reports {
+		d.departmentSection(r, fmt.Sprintf("Отдел №%d «%s»", r.DeptID, r.DeptName), true)
 	}
-	return *buf, nil
+
+	// 4) Сводка по компании
+	d.heading("Сводка по компании", 1, 18, true)
+	{
+		tbl := doc.AddTable()
+		props := tbl.Properties()
+		props.SetWidthPercent(100)
+		props.Borders().SetAll(
+			wml.ST_BorderSingle, borderColor, 1*measurement.Point,
+		)
+
+		addRow := func(background, textColor color.Color, size measurement.Distance, bold bool, cells ...string) {
+			row := tbl.AddRow()
+			row.Properties().SetHeight(0.9*measurement.Centimeter, wml.ST_HeightRuleAtLeast)
+			for _, txt := range cells {
+				SetupTableCell(row.AddCell(), background, defaultFontFamily, size, textColor, bold, txt)
+			}
+		}
+
+		addRow(borderColor, textColorHead, 12, true, "ID", "Отдел", "ОТД_РАЗМ", "Сотрудников", "ОТД_СОТР_ЗАРП", "Сумма окладов")
+
+		// Расхождение ОТДЕЛЫ и СОТРУДНИКИ выделяем красным, как в XLSX-сводке
+		allVisible := true
+		var expectedSalary, actualSalary float64
+		var expectedCount, actualCount int
+		for i, r := range reports {
+			background, textColor, bold := backgroundDefault, color.Black, false
+			if i%2 == 1 {
+				background = alternativeBackground
+			}
+			if r.ExpectedCount != r.ActualCount || r.SalariesVisible && r.ExpectedSalary != r.ActualSalary {
+				textColor, bold = textColorStatHighlight2, true
+			}
+			addRow(background, textColor, 10, bold,
+				strconv.Itoa(r.DeptID), r.DeptName,
+				strconv.Itoa(r.ExpectedCount), strconv.Itoa(r.ActualCount),
+				r.FormatSalary(r.ExpectedSalary), r.FormatSalary(r.ActualSalary),
+			)
+
+			allVisible = allVisible && r.SalariesVisible
+			expectedSalary += r.ExpectedSalary
+			actualSalary += r.ActualSalary
+			expectedCount += r.ExpectedCount
+			actualCount += r.ActualCount
+		}
+
+		// Итоги; суммы зарплат — только если видны все отделы
+		expectedTotal, actualTotal := "—", "—"
+		if allVisible {
+			expectedTotal, actualTotal = fmt.Sprintf("%.2f", expectedSalary), fmt.Sprintf("%.2f", actualSalary)
+		}
+		addRow(alternativeBackground, color.Black, 10, true,
+			"", "Итого",
+			strconv.Itoa(expectedCount), strconv.Itoa(actualCount),
+			expectedTotal, actualTotal,
+		)
+	}
+
+	return d.save()
 }
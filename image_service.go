@@ -1,54 +1,157 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 	"io"
-	"mime/multipart"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
 // storageEmployeeImages - путь к директории для хранения изображений сотрудников
 
-// Утилита: сохраняет загруженный файл и возвращает имя сохранённого файла
-func saveUploadedPhoto(fileHeader *multipart.FileHeader, empID int) (string, error) {
-	// Открываем загруженный файл
-	src, err := fileHeader.Open()
+// employeePhotoExtensions расширения фото сотрудников в порядке поиска
+var employeePhotoExtensions = []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp"}
+
+// defaultPhotoName заглушка для сотрудников без фото
+const defaultPhotoName = "default.png"
+
+// findEmployeePhoto ключ фото сотрудника empID в photoStore и его расширение; "" — фото нет
+func findEmployeePhoto(ctx context.Context, empID int) (string, string, error) {
+	for _, ext := range employeePhotoExtensions {
+		key := fmt.Sprintf("emp_%d%s", empID, ext)
+		_, err := photoStore.Stat(ctx, key)
+		if err == nil {
+			return key, ext, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return "", "", err
+		}
+	}
+	return "", "", nil
+}
+
+// reportPhoto фото сотрудника, встраиваемое в отчёт
+type reportPhoto struct {
+	data          []byte
+	format        string // png, jpeg или gif
+	width, height int    // пиксели
+}
+
+// readReportPhoto читает из photoStore изображение, которое можно встроить в DOCX
+func readReportPhoto(ctx context.Context, key string) (*reportPhoto, error) {
+	r, _, err := photoStore.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла %s: %v", key, err)
+	}
+	return decodeReportPhoto(key, data)
+}
+
+// decodeReportPhoto проверяет, что изображение name можно встроить в DOCX
+func decodeReportPhoto(name string, data []byte) (*reportPhoto, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
 	if err != nil {
-		return "", fmt.Errorf("не удалось открыть загруженный файл: %v", err)
+		return nil, fmt.Errorf("%s: неподдерживаемое изображение: %v", name, err)
 	}
-	defer src.Close()
+	return &reportPhoto{data: data, format: format, width: cfg.Width, height: cfg.Height}, nil
+}
 
-	// Определяем расширение из исходного имени
-	ext := filepath.Ext(fileHeader.Filename)
-	if ext == "" {
-		ext = ".jpg"
+// fit размеры фото, вписанного в квадрат со стороной side, с сохранением пропорций
+func (p *reportPhoto) fit(side float64) (w, h float64) {
+	if p.width >= p.height {
+		return side, side * float64(p.height) / float64(p.width)
 	}
-	filename := fmt.Sprintf("emp_%d%s", empID, ext)
-	fullPath := filepath.Join(storageEmployeeImages, filename)
+	return side * float64(p.width) / float64(p.height), side
+}
 
-	// Создаём файл на диске
-	dst, err := os.Create(fullPath)
+// attachPhotos загружает фото сотрудников отчёта; без фото или с фото в формате,
+// который нельзя встроить (bmp, webp), используется default.png.
+// Если нет и заглушки, ячейка фото остаётся пустой.
+func attachPhotos(ctx context.Context, report *departmentReport) error {
+	report.Photos = true
+	placeholder, err := readDefaultPhoto()
 	if err != nil {
-		return "", fmt.Errorf("ошибка создания файла на сервере: %v", err)
+		placeholder = nil
 	}
-	defer dst.Close()
+	for i := range report.Employees {
+		report.Employees[i].Photo = placeholder
+		key, _, err := findEmployeePhoto(ctx, report.Employees[i].ID)
+		if err != nil {
+			return fmt.Errorf("ошибка чтения фото сотрудника %d: %v", report.Employees[i].ID, err)
+		}
+		if key == "" {
+			continue
+		}
+		// Миниатюры достаточно для печати; у старых фото её может не быть
+		for _, candidate := range []string{photoVariantKey(key, photoSizeThumb), key} {
+			if photo, err := readReportPhoto(ctx, candidate); err == nil {
+				report.Employees[i].Photo = photo
+				break
+			}
+		}
+	}
+	return nil
+}
 
-	// Копируем содержимое
-	if _, err := io.Copy(dst, src); err != nil {
-		return "", fmt.Errorf("ошибка сохранения файла: %v", err)
+// readDefaultPhoto заглушка default.png; она входит в поставку приложения
+// и всегда лежит в storage.employee_images, какое бы хранилище ни было выбрано
+func readDefaultPhoto() (*reportPhoto, error) {
+	data, err := os.ReadFile(filepath.Join(storageEmployeeImages, defaultPhotoName))
+	if err != nil {
+		return nil, err
 	}
+	return decodeReportPhoto(defaultPhotoName, data)
+}
 
-	return filename, nil
+// save сохраняет все размеры фото сотрудника empID и возвращает ключ основного файла
+// (размер original); остальные размеры хранятся рядом (см. photoVariantKey)
+func (p processedPhoto) save(ctx context.Context, empID int) (string, error) {
+	key := fmt.Sprintf("emp_%d.jpg", empID)
+	for size, data := range p {
+		if err := photoStore.Put(ctx, photoVariantKey(key, size), data, "image/jpeg"); err != nil {
+			return "", fmt.Errorf("ошибка сохранения файла: %v", err)
+		}
+	}
+	return key, nil
 }
 
-// Утилита: удаляет файл по имени
-// Возвращает ошибку, если файл не найден или не может быть удалён
-func deletePhoto(filename string) error {
+// photoVariantKey ключ фото размера size: emp_<id>.jpg -> emp_<id>_thumb.jpg.
+// Фото, загруженные до появления размеров, есть только в исходном файле.
+func photoVariantKey(key, size string) string {
+	if size == photoSizeOriginal {
+		return key
+	}
+	return strings.TrimSuffix(key, path.Ext(key)) + "_" + size + ".jpg"
+}
 
-	fullPath := filepath.Join(storageEmployeeImages, filename)
-	if err := os.Remove(fullPath); err != nil {
+// Утилита: удаляет фото по имени вместе с уменьшенными копиями
+// Возвращает ошибку, если фото не найдено или не может быть удалено
+func deletePhoto(ctx context.Context, filename string) error {
+	if err := photoStore.Delete(ctx, filename); err != nil {
 		return fmt.Errorf("ошибка удаления файла %s: %v", filename, err)
 	}
+	// Уменьшенные копии могут отсутствовать у старых фото
+	for size := range photoSizes {
+		variant := photoVariantKey(filename, size)
+		if variant == filename {
+			continue
+		}
+		if err := photoStore.Delete(ctx, variant); err != nil && !errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("ошибка удаления файла %s: %v", variant, err)
+		}
+	}
 	return nil
 }
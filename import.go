@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Ограничения импорта сотрудников
+const (
+	maxImportSize = 10 << 20 // байт
+	maxImportRows = 5000
+)
+
+// Статусы сотрудника (СЛУ_СТАТ)
+var employeeStatuses = map[string]bool{
+	"active":   true,
+	"inactive": true,
+	"fired":    true,
+}
+
+// Столбцы файла импорта; порядок в файле произвольный, регистр не важен
+var importColumns = []string{"name", "status", "salary", "dept_id"}
+
+// ImportError ошибка строки файла; Row — номер строки в файле (шапка — строка 1)
+type ImportError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportReport результат POST /api/employees/import
+type ImportReport struct {
+	DryRun  bool          `json:"dry_run"`
+	Rows    int           `json:"rows"`  // строк с данными
+	Valid   int           `json:"valid"` // строк без ошибок
+	Errors  []ImportError `json:"errors"`
+	Created []int         `json:"created,omitempty"` // ID добавленных сотрудников
+}
+
+// importRow строка файла: номер и значения по именам столбцов
+type importRow struct {
+	line   int
+	values map[string]string
+}
+
+// readImportFile разбирает CSV или XLSX (по расширению имени файла)
+func readImportFile(filename string, data []byte) ([]importRow, error) {
+	var records [][]string
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		records, err = readCSVRecords(data)
+	case ".xlsx":
+		records, err = readXLSXRecords(data)
+	default:
+		return nil, fmt.Errorf("неподдерживаемый формат файла: ожидается .csv или .xlsx")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("файл пуст")
+	}
+
+	// Шапка: имена столбцов -> индексы
+	index := map[string]int{}
+	for i, h := range records[0] {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	var missing []string
+	for _, col := range importColumns {
+		if _, ok := index[col]; !ok {
+			missing = append(missing, col)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("в шапке нет столбцов: %s", strings.Join(missing, ", "))
+	}
+
+	var rows []importRow
+	for i, rec := range records[1:] {
+		row := importRow{line: i + 2, values: map[string]string{}}
+		empty := true
+		for _, col := range importColumns {
+			if j := index[col]; j < len(rec) {
+				row.values[col] = strings.TrimSpace(rec[j])
+				empty = empty && row.values[col] == ""
+			}
+		}
+		if empty {
+			continue // пустые строки в конце таблиц — обычное дело
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) > maxImportRows {
+		return nil, fmt.Errorf("слишком много строк: %d, не более %d", len(rows), maxImportRows)
+	}
+	return rows, nil
+}
+
+// readCSVRecords читает CSV с разделителем "," или ";" (определяется по шапке);
+// записи идут по номеру строки файла, на месте пустых строк — nil
+func readCSVRecords(data []byte) ([][]string, error) {
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	r := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		r.Comma = ';'
+	}
+	r.FieldsPerRecord = -1
+	var records [][]string
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка разбора CSV: %v", err)
+		}
+		// csv пропускает пустые строки; records[i] — строка i+1 файла, как в XLSX
+		line, _ := r.FieldPos(0)
+		for len(records) < line-1 {
+			records = append(records, nil)
+		}
+		records = append(records, rec)
+	}
+}
+
+// readXLSXRecords читает первый лист XLSX; числа берутся без форматирования.
+// Разбор не зависит от рендерера документов и лицензии unidoc (см. readXLSXSheet)
+// и останавливается на первой заполненной строке после шапки и maxImportRows строк.
+func readXLSXRecords(data []byte) ([][]string, error) {
+	records, err := readXLSXSheet(data, maxImportRows+1)
+	if errors.Is(err, errSheetTooLong) {
+		return nil, fmt.Errorf("слишком много строк: не более %d", maxImportRows)
+	} else if err != nil {
+		return nil, fmt.Errorf("ошибка разбора XLSX: %v", err)
+	}
+	return records, nil
+}
+
+// columnIndex номер столбца по букве: A -> 0, AA -> 26
+func columnIndex(col string) int {
+	n := 0
+	for _, ch := range col {
+		n = n*26 + int(ch-'A') + 1
+	}
+	return n - 1
+}
+
+// validateImportRows проверяет строки и возвращает сотрудников и ошибки.
+// departments — существующие отделы.
+func validateImportRows(rows []importRow, departments map[int]bool) ([]Employee, []ImportError) {
+	var emps []Employee
+	var errs []ImportError
+	for _, row := range rows {
+		fail := func(field, format string, args ...interface{}) {
+			errs = append(errs, ImportError{Row: row.line, Field: field, Message: fmt.Sprintf(format, args...)})
+		}
+		before := len(errs)
+		e := Employee{Name: row.values["name"], Status: row.values["status"]}
+
+		if e.Name == "" {
+			fail("name", "не задано имя")
+		} else if utf8.RuneCountInString(e.Name) > 100 {
+			fail("name", "имя длиннее 100 символов")
+		}
+		if !employeeStatuses[e.Status] {
+			fail("status", "некорректный статус %q: active, inactive или fired", e.Status)
+		}
+		// Excel в русской локали пишет дробную часть через запятую
+		salary, err := strconv.ParseFloat(strings.ReplaceAll(row.values["salary"], ",", "."), 64)
+		switch {
+		case err != nil:
+			fail("salary", "некорректная зарплата %q", row.values["salary"])
+		case !(salary >= 0 && salary < 1e10): // заодно отсекает NaN
+			fail("salary", "зарплата должна быть от 0 до 9999999999.99")
+		default:
+			e.Salary = salary
+		}
+		deptID, err := strconv.Atoi(row.values["dept_id"])
+		switch {
+		case err != nil:
+			fail("dept_id", "некорректный dept_id %q", row.values["dept_id"])
+		case !departments[deptID]:
+			fail("dept_id", "отдел %d не существует", deptID)
+		default:
+			e.DeptID = deptID
+		}
+
+		if len(errs) == before {
+			emps = append(emps, e)
+		}
+	}
+	return emps, errs
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// buildTestXLSX книга с одним листом: sheetData — содержимое <sheetData>,
+// shared — таблица общих строк (nil — без xl/sharedStrings.xml)
+func buildTestXLSX(t *testing.T, sheetData string, shared []string) []byte {
+	t.Helper()
+	parts := []ooxmlPart{
+		{"[Content_Types].xml", xmlProlog + `<Types xmlns="` + nsTypes + `"/>`},
+		{"xl/workbook.xml", xmlProlog + `<workbook xmlns="` + nsSheetML + `" xmlns:r="` + nsRels + `">` +
+			`<sheets><sheet name="Лист1" sheetId="1" r:id="rId7"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xmlProlog + `<Relationships xmlns="` + nsPkgRels + `">` +
+			`<Relationship Id="rId7" Type="` + nsRels + `/worksheet" Target="/xl/worksheets/data.xml"/></Relationships>`},
+		{"xl/worksheets/data.xml", xmlProlog + `<worksheet xmlns="` + nsSheetML + `"><sheetData>` + sheetData + `</sheetData></worksheet>`},
+	}
+	if shared != nil {
+		sst := xmlProlog + `<sst xmlns="` + nsSheetML + `">`
+		for _, s := range shared {
+			sst += `<si><t>` + xmlEscape(s) + `</t></si>`
+		}
+		parts = append(parts, ooxmlPart{"xl/sharedStrings.xml", sst + `</sst>`})
+	}
+	buf, err := writeOOXMLPackage(parts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadXLSXSheet(t *testing.T) {
+	shared := []string{"name", "Анна & Co"}
+	data := buildTestXLSX(t, `
+		<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="inlineStr"><is><t>status</t></is></c></row>
+		<row r="3"><c r="A3" t="s"><v>1</v></c><c r="C3"><v>1500.5</v></c></row>
+		<row><c t="inlineStr"><is><r><t>Бо</t></r><r><t>рис</t></r></is></c><c t="b"><v>1</v></c></row>
+		<row r="6"><c r="B6" t="str"><v>по формуле</v></c></row>`, shared)
+
+	records, err := readXLSXSheet(data, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"name", "status"},
+		nil, // пропущенная строка
+		{"Анна & Co", "", "1500.5"},
+		{"Борис", "TRUE"},
+		nil,
+		{"", "по формуле"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("строки %q, ожидалось %q", records, want)
+	}
+
+	for _, tt := range []struct {
+		name      string
+		sheetData string
+		shared    []string
+		wantErr   string
+	}{
+		{"ссылка за пределы общих строк", `<row r="1"><c r="A1" t="s"><v>5</v></c></row>`, shared, "общую строку"},
+		{"общие строки без таблицы", `<row r="1"><c r="A1" t="s"><v>0</v></c></row>`, nil, "общую строку"},
+		{"строки не по порядку", `<row r="2"><c r="A2"><v>1</v></c></row><row r="1"><c r="A1"><v>1</v></c></row>`, nil, "номер строки"},
+		{"столбец за пределами листа", `<row r="1"><c r="ZZZZ1"><v>1</v></c></row>`, nil, "некорректная ячейка"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readXLSXSheet(buildTestXLSX(t, tt.sheetData, tt.shared), 10)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ошибка %v, ожидалась %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadXLSXSheetMaxRows(t *testing.T) {
+	// Оформленные пустые строки за пределом не мешают и не попадают в результат
+	data := buildTestXLSX(t, `
+		<row r="1"><c r="A1"><v>1</v></c></row>
+		<row r="2"><c r="A2"><v>2</v></c></row>
+		<row r="3"><c r="A3" s="1"/></row>
+		<row r="1048576"><c r="A1048576" t="inlineStr"><is><t></t></is></c></row>`, nil)
+	records, err := readXLSXSheet(data, 2)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("строки %q: %v", records, err)
+	}
+
+	// Заполненная строка за пределом останавливает разбор
+	data = buildTestXLSX(t, `
+		<row r="1"><c r="A1"><v>1</v></c></row>
+		<row r="1048576"><c r="A1048576"><v>2</v></c></row>`, nil)
+	if _, err := readXLSXSheet(data, 2); !errors.Is(err, errSheetTooLong) {
+		t.Fatalf("ошибка %v, ожидалась errSheetTooLong", err)
+	}
+}
+
+// importCSV файл импорта из строк
+func importCSV(lines ...string) []byte {
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// xlsxImportRows лист импорта: шапка и строки в общих и встроенных строках
+func xlsxImportRows(t *testing.T, extra string) []byte {
+	return buildTestXLSX(t, `
+		<row r="1">
+			<c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c>
+			<c r="C1" t="inlineStr"><is><t>Salary</t></is></c><c r="D1" t="inlineStr"><is><t>DEPT_ID</t></is></c>
+		</row>
+		<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2" t="inlineStr"><is><t>active</t></is></c><c r="C2"><v>1200.5</v></c><c r="D2"><v>1</v></c></row>
+		<row r="4"><c r="A4" t="inlineStr"><is><t>Борис</t></is></c><c r="B4" t="s"><v>3</v></c><c r="D4"><v>2</v></c></row>
+		<row r="5"><c r="B5" s="1"/></row>`+extra, []string{"name", "status", "Анна", "fired"})
+}
+
+func TestReadImportFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		data     []byte
+		want     []importRow
+		wantErr  string
+	}{
+		{
+			name:     "CSV с запятой",
+			filename: "staff.csv",
+			data:     importCSV("name,status,salary,dept_id", "Анна,active,1000,1", "", "Борис,fired,2000,2"),
+			want: []importRow{
+				{line: 2, values: map[string]string{"name": "Анна", "status": "active", "salary": "1000", "dept_id": "1"}},
+				{line: 4, values: map[string]string{"name": "Борис", "status": "fired", "salary": "2000", "dept_id": "2"}},
+			},
+		},
+		{
+			name:     "CSV с ; и BOM, столбцы в другом порядке",
+			filename: "STAFF.CSV",
+			data:     []byte("\ufeffDept_ID;Salary;Name;Status;Note\r\n1;1 000,50;\"Иванов; Иван\";active;x\r\n;;;;\r\n"),
+			want: []importRow{
+				{line: 2, values: map[string]string{"name": "Иванов; Иван", "status": "active", "salary": "1 000,50", "dept_id": "1"}},
+			},
+		},
+		{
+			name:     "короткая строка",
+			filename: "staff.csv",
+			data:     importCSV("name,status,salary,dept_id", "Анна,active"),
+			want: []importRow{
+				{line: 2, values: map[string]string{"name": "Анна", "status": "active"}},
+			},
+		},
+		{
+			name:     "XLSX: общие и встроенные строки, пропуски",
+			filename: "staff.xlsx",
+			data:     xlsxImportRows(t, ""),
+			want: []importRow{
+				{line: 2, values: map[string]string{"name": "Анна", "status": "active", "salary": "1200.5", "dept_id": "1"}},
+				{line: 4, values: map[string]string{"name": "Борис", "status": "fired", "salary": "", "dept_id": "2"}},
+			},
+		},
+		{
+			name:     "в шапке нет столбцов",
+			filename: "staff.csv",
+			data:     importCSV("name,salary", "Анна,1000"),
+			wantErr:  "в шапке нет столбцов: status, dept_id",
+		},
+		{
+			name:     "пустой файл",
+			filename: "staff.csv",
+			data:     nil,
+			wantErr:  "файл пуст",
+		},
+		{
+			name:     "неизвестный формат",
+			filename: "staff.xls",
+			data:     []byte("name"),
+			wantErr:  "неподдерживаемый формат",
+		},
+		{
+			name:     "CSV с незакрытой кавычкой",
+			filename: "staff.csv",
+			data:     importCSV("name,status,salary,dept_id", `"Анна,active,1000,1`),
+			wantErr:  "ошибка разбора CSV",
+		},
+		{
+			name:     "не zip",
+			filename: "staff.xlsx",
+			data:     []byte("name,status"),
+			wantErr:  "ошибка разбора XLSX",
+		},
+		{
+			name:     "XLSX длиннее maxImportRows",
+			filename: "staff.xlsx",
+			data:     xlsxImportRows(t, fmt.Sprintf(`<row r="%d"><c r="A%[1]d"><v>1</v></c></row>`, maxImportRows+2)),
+			wantErr:  fmt.Sprintf("слишком много строк: не более %d", maxImportRows),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readImportFile(tt.filename, tt.data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ошибка %v, ожидалась %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(rows, tt.want) {
+				t.Fatalf("строки %+v, ожидалось %+v", rows, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadImportFileRowLimit(t *testing.T) {
+	lines := []string{"name,status,salary,dept_id"}
+	for i := 0; i < maxImportRows; i++ {
+		lines = append(lines, fmt.Sprintf("Сотрудник %d,active,1000,1", i))
+	}
+	if rows, err := readImportFile("staff.csv", importCSV(lines...)); err != nil || len(rows) != maxImportRows {
+		t.Fatalf("строк %d: %v", len(rows), err)
+	}
+	lines = append(lines, "Лишний,active,1000,1")
+	if _, err := readImportFile("staff.csv", importCSV(lines...)); err == nil || !strings.Contains(err.Error(), "слишком много строк") {
+		t.Fatalf("ошибка %v", err)
+	}
+}
+
+func TestValidateImportRows(t *testing.T) {
+	row := func(name, status, salary, deptID string) importRow {
+		return importRow{line: 2, values: map[string]string{"name": name, "status": status, "salary": salary, "dept_id": deptID}}
+	}
+	departments := map[int]bool{1: true}
+
+	tests := []struct {
+		name    string
+		row     importRow
+		want    Employee
+		wantErr []string // поля с ошибками
+	}{
+		{"корректная строка", row("Анна", "active", "1000", "1"), Employee{Name: "Анна", Status: "active", Salary: 1000, DeptID: 1}, nil},
+		{"десятичная запятая", row("Анна", "inactive", "1234,56", "1"), Employee{Name: "Анна", Status: "inactive", Salary: 1234.56, DeptID: 1}, nil},
+		{"нулевая зарплата", row("Анна", "fired", "0", "1"), Employee{Name: "Анна", Status: "fired", DeptID: 1}, nil},
+		{"неизвестный отдел", row("Анна", "active", "1000", "7"), Employee{}, []string{"dept_id"}},
+		{"dept_id не число", row("Анна", "active", "1000", "первый"), Employee{}, []string{"dept_id"}},
+		{"без имени", row("", "active", "1000", "1"), Employee{}, []string{"name"}},
+		{"длинное имя", row(strings.Repeat("я", 101), "active", "1000", "1"), Employee{}, []string{"name"}},
+		{"неизвестный статус", row("Анна", "Active", "1000", "1"), Employee{}, []string{"status"}},
+		{"пробел в зарплате", row("Анна", "active", "1 000", "1"), Employee{}, []string{"salary"}},
+		{"отрицательная зарплата", row("Анна", "active", "-1", "1"), Employee{}, []string{"salary"}},
+		{"NaN", row("Анна", "active", "NaN", "1"), Employee{}, []string{"salary"}},
+		{"слишком большая зарплата", row("Анна", "active", "1e10", "1"), Employee{}, []string{"salary"}},
+		{"все поля с ошибками", row("", "", "", ""), Employee{}, []string{"name", "status", "salary", "dept_id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emps, errs := validateImportRows([]importRow{tt.row}, departments)
+			var fields []string
+			for _, e := range errs {
+				if e.Row != 2 || e.Message == "" {
+					t.Errorf("ошибка %+v", e)
+				}
+				fields = append(fields, e.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.wantErr) {
+				t.Fatalf("ошибки в полях %v, ожидались %v", fields, tt.wantErr)
+			}
+			switch {
+			case tt.wantErr != nil && len(emps) != 0:
+				t.Fatalf("строка с ошибками принята: %+v", emps)
+			case tt.wantErr == nil && (len(emps) != 1 || emps[0] != tt.want):
+				t.Fatalf("сотрудники %+v, ожидался %+v", emps, tt.want)
+			}
+		})
+	}
+}
+
+// upload выполняет импорт файла filename с содержимым data
+func (s *testServer) upload(target, token, filename string, data []byte) testResponse {
+	s.t.Helper()
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	w, _ := mw.CreateFormFile("file", filename)
+	w.Write(data)
+	mw.Close()
+	return s.do(http.MethodPost, target, token, body, mw.FormDataContentType())
+}
+
+func TestImportEmployeesAPI(t *testing.T) {
+	s := newTestServer(t)
+	hr := s.token("hr", roleHR, nil)
+	dept := s.createDepartment("Склад")
+	valid := importCSV("name;status;salary;dept_id", fmt.Sprintf("Анна;active;1000,5;%d", dept), fmt.Sprintf("Борис;fired;2000;%d", dept))
+	invalid := importCSV("name;status;salary;dept_id", fmt.Sprintf("Анна;active;1000;%d", dept), "Борис;active;2000;999")
+
+	employees := func() int {
+		_, total, err := s.repos.Employees.List(context.Background(), EmployeeQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return total
+	}
+
+	tests := []struct {
+		name       string
+		target     string
+		token      string
+		data       []byte
+		code       int
+		wantValid  int
+		wantErrors int
+		created    int // сотрудников добавлено запросом
+	}{
+		{"dry-run корректного файла", "/api/employees/import?dry_run=true", hr, valid, http.StatusOK, 2, 0, 0},
+		{"dry-run файла с ошибками", "/api/employees/import?dry_run=true", hr, invalid, http.StatusOK, 1, 1, 0},
+		{"файл с ошибками — ни одного", "/api/employees/import", hr, invalid, http.StatusUnprocessableEntity, 1, 1, 0},
+		{"импорт корректного файла", "/api/employees/import", hr, valid, http.StatusCreated, 2, 0, 2},
+		{"viewer не импортирует", "/api/employees/import", s.token("v", roleViewer, nil), valid, http.StatusForbidden, 0, 0, 0},
+		{"некорректный dry_run", "/api/employees/import?dry_run=да", hr, valid, http.StatusBadRequest, 0, 0, 0},
+		{"только шапка", "/api/employees/import", hr, importCSV("name,status,salary,dept_id"), http.StatusBadRequest, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := employees()
+			resp := s.upload(tt.target, tt.token, "staff.csv", tt.data).expect(t, tt.code)
+			if got := employees() - before; got != tt.created {
+				t.Fatalf("добавлено %d сотрудников, ожидалось %d", got, tt.created)
+			}
+			if tt.wantValid == 0 {
+				return
+			}
+			var report ImportReport
+			resp.decode(t, &report)
+			if report.Rows != 2 || report.Valid != tt.wantValid || len(report.Errors) != tt.wantErrors || len(report.Created) != tt.created {
+				t.Fatalf("отчёт %+v", report)
+			}
+			if tt.wantErrors > 0 && (report.Errors[0].Row != 3 || report.Errors[0].Field != "dept_id") {
+				t.Fatalf("ошибка %+v", report.Errors[0])
+			}
+		})
+	}
+
+	// Импорт одной транзакцией: суммы отдела учитывают всех добавленных
+	d, err := s.repos.Departments.Get(context.Background(), dept)
+	if err != nil || d.Size != 2 || d.TotalSalary != 3000.5 {
+		t.Fatalf("отдел %+v: %v", d, err)
+	}
+}
@@ -1,20 +1,22 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/unidoc/unioffice/v2/common/license"
 )
 
 // setupDatabase initializes the database connection
-func setupDatabase() (*sql.DB, error) {
+func setupDatabase(dsn string) (*sql.DB, error) {
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка подключения к БД: %v", err)
@@ -29,14 +31,14 @@ func setupDatabase() (*sql.DB, error) {
 }
 
 // Wait for the database to be available. Pinging it every {backoff} seconds
-func waitForDB() *sql.DB {
+func waitForDB(dsn string) *sql.DB {
 	var (
 		conn *sql.DB
 		err  error
 	)
 	backoff := 2 * time.Second
 	for {
-		conn, err = setupDatabase()
+		conn, err = setupDatabase(dsn)
 		if err != nil {
 			log.Printf("Ошибка открытия БД: %v. Повтор через %s…", err, backoff)
 		} else if pingErr := conn.Ping(); pingErr != nil {
@@ -70,52 +72,190 @@ func dbAliveMiddleware(db *sql.DB) gin.HandlerFunc {
 	}
 }
 
-// API маршруты для работы с отделами и сотрудниками
-func setupAPIRoutes(r *gin.Engine, db *sql.DB) {
-	api := r.Group("/api")
+// corsMiddleware CORS для клиента с другого адреса (!Client): к заголовкам по умолчанию
+// добавлен Authorization с access-токеном, клиенту доступны имя файла и адрес задания
+func corsMiddleware() gin.HandlerFunc {
+	config := cors.DefaultConfig()
+	config.AllowAllOrigins = true
+	config.AllowHeaders = append(config.AllowHeaders, "Authorization")
+	config.ExposeHeaders = []string{"Content-Disposition", "Location"}
+	return cors.New(config)
+}
+
+// API маршруты для работы с отделами и сотрудниками.
+// Кроме /api/auth/*, все маршруты требуют access-токен и роль не ниже указанной.
+func setupAPIRoutes(r *gin.Engine, repos Repositories, tokens *tokenIssuer, jobs *reportJobs) {
+	api := r.Group("/api", actorMiddleware())
+
+	// Авторизация
+	api.POST("/auth/login", loginAPI(repos.Users, tokens))
+	api.POST("/auth/refresh", refreshTokenAPI(repos.Users, tokens))
+	api.POST("/auth/logout", logoutAPI(repos.Users))
+
+	viewer, hr, admin := requireRole(roleViewer), requireRole(roleHR), requireRole(roleAdmin)
+	private := api.Group("", authMiddleware(tokens))
 	{
+		private.GET("/auth/me", viewer, getCurrentUserAPI())
+		private.POST("/auth/link", viewer, createSignedLinkAPI(tokens))
+
 		// Отделы
-		api.GET("/departments", getDepartments(db))
-		api.PUT("/departments/:id", updateDepartmentAPI(db))
+		private.GET("/departments", viewer, getDepartments(repos.Departments))
+		private.GET("/departments/export", viewer, exportDepartmentsHandler(repos))
+		private.POST("/departments", hr, createDepartmentAPI(repos.Departments))
+		private.PUT("/departments/:id", hr, updateDepartmentAPI(repos.Departments))
+		private.DELETE("/departments/:id", admin, deleteDepartmentAPI(repos.Departments))
+		private.GET("/departments/reconcile", admin, reconcileDepartmentsAPI(repos.Departments))
+		private.POST("/departments/reconcile", admin, reconcileDepartmentsAPI(repos.Departments))
 
 		// Служащие
-		api.GET("/employees", getEmployees(db))
-		api.GET("/employees/:id", getEmployee(db))
-		api.GET("/employeesByDepart/:id", getEmployeeByDepartment(db))
-		api.POST("/employees", createEmployeeAPI(db))
-		api.PUT("/employees/:id", updateEmployeeAPI(db))
-		api.DELETE("/employees/:id", deleteEmployeeAPI(db))
+		private.GET("/employees", viewer, getEmployees(repos))
+		private.GET("/employees/search", viewer, searchEmployeesAPI(repos))
+		private.GET("/employees/:id", viewer, getEmployee(repos))
+		private.GET("/employeesByDepart/:id", viewer, getEmployeeByDepartment(repos))
+		private.POST("/employees", hr, createEmployeeAPI(repos.Employees))
+		private.POST("/employees/import", hr, importEmployeesAPI(repos))
+		private.PUT("/employees/:id", viewer, updateEmployeeAPI(repos)) // руководитель — в своём отделе
+		private.DELETE("/employees/:id", hr, deleteEmployeeAPI(repos.Employees))
+
+		// История и запланированные изменения зарплат
+		private.GET("/employees/:id/salary-history", hr, getSalaryHistoryAPI(repos.Salaries))
+		private.POST("/employees/:id/salary-history", hr, scheduleSalaryChangeAPI(repos.Salaries))
+		private.DELETE("/employees/:id/salary-history/:change", hr, cancelSalaryChangeAPI(repos.Salaries))
+
+		// Журнал аудита
+		private.GET("/audit", admin, getAuditAPI(repos.Audit))
 
 		// Return image URL for employee photo
-		api.GET("/employees/:id/photo", getEmployeePhotoHandler())
+		private.GET("/employees/:id/photo", viewer, getEmployeePhotoHandler())
 
 		// Return document MS Word for employee
-		api.GET("/employeesByDepart/:id/document", getEmployeeByDepartDocumentHandler(db))
+		private.GET("/employeesByDepart/:id/document", viewer, getEmployeeByDepartDocumentHandler(repos))
+
+		// Отчёты и шаблоны отчётов
+		private.GET("/reports/company", viewer, getCompanyReportHandler(repos))
+		private.POST("/reports", viewer, createReportJobAPI(repos, jobs))
+		private.GET("/reports/:id", viewer, getReportJobAPI(jobs))
+		private.GET("/reports/:id/download", viewer, downloadReportJobHandler(jobs))
+		private.GET("/reports/templates", viewer, listTemplatesAPI(repos.Templates))
+		private.POST("/reports/templates", hr, uploadTemplateAPI(repos.Templates))
+		private.GET("/reports/templates/:name", viewer, downloadTemplateHandler(repos.Templates))
 	}
 }
 
-func init() {
-	// Загружаем API-ключ из переменной окружения
-	err := license.SetMeteredKey(unidocKey)
-	if err != nil {
-		panic(err)
+func main() {
+	// Первый аргумент без "-" — имя команды, по умолчанию запускается сервер
+	args := os.Args[1:]
+	command := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
 	}
 
+	var err error
+	switch command {
+	case "serve":
+		err = runServer(args)
+	case "migrate":
+		err = runMigrate(args)
+	case "reconcile":
+		err = runReconcile(args)
+	case "useradd":
+		err = runUserAdd(args)
+	default:
+		err = fmt.Errorf("неизвестная команда %q (serve, migrate, reconcile, useradd)", command)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
-func main() {
+// runServer запускает HTTPS API
+func runServer(args []string) error {
 
-	// Настройка соединения с базой данных
-	db := waitForDB()
+	// Загружаем конфигурацию: файл, переменные окружения APP_*, флаги
+	cfg, _, err := loadConfig("serve", args)
+	if err != nil {
+		return err
+	}
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	storageEmployeeImages = cfg.Storage.EmployeeImages
+	documentSettings = cfg.Documents
+
+	// Выбираем рендерер документов: без лицензии unidoc сервер всё равно запускается
+	renderer, err := setupDocumentRenderer(cfg)
+	if err != nil {
+		return err
+	}
+	documentRenderer = renderer
+	log.Printf("Документы формируются рендерером %s", renderer.Name())
+
+	// Хранилище фото сотрудников: локальная директория или S3-совместимый бакет
+	store, err := newBlobStore(cfg.Storage)
+	if err != nil {
+		return err
+	}
+	photoStore, photoPresignTTL = store, cfg.Storage.S3.PresignTTL
+	log.Printf("Фото сотрудников хранятся в %s", cfg.Storage.Backend)
 
 	r := gin.Default()
-	r.Use(cors.Default())
+	r.Use(corsMiddleware())
+
+	var repos Repositories
+	switch cfg.DB.Driver {
+	case "memory":
+		log.Println("Используется хранилище в памяти, данные не сохраняются между запусками")
+		repos = newMemoryRepositories()
+		if err := bootstrapAdmin(context.Background(), repos.Users, cfg.Auth.AdminPasswordFile); err != nil {
+			return err
+		}
+	default:
+		// Настройка соединения с базой данных
+		db := waitForDB(cfg.DB.DSN())
+
+		// Приводим схему к версии, которую ожидает код
+		if cfg.DB.AutoMigrate {
+			m, err := newMigrator(db)
+			if err != nil {
+				return err
+			}
+			if _, err := m.Up(context.Background()); err != nil {
+				return err
+			}
+		}
+
+		// Выбираем единственный источник агрегатов отделов
+		strategy, err := setupAggregates(context.Background(), db, aggregateStrategy(cfg.DB.Aggregates))
+		if err != nil {
+			return err
+		}
 
-	// Call Middleware проверки подключения к БД
-	r.Use(dbAliveMiddleware(db))
+		// Поисковые ключи имён, изменённых в обход сервера
+		if n, err := repairSearchKeys(context.Background(), db); err != nil {
+			return fmt.Errorf("ошибка обновления поисковых ключей: %v", err)
+		} else if n > 0 {
+			log.Printf("Обновлены поисковые ключи %d сотрудников", n)
+		}
+
+		// Call Middleware проверки подключения к БД
+		r.Use(dbAliveMiddleware(db))
+		repos = newMySQLRepositories(db, strategy)
+	}
+
+	// Запланированные изменения зарплат применяются в фоне
+	go runSalaryScheduler(context.Background(), repos.Salaries)
+
+	// Отчёты, поставленные в очередь через POST /api/reports
+	reportStore, err := newReportStore(cfg)
+	if err != nil {
+		return err
+	}
+	jobs := newReportJobs(repos.Employees, reportStore, cfg.Reports)
+	jobs.start(context.Background(), cfg.Reports.Workers)
 
 	// Call routes setup function
-	setupAPIRoutes(r, db)
+	setupAPIRoutes(r, repos, newTokenIssuer(cfg.Auth), jobs)
 
-	r.RunTLS(listenIP, certPath, keyPath) // Запуск сервера с поддержкой HTTPS
+	// Запуск сервера с поддержкой HTTPS
+	return r.RunTLS(cfg.Server.Listen, cfg.Server.CertFile, cfg.Server.KeyFile)
 }
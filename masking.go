@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Диапазоны, которыми показываются скрытые зарплаты: до below — шагом width
+var salaryBands = []struct{ below, width float64 }{
+	{200_000, 25_000},
+	{1_000_000, 100_000},
+	{10_000_000, 1_000_000},
+}
+
+// salaryBucket диапазон, в который попадает сумма, например "50k–75k"
+func salaryBucket(v float64) string {
+	for _, b := range salaryBands {
+		if v < b.below {
+			if v < 0 {
+				v = 0
+			}
+			low := float64(int64(v/b.width)) * b.width
+			return formatAmount(low) + "–" + formatAmount(low+b.width)
+		}
+	}
+	last := salaryBands[len(salaryBands)-1]
+	return formatAmount(last.below) + "+"
+}
+
+// formatAmount краткая запись суммы: 0, 75k, 1.5M
+func formatAmount(v float64) string {
+	switch {
+	case v >= 1_000_000:
+		return strconv.FormatFloat(v/1_000_000, 'f', -1, 64) + "M"
+	case v >= 1_000:
+		return strconv.FormatFloat(v/1_000, 'f', -1, 64) + "k"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// salaryVisibility какие зарплаты пользователь запроса видит точно.
+// Роль hr и выше — все; руководитель — сотрудников и фонд своих отделов;
+// остальным зарплаты показываются диапазонами (salaryBucket).
+type salaryVisibility struct {
+	all   bool
+	heads map[int]bool // отделы, которыми руководит пользователь
+}
+
+// newSalaryVisibility права пользователя запроса на просмотр зарплат
+func newSalaryVisibility(c *gin.Context, departments DepartmentRepository) (salaryVisibility, error) {
+	claims, _ := authUser(c)
+	if roleLevels[claims.Role] >= roleLevels[roleHR] {
+		return salaryVisibility{all: true}, nil
+	}
+	v := salaryVisibility{heads: map[int]bool{}}
+	if claims.EmpID == nil {
+		return v, nil
+	}
+	deps, err := departments.List(c.Request.Context())
+	if err != nil {
+		return v, fmt.Errorf("ошибка при получении отделов: %v", err)
+	}
+	for _, d := range deps {
+		if managesDepartment(c, d) {
+			v.heads[d.ID] = true
+		}
+	}
+	return v, nil
+}
+
+// department видит ли пользователь точные зарплаты отдела deptID
+func (v salaryVisibility) department(deptID int) bool {
+	return v.all || v.heads[deptID]
+}
+
+// filter можно ли пользователю фильтровать и сортировать список по зарплате:
+// иначе по ответам можно восстановить скрытые значения
+func (v salaryVisibility) filter(q EmployeeQuery) bool {
+	if v.all || q.SalaryMin == nil && q.SalaryMax == nil && q.Sort != sortBySalary {
+		return true
+	}
+	return q.DeptID != nil && v.heads[*q.DeptID]
+}
+
+// formatter функция вывода зарплат отдела deptID в документах
+func (v salaryVisibility) formatter(deptID int) func(float64) string {
+	if v.department(deptID) {
+		return func(s float64) string { return fmt.Sprintf("%.2f", s) }
+	}
+	return salaryBucket
+}
+
+// EmployeeView сотрудник в ответе API: без прав salary == null, вместо неё salary_range
+type EmployeeView struct {
+	Employee
+	Salary      *float64 `json:"salary"`
+	SalaryRange string   `json:"salary_range,omitempty"`
+}
+
+func (v salaryVisibility) employee(e Employee) EmployeeView {
+	view := EmployeeView{Employee: e}
+	if v.department(e.DeptID) {
+		view.Salary = &e.Salary
+	} else {
+		view.SalaryRange = salaryBucket(e.Salary)
+	}
+	return view
+}
+
+func (v salaryVisibility) employees(emps []Employee) []EmployeeView {
+	views := make([]EmployeeView, 0, len(emps))
+	for _, e := range emps {
+		views = append(views, v.employee(e))
+	}
+	return views
+}
+
+// DepartmentView отдел в ответе API: без прав total_salary == null, вместо неё total_salary_range
+type DepartmentView struct {
+	Department
+	TotalSalary      *float64 `json:"total_salary"`
+	TotalSalaryRange string   `json:"total_salary_range,omitempty"`
+}
+
+func (v salaryVisibility) departments(deps []Department) []DepartmentView {
+	views := make([]DepartmentView, 0, len(deps))
+	for _, d := range deps {
+		view := DepartmentView{Department: d}
+		if v.department(d.ID) {
+			view.TotalSalary = &d.TotalSalary
+		} else {
+			view.TotalSalaryRange = salaryBucket(d.TotalSalary)
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+// EmployeeSearchView результат поиска с сотрудником в виде EmployeeView
+type EmployeeSearchView struct {
+	EmployeeSearchResult
+	Employee EmployeeView `json:"employee"`
+}
+
+func (v salaryVisibility) searchResults(results []EmployeeSearchResult) []EmployeeSearchView {
+	views := make([]EmployeeSearchView, 0, len(results))
+	for _, r := range results {
+		views = append(views, EmployeeSearchView{EmployeeSearchResult: r, Employee: v.employee(r.Employee)})
+	}
+	return views
+}
@@ -0,0 +1,86 @@
+package main
+
+import "testing"
+
+func TestSalaryBucket(t *testing.T) {
+	tests := []struct {
+		salary float64
+		want   string
+	}{
+		{-5, "0–25k"},
+		{0, "0–25k"},
+		{24_999.99, "0–25k"},
+		{25_000, "25k–50k"},
+		{60_000, "50k–75k"},
+		{199_999, "175k–200k"},
+		{200_000, "200k–300k"},
+		{999_999, "900k–1M"},
+		{1_000_000, "1M–2M"},
+		{1_500_000, "1M–2M"},
+		{9_999_999, "9M–10M"},
+		{10_000_000, "10M+"},
+		{50_000_000, "10M+"},
+	}
+	for _, tt := range tests {
+		if got := salaryBucket(tt.salary); got != tt.want {
+			t.Errorf("salaryBucket(%v) = %q, ожидалось %q", tt.salary, got, tt.want)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{75_000, "75k"},
+		{1_500_000, "1.5M"},
+	}
+	for _, tt := range tests {
+		if got := formatAmount(tt.v); got != tt.want {
+			t.Errorf("formatAmount(%v) = %q, ожидалось %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestSalaryVisibility(t *testing.T) {
+	head := salaryVisibility{heads: map[int]bool{2: true}}
+	salaryFilter := EmployeeQuery{SalaryMin: ptr(1000.0)}
+
+	tests := []struct {
+		name string
+		v    salaryVisibility
+		q    EmployeeQuery
+		want bool
+	}{
+		{"hr фильтрует по зарплате", salaryVisibility{all: true}, salaryFilter, true},
+		{"без фильтра по зарплате", head, EmployeeQuery{Name: "ив"}, true},
+		{"сортировка по зарплате без отдела", head, EmployeeQuery{Sort: sortBySalary}, false},
+		{"фильтр в чужом отделе", head, EmployeeQuery{SalaryMin: ptr(1000.0), DeptID: ptr(3)}, false},
+		{"фильтр в своём отделе", head, EmployeeQuery{SalaryMin: ptr(1000.0), DeptID: ptr(2)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.v.filter(tt.q); got != tt.want {
+				t.Fatalf("filter = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+
+	views := head.departments([]Department{{ID: 2, TotalSalary: 100}, {ID: 3, TotalSalary: 60_000}})
+	if views[0].TotalSalary == nil || views[1].TotalSalary != nil || views[1].TotalSalaryRange != "50k–75k" {
+		t.Fatalf("отделы %+v", views)
+	}
+	if got := head.formatter(3)(60_000); got != "50k–75k" {
+		t.Fatalf("formatter чужого отдела: %q", got)
+	}
+	if got := head.formatter(2)(60_000); got != "60000.00" {
+		t.Fatalf("formatter своего отдела: %q", got)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Миграции схемы: migrations/NNNN_name.up.sql и NNNN_name.down.sql.
+// Файлы встраиваются в бинарник, применённые версии хранятся в schema_migrations.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Имя блокировки MySQL, чтобы несколько экземпляров не мигрировали одновременно
+const migrationLock = "schema_migrations"
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// migration одна версия схемы
+type migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// migrationStatus состояние версии для команды migrate status
+type migrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// loadMigrations читает встроенные миграции и сортирует их по версии
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*migration{}
+	for _, entry := range entries {
+		m := migrationFileRe.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("некорректное имя файла миграции: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("миграция %d: разные имена %q и %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("миграция %d_%s: нужны оба файла up и down", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// migrator применяет миграции к MySQL.
+// Все операции выполняются на одном соединении под GET_LOCK.
+type migrator struct {
+	db         *sql.DB
+	migrations []migration
+}
+
+func newMigrator(db *sql.DB) (*migrator, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	return &migrator{db: db, migrations: migrations}, nil
+}
+
+// withLock открывает соединение, берёт блокировку и создаёт schema_migrations
+func (m *migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 60)", migrationLock).Scan(&locked); err != nil {
+		return fmt.Errorf("ошибка получения блокировки миграций: %v", err)
+	}
+	if locked.Int64 != 1 {
+		return fmt.Errorf("не удалось получить блокировку миграций: занята другим процессом")
+	}
+	defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", migrationLock)
+
+	_, err = conn.ExecContext(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version    INT PRIMARY KEY,
+            name       VARCHAR(255) NOT NULL,
+            applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+        ) ENGINE=InnoDB`)
+	if err != nil {
+		return fmt.Errorf("ошибка создания schema_migrations: %v", err)
+	}
+	return fn(conn)
+}
+
+// applied возвращает время применения каждой версии
+func (m *migrator) applied(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := map[int]time.Time{}
+	for rows.Next() {
+		var (
+			version int
+			at      time.Time
+		)
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		done[version] = at
+	}
+	return done, rows.Err()
+}
+
+// Up применяет все ещё не применённые миграции и возвращает их версии
+func (m *migrator) Up(ctx context.Context) ([]int, error) {
+	var applied []int
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			// DDL в MySQL не транзакционен, поэтому версия записывается после успешного выполнения
+			if _, err := conn.ExecContext(ctx, mig.Up); err != nil {
+				return fmt.Errorf("миграция %d_%s: %v", mig.Version, mig.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx,
+				"INSERT INTO schema_migrations (version, name) VALUES (?, ?)", mig.Version, mig.Name,
+			); err != nil {
+				return fmt.Errorf("миграция %d_%s: ошибка записи версии: %v", mig.Version, mig.Name, err)
+			}
+			log.Printf("Применена миграция %d_%s", mig.Version, mig.Name)
+			applied = append(applied, mig.Version)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down откатывает steps последних применённых миграций
+func (m *migrator) Down(ctx context.Context, steps int) ([]int, error) {
+	var reverted []int
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := done[mig.Version]; !ok {
+				continue
+			}
+			if _, err := conn.ExecContext(ctx, mig.Down); err != nil {
+				return fmt.Errorf("откат миграции %d_%s: %v", mig.Version, mig.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", mig.Version); err != nil {
+				return fmt.Errorf("откат миграции %d_%s: ошибка удаления версии: %v", mig.Version, mig.Name, err)
+			}
+			log.Printf("Откачена миграция %d_%s", mig.Version, mig.Name)
+			reverted = append(reverted, mig.Version)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status возвращает список миграций с отметкой о применении
+func (m *migrator) Status(ctx context.Context) ([]migrationStatus, error) {
+	var status []migrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			st := migrationStatus{Version: mig.Version, Name: mig.Name}
+			if at, ok := done[mig.Version]; ok {
+				st.AppliedAt = &at
+			}
+			status = append(status, st)
+		}
+		return nil
+	})
+	return status, err
+}
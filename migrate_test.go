@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMigrationDB имитирует MySQL для migrator: GET_LOCK, таблицу schema_migrations
+// и журнал остальных запросов. Запрос, содержащий fail, завершается ошибкой.
+type fakeMigrationDB struct {
+	mu      sync.Mutex
+	applied map[int]time.Time
+	execs   []string
+	fail    string
+}
+
+func newFakeMigrationDB() *fakeMigrationDB {
+	return &fakeMigrationDB{applied: map[int]time.Time{}}
+}
+
+func (f *fakeMigrationDB) Connect(context.Context) (driver.Conn, error) {
+	return fakeMigrationConn{f}, nil
+}
+
+func (f *fakeMigrationDB) Driver() driver.Driver { return nil }
+
+type fakeMigrationConn struct {
+	db *fakeMigrationDB
+}
+
+func (c fakeMigrationConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare не поддерживается")
+}
+
+func (c fakeMigrationConn) Close() error { return nil }
+
+func (c fakeMigrationConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("транзакции не поддерживаются")
+}
+
+func (c fakeMigrationConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	f := c.db
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case strings.HasPrefix(query, "SELECT RELEASE_LOCK"), strings.Contains(query, "CREATE TABLE IF NOT EXISTS schema_migrations"):
+	case strings.HasPrefix(query, "INSERT INTO schema_migrations"):
+		f.applied[int(args[0].Value.(int64))] = time.Now()
+	case strings.HasPrefix(query, "DELETE FROM schema_migrations"):
+		delete(f.applied, int(args[0].Value.(int64)))
+	default:
+		if f.fail != "" && strings.Contains(query, f.fail) {
+			return nil, errors.New("синтаксическая ошибка")
+		}
+		f.execs = append(f.execs, query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c fakeMigrationConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	f := c.db
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case strings.HasPrefix(query, "SELECT GET_LOCK"):
+		return &fakeRows{columns: []string{"locked"}, values: [][]driver.Value{{int64(1)}}}, nil
+	case strings.HasPrefix(query, "SELECT version, applied_at FROM schema_migrations"):
+		rows := &fakeRows{columns: []string{"version", "applied_at"}}
+		for v, at := range f.applied {
+			rows.values = append(rows.values, []driver.Value{int64(v), at})
+		}
+		return rows, nil
+	}
+	return nil, errors.New("неожиданный запрос: " + query)
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Fatalf("версии миграций идут с пропуском: %d на месте %d", m.Version, i+1)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Fatalf("миграция %d_%s: пустой файл", m.Version, m.Name)
+		}
+	}
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	f := newFakeMigrationDB()
+	db := sql.OpenDB(f)
+	defer db.Close()
+	m, err := newMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	last := len(m.migrations)
+
+	applied, err := m.Up(ctx)
+	if err != nil || len(applied) != last {
+		t.Fatalf("применено %v: %v", applied, err)
+	}
+	for i, mig := range m.migrations {
+		if f.execs[i] != mig.Up {
+			t.Fatalf("миграция %d выполнена не по порядку", mig.Version)
+		}
+	}
+	if applied, _ := m.Up(ctx); len(applied) != 0 {
+		t.Fatalf("повторный up применил %v", applied)
+	}
+
+	f.execs = nil
+	reverted, err := m.Down(ctx, 2)
+	if err != nil || len(reverted) != 2 || reverted[0] != last || reverted[1] != last-1 {
+		t.Fatalf("откачено %v: %v", reverted, err)
+	}
+	if f.execs[0] != m.migrations[last-1].Down || f.execs[1] != m.migrations[last-2].Down {
+		t.Fatal("down выполнен не в обратном порядке")
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range status {
+		if want := st.Version <= last-2; (st.AppliedAt != nil) != want {
+			t.Fatalf("статус версии %d: применена %v, ожидалось %v", st.Version, st.AppliedAt != nil, want)
+		}
+	}
+
+	if applied, _ := m.Up(ctx); len(applied) != 2 || applied[0] != last-1 {
+		t.Fatalf("up после отката применил %v", applied)
+	}
+}
+
+func TestMigratorUpStopsOnError(t *testing.T) {
+	f := newFakeMigrationDB()
+	db := sql.OpenDB(f)
+	defer db.Close()
+	m, err := newMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.fail = m.migrations[2].Up
+
+	applied, err := m.Up(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "миграция 3_") {
+		t.Fatalf("ошибка %v", err)
+	}
+	// Версия записывается только после успешного выполнения
+	if len(applied) != 2 || len(f.applied) != 2 {
+		t.Fatalf("применено %v, в schema_migrations %d версий", applied, len(f.applied))
+	}
+}
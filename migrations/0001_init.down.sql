@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS `after_update_служ`;
+DROP TRIGGER IF EXISTS `after_delete_служ`;
+DROP TRIGGER IF EXISTS `after_insert_служ`;
+DROP TABLE IF EXISTS `СЛУЖАЩИЕ`;
+DROP TABLE IF EXISTS `ОТДЕЛЫ`;
@@ -1,16 +1,14 @@
--- Схема базы данных для приложения "Отделы - Служащие" (MySQL)
--- Без директив DELIMITER, готово к выполнению через MySQL-клиент или Exec из Go (MultiStatements=true)
+-- Исходная схема "Отделы - Служащие" (бывший sql/create.sql).
+-- IF NOT EXISTS позволяет принять существующую установку за версию 1.
 
--- Создание таблицы ОТДЕЛЫ
-CREATE TABLE `ОТДЕЛЫ` (
+CREATE TABLE IF NOT EXISTS `ОТДЕЛЫ` (
   `ОТД_НОМЕР` INT AUTO_INCREMENT PRIMARY KEY,
   `ОТД_РУК` VARCHAR(100) NOT NULL,
   `ОТД_СОТР_ЗАРП` DECIMAL(12,2) NOT NULL DEFAULT 0,
   `ОТД_РАЗМ` INT NOT NULL CHECK (`ОТД_РАЗМ` > 0)
 ) ENGINE=InnoDB;
 
--- Создание таблицы СЛУЖАЩИЕ
-CREATE TABLE `СЛУЖАЩИЕ` (
+CREATE TABLE IF NOT EXISTS `СЛУЖАЩИЕ` (
   `СЛУ_НОМЕР` INT AUTO_INCREMENT PRIMARY KEY,
   `СЛУ_ИМЯ` VARCHAR(100) NOT NULL,
   `СЛУ_СТАТ` VARCHAR(50) NOT NULL,
@@ -21,6 +19,7 @@ CREATE TABLE `СЛУЖАЩИЕ` (
 ) ENGINE=InnoDB;
 
 -- Триггер: после вставки сотрудника
+DROP TRIGGER IF EXISTS `after_insert_служ`;
 CREATE TRIGGER `after_insert_служ`
 AFTER INSERT ON `СЛУЖАЩИЕ`
 FOR EACH ROW
@@ -31,6 +30,7 @@ BEGIN
 END;
 
 -- Триггер: после удаления сотрудника
+DROP TRIGGER IF EXISTS `after_delete_служ`;
 CREATE TRIGGER `after_delete_служ`
 AFTER DELETE ON `СЛУЖАЩИЕ`
 FOR EACH ROW
@@ -41,6 +41,7 @@ BEGIN
 END;
 
 -- Триггер: после обновления сотрудника
+DROP TRIGGER IF EXISTS `after_update_служ`;
 CREATE TRIGGER `after_update_служ`
 AFTER UPDATE ON `СЛУЖАЩИЕ`
 FOR EACH ROW
@@ -61,25 +62,3 @@ BEGIN
     WHERE `ОТД_НОМЕР` = NEW.`СЛУ_ОТД_НОМЕР`;
   END IF;
 END;
-
--- Примеры транзакций (будут использоваться также в Go):
--- добавление нового сотрудника
-START TRANSACTION;
-  INSERT INTO `СЛУЖАЩИЕ` (`СЛУ_ИМЯ`,`СЛУ_СТАТ`,`СЛУ_ЗАРП`,`СЛУ_ОТД_НОМЕР`)
-  VALUES ('Иван Иванов','менеджер',50000.00,1);
-COMMIT;
-
--- удаление сотрудника
-START TRANSACTION;
-  DELETE FROM `СЛУЖАЩИЕ` WHERE `СЛУ_НОМЕР`=42;
-COMMIT;
-
--- изменение зарплаты сотрудника
-START TRANSACTION;
-  UPDATE `СЛУЖАЩИЕ` SET `СЛУ_ЗАРП`=55000.00 WHERE `СЛУ_НОМЕР`=42;
-COMMIT;
-
--- смена руководителя отдела
-START TRANSACTION;
-  UPDATE `ОТДЕЛЫ` SET `ОТД_РУК`='Петр Петров' WHERE `ОТД_НОМЕР`=1;
-COMMIT;
@@ -0,0 +1 @@
+ALTER TABLE `СЛУЖАЩИЕ` DROP COLUMN `IMAGE_URL`;
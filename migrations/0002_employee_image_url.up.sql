@@ -0,0 +1,11 @@
+-- Имя файла фотографии сотрудника (используется API с самого начала,
+-- но отсутствовало в sql/create.sql). Колонку добавляем, только если её ещё нет.
+SET @stmt = IF(
+  (SELECT COUNT(*) FROM information_schema.COLUMNS
+    WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'СЛУЖАЩИЕ' AND COLUMN_NAME = 'IMAGE_URL') = 0,
+  'ALTER TABLE `СЛУЖАЩИЕ` ADD COLUMN `IMAGE_URL` VARCHAR(255) NULL',
+  'DO 0'
+);
+PREPARE s FROM @stmt;
+EXECUTE s;
+DEALLOCATE PREPARE s;
@@ -0,0 +1,3 @@
+ALTER TABLE `ОТДЕЛЫ` MODIFY `ОТД_РУК` VARCHAR(100) NULL;
+UPDATE `ОТДЕЛЫ` SET `ОТД_РУК` = '' WHERE `ОТД_РУК` IS NULL;
+ALTER TABLE `ОТДЕЛЫ` MODIFY `ОТД_РУК` VARCHAR(100) NOT NULL;
@@ -0,0 +1,5 @@
+-- ОТД_РУК хранит ID сотрудника-руководителя (NULL — не назначен),
+-- а не имя, как в исходной схеме. Нечисловые значения сбрасываются.
+ALTER TABLE `ОТДЕЛЫ` MODIFY `ОТД_РУК` VARCHAR(100) NULL;
+UPDATE `ОТДЕЛЫ` SET `ОТД_РУК` = NULL WHERE `ОТД_РУК` NOT REGEXP '^[0-9]+$';
+ALTER TABLE `ОТДЕЛЫ` MODIFY `ОТД_РУК` INT NULL;
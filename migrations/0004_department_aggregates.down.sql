@@ -0,0 +1,47 @@
+DROP VIEW IF EXISTS `ОТДЕЛЫ_ИТОГИ`;
+
+-- Возвращаем триггеры версии 1 (только ОТД_СОТР_ЗАРП)
+-- Триггер: после вставки сотрудника
+DROP TRIGGER IF EXISTS `after_insert_служ`;
+CREATE TRIGGER `after_insert_служ`
+AFTER INSERT ON `СЛУЖАЩИЕ`
+FOR EACH ROW
+BEGIN
+  UPDATE `ОТДЕЛЫ`
+  SET `ОТД_СОТР_ЗАРП` = `ОТД_СОТР_ЗАРП` + NEW.`СЛУ_ЗАРП`
+  WHERE `ОТД_НОМЕР` = NEW.`СЛУ_ОТД_НОМЕР`;
+END;
+
+-- Триггер: после удаления сотрудника
+DROP TRIGGER IF EXISTS `after_delete_служ`;
+CREATE TRIGGER `after_delete_служ`
+AFTER DELETE ON `СЛУЖАЩИЕ`
+FOR EACH ROW
+BEGIN
+  UPDATE `ОТДЕЛЫ`
+  SET `ОТД_СОТР_ЗАРП` = `ОТД_СОТР_ЗАРП` - OLD.`СЛУ_ЗАРП`
+  WHERE `ОТД_НОМЕР` = OLD.`СЛУ_ОТД_НОМЕР`;
+END;
+
+-- Триггер: после обновления сотрудника
+DROP TRIGGER IF EXISTS `after_update_служ`;
+CREATE TRIGGER `after_update_служ`
+AFTER UPDATE ON `СЛУЖАЩИЕ`
+FOR EACH ROW
+BEGIN
+  -- корректируем сумму при смене зарплаты
+  IF NEW.`СЛУ_ЗАРП` <> OLD.`СЛУ_ЗАРП` THEN
+    UPDATE `ОТДЕЛЫ`
+    SET `ОТД_СОТР_ЗАРП` = `ОТД_СОТР_ЗАРП` + (NEW.`СЛУ_ЗАРП` - OLD.`СЛУ_ЗАРП`)
+    WHERE `ОТД_НОМЕР` = NEW.`СЛУ_ОТД_НОМЕР`;
+  END IF;
+  -- корректируем при смене отдела
+  IF NEW.`СЛУ_ОТД_НОМЕР` <> OLD.`СЛУ_ОТД_НОМЕР` THEN
+    UPDATE `ОТДЕЛЫ`
+    SET `ОТД_СОТР_ЗАРП` = `ОТД_СОТР_ЗАРП` - OLD.`СЛУ_ЗАРП`
+    WHERE `ОТД_НОМЕР` = OLD.`СЛУ_ОТД_НОМЕР`;
+    UPDATE `ОТДЕЛЫ`
+    SET `ОТД_СОТР_ЗАРП` = `ОТД_СОТР_ЗАРП` + NEW.`СЛУ_ЗАРП`
+    WHERE `ОТД_НОМЕР` = NEW.`СЛУ_ОТД_НОМЕР`;
+  END IF;
+END;
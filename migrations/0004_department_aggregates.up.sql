@@ -0,0 +1,57 @@
+-- Триггеры поддерживают оба агрегата отдела: ОТД_СОТР_ЗАРП и ОТД_РАЗМ.
+-- Сервер обнаруживает их при старте и перестаёт обновлять агрегаты сам (см. aggregates.go).
+
+DROP TRIGGER IF EXISTS `after_insert_служ`;
+CREATE TRIGGER `after_insert_служ`
+AFTER INSERT ON `СЛУЖАЩИЕ`
+FOR EACH ROW
+BEGIN
+  UPDATE `ОТДЕЛЫ`
+  SET `ОТД_СОТР_ЗАРП` = `ОТД_СОТР_ЗАРП` + NEW.`СЛУ_ЗАРП`,
+      `ОТД_РАЗМ` = `ОТД_РАЗМ` + 1
+  WHERE `ОТД_НОМЕР` = NEW.`СЛУ_ОТД_НОМЕР`;
+END;
+
+DROP TRIGGER IF EXISTS `after_delete_служ`;
+CREATE TRIGGER `after_delete_служ`
+AFTER DELETE ON `СЛУЖАЩИЕ`
+FOR EACH ROW
+BEGIN
+  UPDATE `ОТДЕЛЫ`
+  SET `ОТД_СОТР_ЗАРП` = `ОТД_СОТР_ЗАРП` - OLD.`СЛУ_ЗАРП`,
+      `ОТД_РАЗМ` = `ОТД_РАЗМ` - 1
+  WHERE `ОТД_НОМЕР` = OLD.`СЛУ_ОТД_НОМЕР`;
+END;
+
+DROP TRIGGER IF EXISTS `after_update_служ`;
+CREATE TRIGGER `after_update_служ`
+AFTER UPDATE ON `СЛУЖАЩИЕ`
+FOR EACH ROW
+BEGIN
+  IF NEW.`СЛУ_ОТД_НОМЕР` <> OLD.`СЛУ_ОТД_НОМЕР` THEN
+    -- перевод: минус из старого отдела, плюс в новый
+    UPDATE `ОТДЕЛЫ`
+    SET `ОТД_СОТР_ЗАРП` = `ОТД_СОТР_ЗАРП` - OLD.`СЛУ_ЗАРП`,
+        `ОТД_РАЗМ` = `ОТД_РАЗМ` - 1
+    WHERE `ОТД_НОМЕР` = OLD.`СЛУ_ОТД_НОМЕР`;
+    UPDATE `ОТДЕЛЫ`
+    SET `ОТД_СОТР_ЗАРП` = `ОТД_СОТР_ЗАРП` + NEW.`СЛУ_ЗАРП`,
+        `ОТД_РАЗМ` = `ОТД_РАЗМ` + 1
+    WHERE `ОТД_НОМЕР` = NEW.`СЛУ_ОТД_НОМЕР`;
+  ELSEIF NEW.`СЛУ_ЗАРП` <> OLD.`СЛУ_ЗАРП` THEN
+    -- тот же отдел: меняется только сумма зарплат
+    UPDATE `ОТДЕЛЫ`
+    SET `ОТД_СОТР_ЗАРП` = `ОТД_СОТР_ЗАРП` + (NEW.`СЛУ_ЗАРП` - OLD.`СЛУ_ЗАРП`)
+    WHERE `ОТД_НОМЕР` = NEW.`СЛУ_ОТД_НОМЕР`;
+  END IF;
+END;
+
+-- Агрегаты, вычисляемые при чтении (стратегия view)
+CREATE OR REPLACE VIEW `ОТДЕЛЫ_ИТОГИ` AS
+SELECT
+  d.`ОТД_НОМЕР`,
+  COALESCE(SUM(e.`СЛУ_ЗАРП`), 0) AS `ОТД_СОТР_ЗАРП`,
+  COUNT(e.`СЛУ_НОМЕР`) AS `ОТД_РАЗМ`
+FROM `ОТДЕЛЫ` d
+LEFT JOIN `СЛУЖАЩИЕ` e ON e.`СЛУ_ОТД_НОМЕР` = d.`ОТД_НОМЕР`
+GROUP BY d.`ОТД_НОМЕР`;
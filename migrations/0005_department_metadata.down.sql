@@ -0,0 +1,4 @@
+ALTER TABLE `ОТДЕЛЫ`
+  DROP COLUMN `ОТД_ЦФО`,
+  DROP COLUMN `ОТД_ОПИС`,
+  DROP COLUMN `ОТД_НАЗВ`;
@@ -0,0 +1,5 @@
+-- Редактируемые сведения об отделе: название, описание, центр финансовой ответственности
+ALTER TABLE `ОТДЕЛЫ`
+  ADD COLUMN `ОТД_НАЗВ` VARCHAR(100) NOT NULL DEFAULT '',
+  ADD COLUMN `ОТД_ОПИС` TEXT NULL,
+  ADD COLUMN `ОТД_ЦФО` VARCHAR(32) NULL;
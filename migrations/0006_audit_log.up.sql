@@ -0,0 +1,14 @@
+-- Журнал изменений: кто, когда, что и какие значения были до и после.
+-- changes: {"поле": {"old": ..., "new": ...}}
+CREATE TABLE IF NOT EXISTS `audit_log` (
+  `id`         BIGINT AUTO_INCREMENT PRIMARY KEY,
+  `created_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+  `actor`      VARCHAR(255) NOT NULL,
+  `entity`     VARCHAR(32) NOT NULL,
+  `entity_id`  INT NOT NULL,
+  `operation`  VARCHAR(16) NOT NULL,
+  `changes`    JSON NOT NULL,
+  KEY `idx_audit_entity` (`entity`, `entity_id`, `created_at`),
+  KEY `idx_audit_actor` (`actor`, `created_at`),
+  KEY `idx_audit_created` (`created_at`)
+) ENGINE=InnoDB;
@@ -0,0 +1,21 @@
+-- История зарплат: каждое изменение СЛУ_ЗАРП с датой вступления в силу, причиной и автором.
+-- applied_at IS NULL — изменение запланировано и ещё не применено.
+CREATE TABLE IF NOT EXISTS `salary_history` (
+  `id`             BIGINT AUTO_INCREMENT PRIMARY KEY,
+  `emp_id`         INT NOT NULL,
+  `effective_date` DATE NOT NULL,
+  `old_salary`     DECIMAL(12,2) NULL,
+  `new_salary`     DECIMAL(12,2) NOT NULL CHECK (`new_salary` >= 0),
+  `reason`         VARCHAR(255) NULL,
+  `author`         VARCHAR(255) NOT NULL,
+  `created_at`     DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+  `applied_at`     DATETIME(6) NULL,
+  KEY `idx_salary_emp` (`emp_id`, `effective_date`),
+  KEY `idx_salary_due` (`applied_at`, `effective_date`),
+  CONSTRAINT `fk_salary_emp` FOREIGN KEY (`emp_id`) REFERENCES `СЛУЖАЩИЕ`(`СЛУ_НОМЕР`) ON DELETE CASCADE
+) ENGINE=InnoDB;
+
+-- Текущие зарплаты становятся началом истории
+INSERT INTO `salary_history` (`emp_id`, `effective_date`, `new_salary`, `author`, `applied_at`)
+SELECT `СЛУ_НОМЕР`, CURRENT_DATE, `СЛУ_ЗАРП`, 'migration', CURRENT_TIMESTAMP(6)
+FROM `СЛУЖАЩИЕ`;
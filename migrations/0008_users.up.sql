@@ -0,0 +1,22 @@
+-- Пользователи API: пароль хранится как bcrypt-хэш.
+CREATE TABLE IF NOT EXISTS `users` (
+  `id`            INT AUTO_INCREMENT PRIMARY KEY,
+  `login`         VARCHAR(64) NOT NULL,
+  `password_hash` VARCHAR(100) NOT NULL,
+  `role`          VARCHAR(16) NOT NULL,
+  `created_at`    DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+  UNIQUE KEY `uq_users_login` (`login`),
+  CONSTRAINT `chk_users_role` CHECK (`role` IN ('viewer', 'hr', 'admin'))
+) ENGINE=InnoDB;
+
+-- Refresh-токены: хранится SHA-256 токена, сам токен есть только у клиента.
+-- Использованный токен отзывается (revoked_at), повторное предъявление отзывает все токены пользователя.
+CREATE TABLE IF NOT EXISTS `refresh_tokens` (
+  `token_hash` CHAR(64) PRIMARY KEY,
+  `user_id`    INT NOT NULL,
+  `expires_at` DATETIME(6) NOT NULL,
+  `revoked_at` DATETIME(6) NULL,
+  `created_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+  KEY `idx_refresh_user` (`user_id`),
+  CONSTRAINT `fk_refresh_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB;
@@ -0,0 +1,2 @@
+ALTER TABLE `users` DROP FOREIGN KEY `fk_users_emp`;
+ALTER TABLE `users` DROP COLUMN `emp_id`;
@@ -0,0 +1,5 @@
+-- Связь пользователя с сотрудником: руководитель отдела (ОТДЕЛЫ.ОТД_РУК)
+-- получает права hr на свой отдел.
+ALTER TABLE `users`
+  ADD COLUMN `emp_id` INT NULL,
+  ADD CONSTRAINT `fk_users_emp` FOREIGN KEY (`emp_id`) REFERENCES `СЛУЖАЩИЕ`(`СЛУ_НОМЕР`) ON DELETE SET NULL;
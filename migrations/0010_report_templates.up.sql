@@ -0,0 +1,10 @@
+-- Шаблоны DOCX-отчётов: каждая загрузка под тем же именем — новая версия.
+CREATE TABLE IF NOT EXISTS `report_templates` (
+  `id`         INT AUTO_INCREMENT PRIMARY KEY,
+  `name`       VARCHAR(64) NOT NULL,
+  `version`    INT NOT NULL,
+  `content`    MEDIUMBLOB NOT NULL,
+  `created_by` VARCHAR(100) NOT NULL,
+  `created_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+  UNIQUE KEY `uq_report_templates_version` (`name`, `version`)
+) ENGINE=InnoDB;
@@ -0,0 +1,15 @@
+-- Вернуть CHECK (`ОТД_РАЗМ` > 0) можно, только пока пустых отделов нет.
+-- Иначе откат отказывает с понятной ошибкой: пустые отделы нужно удалить
+-- или перевести в них сотрудников вручную.
+DROP PROCEDURE IF EXISTS `migrate_0011_down`;
+CREATE PROCEDURE `migrate_0011_down`()
+BEGIN
+  IF EXISTS (SELECT 1 FROM `ОТДЕЛЫ` WHERE `ОТД_РАЗМ` = 0) THEN
+    SIGNAL SQLSTATE '45000'
+      SET MESSAGE_TEXT = 'Откат 0011 невозможен: есть отделы без сотрудников (ОТД_РАЗМ = 0)';
+  END IF;
+  ALTER TABLE `ОТДЕЛЫ` DROP CHECK `chk_отд_разм`;
+  ALTER TABLE `ОТДЕЛЫ` ADD CONSTRAINT `chk_отд_разм` CHECK (`ОТД_РАЗМ` > 0);
+END;
+CALL `migrate_0011_down`();
+DROP PROCEDURE `migrate_0011_down`;
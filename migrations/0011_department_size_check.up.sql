@@ -0,0 +1,18 @@
+-- Отдел может быть пустым (новый отдел, уволены все сотрудники),
+-- поэтому CHECK (`ОТД_РАЗМ` > 0) заменяется на >= 0.
+-- Имя исходного ограничения генерируется MySQL, ищем его по тексту условия;
+-- если ограничение уже заменено, оно пересоздаётся.
+SET @chk = (
+  SELECT cc.CONSTRAINT_NAME
+  FROM information_schema.CHECK_CONSTRAINTS cc
+  JOIN information_schema.TABLE_CONSTRAINTS tc
+    ON tc.CONSTRAINT_SCHEMA = cc.CONSTRAINT_SCHEMA AND tc.CONSTRAINT_NAME = cc.CONSTRAINT_NAME
+  WHERE tc.TABLE_SCHEMA = DATABASE() AND tc.TABLE_NAME = 'ОТДЕЛЫ'
+    AND tc.CONSTRAINT_TYPE = 'CHECK' AND cc.CHECK_CLAUSE LIKE '%ОТД_РАЗМ%'
+  LIMIT 1
+);
+SET @stmt = IF(@chk IS NULL, 'DO 0', CONCAT('ALTER TABLE `ОТДЕЛЫ` DROP CHECK `', @chk, '`'));
+PREPARE s FROM @stmt;
+EXECUTE s;
+DEALLOCATE PREPARE s;
+ALTER TABLE `ОТДЕЛЫ` ADD CONSTRAINT `chk_отд_разм` CHECK (`ОТД_РАЗМ` >= 0);
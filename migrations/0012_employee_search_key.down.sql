@@ -0,0 +1,2 @@
+ALTER TABLE `СЛУЖАЩИЕ` DROP INDEX `ft_служ_поиск`;
+ALTER TABLE `СЛУЖАЩИЕ` DROP COLUMN `СЛУ_ПОИСК`;
@@ -0,0 +1,13 @@
+-- Нормализованное имя для нечёткого поиска (см. searchKey): нижний регистр,
+-- кириллица в латинице, слова через пробел. Индекс FULLTEXT с парсером ngram
+-- (биграммы при ngram_token_size = 2 по умолчанию) отбирает кандидатов,
+-- которых затем ранжирует сервер. Значения заполняет сервер при старте.
+ALTER TABLE `СЛУЖАЩИЕ`
+  ADD COLUMN `СЛУ_ПОИСК` VARCHAR(400) NOT NULL DEFAULT '';
+
+-- Парсер ngram не индексирует биграммы, содержащие стоп-слово, а в стандартном
+-- списке InnoDB есть "a" и "i". Настройка фиксируется при создании индекса.
+SET SESSION innodb_ft_enable_stopword = OFF;
+ALTER TABLE `СЛУЖАЩИЕ`
+  ADD FULLTEXT INDEX `ft_служ_поиск` (`СЛУ_ПОИСК`) WITH PARSER ngram;
+SET SESSION innodb_ft_enable_stopword = ON;
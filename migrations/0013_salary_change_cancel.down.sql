@@ -0,0 +1,5 @@
+-- Без отметки отменённые изменения снова стали бы запланированными
+DELETE FROM `salary_history` WHERE `cancelled_at` IS NOT NULL;
+ALTER TABLE `salary_history`
+  DROP COLUMN `cancelled_by`,
+  DROP COLUMN `cancelled_at`;
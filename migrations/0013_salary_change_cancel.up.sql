@@ -0,0 +1,5 @@
+-- Отменённое запланированное изменение зарплаты остаётся в истории с отметкой,
+-- кто и когда его отменил; применяются только неотменённые
+ALTER TABLE `salary_history`
+  ADD COLUMN `cancelled_at` DATETIME(6) NULL,
+  ADD COLUMN `cancelled_by` VARCHAR(255) NULL;
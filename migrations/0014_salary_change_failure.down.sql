@@ -0,0 +1,3 @@
+ALTER TABLE `salary_history`
+  DROP COLUMN `fail_reason`,
+  DROP COLUMN `failed_at`;
@@ -0,0 +1,5 @@
+-- Запланированное изменение, которое не удалось применить (например, нарушен CHECK),
+-- отмечается с причиной и больше не применяется, чтобы не задерживать остальные
+ALTER TABLE `salary_history`
+  ADD COLUMN `failed_at` DATETIME(6) NULL,
+  ADD COLUMN `fail_reason` VARCHAR(1024) NULL;
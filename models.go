@@ -1,14 +1,66 @@
 package main
 
+import (
+	"database/sql"
+	"time"
+)
+
 // Department модель отдела
 type Department struct {
 	ID          int     `json:"id"`
-	BossID      *int    `json:"boss_id,omitempty"` // nil если NULL
-	BossName    *string `json:"boss_name"`         // имя руководителя или "Не установлен"
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	CostCenter  *string `json:"cost_center,omitempty"` // центр финансовой ответственности (ЦФО)
+	BossID      *int    `json:"boss_id,omitempty"`     // nil если NULL
+	BossName    *string `json:"boss_name"`             // имя руководителя или "Не установлен"
 	TotalSalary float64 `json:"total_salary"`
 	Size        int     `json:"size"`
 }
 
+// DepartmentPatch частичное изменение отдела; nil-поля не меняются
+type DepartmentPatch struct {
+	SetBoss     bool // менять ли руководителя (BossID == nil — сбросить)
+	BossID      *int
+	Name        *string
+	Description *string // "" — очистить
+	CostCenter  *string // "" — очистить
+}
+
+// applyDepartmentPatch отдел после изменения patch; "" в описании и ЦФО становится nil
+func applyDepartmentPatch(d Department, patch DepartmentPatch) Department {
+	if patch.SetBoss {
+		d.BossID = patch.BossID
+	}
+	if patch.Name != nil {
+		d.Name = *patch.Name
+	}
+	if patch.Description != nil {
+		d.Description = emptyToNil(patch.Description)
+	}
+	if patch.CostCenter != nil {
+		d.CostCenter = emptyToNil(patch.CostCenter)
+	}
+	return d
+}
+
+// DepartmentDeletePolicy что делать с сотрудниками удаляемого отдела
+type DepartmentDeletePolicy string
+
+const (
+	DeleteRefuse   DepartmentDeletePolicy = "refuse"   // отказать, если в отделе есть сотрудники
+	DeleteReassign DepartmentDeletePolicy = "reassign" // перевести сотрудников в другой отдел
+	DeleteCascade  DepartmentDeletePolicy = "cascade"  // удалить сотрудников вместе с отделом
+)
+
+// DepartmentDrift расхождение сохранённых агрегатов отдела с фактическими
+type DepartmentDrift struct {
+	ID           int     `json:"id"`
+	StoredSalary float64 `json:"stored_salary"` // ОТД_СОТР_ЗАРП
+	ActualSalary float64 `json:"actual_salary"` // SUM(СЛУ_ЗАРП)
+	StoredSize   int     `json:"stored_size"`   // ОТД_РАЗМ
+	ActualSize   int     `json:"actual_size"`   // COUNT(*)
+}
+
 // Employee модель сотрудника
 type Employee struct {
 	ID       int     `json:"id"`
@@ -19,6 +71,71 @@ type Employee struct {
 	ImageURL *string `json:"image_url,omitempty"` // URL для изображения сотрудника
 }
 
+// SalaryChange запись истории зарплаты сотрудника (таблица salary_history)
+type SalaryChange struct {
+	ID            int64      `json:"id"`
+	EmployeeID    int        `json:"employee_id"`
+	EffectiveDate string     `json:"effective_date"` // YYYY-MM-DD
+	OldSalary     *float64   `json:"old_salary"`     // nil при приёме на работу и у незапланированных
+	NewSalary     float64    `json:"new_salary"`
+	Reason        string     `json:"reason,omitempty"`
+	Author        string     `json:"author"`
+	CreatedAt     time.Time  `json:"created_at"`
+	AppliedAt     *time.Time `json:"applied_at"`             // nil — запланировано и ещё не применено
+	CancelledAt   *time.Time `json:"cancelled_at,omitempty"` // отменено; такое изменение не применяется
+	CancelledBy   string     `json:"cancelled_by,omitempty"`
+	FailedAt      *time.Time `json:"failed_at,omitempty"` // применить не удалось, причина в FailReason
+	FailReason    string     `json:"fail_reason,omitempty"`
+}
+
+// User пользователь API (таблица users)
+type User struct {
+	ID           int       `json:"id"`
+	Login        string    `json:"login"`
+	PasswordHash string    `json:"-"`                // bcrypt
+	Role         string    `json:"role"`             // viewer, hr, admin
+	EmpID        *int      `json:"emp_id,omitempty"` // сотрудник; руководитель отдела получает права на свой отдел
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// RefreshToken выданный refresh-токен (таблица refresh_tokens)
+type RefreshToken struct {
+	Hash      string // SHA-256 токена в hex
+	UserID    int
+	ExpiresAt time.Time
+}
+
+// ReportTemplate версия пользовательского DOCX-шаблона отчёта (таблица report_templates)
+type ReportTemplate struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	Size      int       `json:"size"` // байт
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	Content   []byte    `json:"-"`
+}
+
+// ReportJob задание на асинхронное формирование отчёта (POST /api/reports).
+// Задания хранятся в памяти сервера и удаляются через reports.ttl после завершения.
+type ReportJob struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`     // department, company, export
+	Status     string     `json:"status"`   // queued, running, done, failed
+	Progress   int        `json:"progress"` // процент выполнения
+	Error      string     `json:"error,omitempty"`
+	Filename   string     `json:"filename"`
+	Size       int        `json:"size,omitempty"` // байт, для status=done
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+
+	contentType string
+	key         string // документ в хранилище отчётов (reportJobs.store)
+}
+
 // NavItem модель элемента навигации
 type NavItem struct {
 	Label string
@@ -31,4 +148,30 @@ type emp struct {
 	Name   string
 	Status string
 	Salary float64
+	Photo  *reportPhoto // только при departmentReport.Photos; nil — нет даже заглушки
+}
+
+// departmentReport содержимое отчёта по отделу, общее для DOCX, PDF и XLSX
+type departmentReport struct {
+	DeptID         int
+	DeptName       string
+	BossID         sql.NullInt64
+	Employees      []emp
+	ExpectedSalary float64 // ОТДЕЛЫ.ОТД_СОТР_ЗАРП
+	ExpectedCount  int     // ОТДЕЛЫ.ОТД_РАЗМ
+	ActualSalary   float64 // по таблице СОТРУДНИКИ
+	ActualCount    int
+
+	SalariesVisible bool                 // false — суммы выводятся только диапазонами
+	FormatSalary    func(float64) string // точная сумма или диапазон (см. salaryVisibility.formatter)
+	Photos          bool                 // столбец с фото сотрудников (только DOCX, см. attachPhotos)
+}
+
+// documentMeta колонтитулы и водяной знак выгружаемого документа
+type documentMeta struct {
+	Company     string // DocumentsConfig.CompanyName
+	Title       string // название отдела или отчёта в верхнем колонтитуле
+	GeneratedAt time.Time
+	GeneratedBy string // логин пользователя
+	Watermark   string // "" — без водяного знака
 }
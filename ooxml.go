@@ -0,0 +1,694 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Запись DOCX и XLSX без сторонних библиотек: резервный рендерер на случай,
+// когда лицензия unidoc недоступна (нет сети для metered-ключа).
+// Содержимое то же, что у unidocRenderer; оформление упрощено.
+
+const (
+	nsWordML   = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+	nsSheetML  = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	nsRels     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	nsPkgRels  = "http://schemas.openxmlformats.org/package/2006/relationships"
+	nsTypes    = "http://schemas.openxmlformats.org/package/2006/content-types"
+	xmlProlog  = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+	twipsPerCm = 567
+
+	docxTextWidth = 9638 // ширина полосы набора A4 с полями 2 см, twips
+)
+
+// ooxmlPart файл внутри пакета OOXML
+type ooxmlPart struct {
+	name    string
+	content string
+}
+
+// writeOOXMLPackage упаковывает части в zip; [Content_Types].xml должен идти первым
+func writeOOXMLPackage(parts []ooxmlPart) (bytes.Buffer, error) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, p := range parts {
+		w, err := zw.Create(p.name)
+		if err != nil {
+			return bytes.Buffer{}, fmt.Errorf("ошибка записи %s: %v", p.name, err)
+		}
+		if _, err := w.Write([]byte(p.content)); err != nil {
+			return bytes.Buffer{}, fmt.Errorf("ошибка записи %s: %v", p.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return bytes.Buffer{}, fmt.Errorf("ошибка сохранения документа: %v", err)
+	}
+	return *buf, nil
+}
+
+// xmlEscape экранирует текст и значения атрибутов
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// ---------------------------------------------------------------- DOCX
+
+// docxRun оформление фрагмента текста
+type docxRun struct {
+	size   float64 // пт; 0 — defaultFontSize
+	color  string  // hex без #; "" — чёрный
+	bold   bool
+	italic bool
+}
+
+func (r docxRun) props() string {
+	var sb strings.Builder
+	sb.WriteString(`<w:rPr><w:rFonts w:ascii="Arial" w:hAnsi="Arial" w:cs="Arial" w:eastAsia="Arial"/>`)
+	if r.bold {
+		sb.WriteString(`<w:b/>`)
+	}
+	if r.italic {
+		sb.WriteString(`<w:i/>`)
+	}
+	if r.color != "" {
+		sb.WriteString(`<w:color w:val="` + r.color + `"/>`)
+	}
+	size := r.size
+	if size == 0 {
+		size = defaultFontSize
+	}
+	half := strconv.Itoa(int(size * 2))
+	sb.WriteString(`<w:sz w:val="` + half + `"/><w:szCs w:val="` + half + `"/></w:rPr>`)
+	return sb.String()
+}
+
+// text фрагмент текста
+func (r docxRun) text(s string) string {
+	return `<w:r>` + r.props() + `<w:t xml:space="preserve">` + xmlEscape(s) + `</w:t></w:r>`
+}
+
+// tab табуляция
+func (r docxRun) tab() string {
+	return `<w:r>` + r.props() + `<w:tab/></w:r>`
+}
+
+// field поле Word (PAGE, NUMPAGES, TOC); placeholder виден до обновления полей
+func (r docxRun) field(instr, placeholder string) string {
+	props := r.props()
+	return `<w:r>` + props + `<w:fldChar w:fldCharType="begin" w:dirty="true"/></w:r>` +
+		`<w:r>` + props + `<w:instrText xml:space="preserve"> ` + xmlEscape(instr) + ` </w:instrText></w:r>` +
+		`<w:r>` + props + `<w:fldChar w:fldCharType="separate"/></w:r>` +
+		r.text(placeholder) +
+		`<w:r>` + props + `<w:fldChar w:fldCharType="end"/></w:r>`
+}
+
+// docxPara оформление абзаца
+type docxPara struct {
+	style     string // "Heading1"
+	pageBreak bool
+	tabRight  bool // табуляция к правому краю полосы набора
+	after     int  // интервал после, пт
+	before    int  // интервал до, пт
+	indent    int  // отступ слева, twips
+	align     string
+}
+
+func (p docxPara) xml(runs ...string) string {
+	var sb strings.Builder
+	sb.WriteString(`<w:p><w:pPr>`)
+	if p.style != "" {
+		sb.WriteString(`<w:pStyle w:val="` + p.style + `"/>`)
+	}
+	if p.pageBreak {
+		sb.WriteString(`<w:pageBreakBefore/>`)
+	}
+	if p.tabRight {
+		sb.WriteString(`<w:tabs><w:tab w:val="right" w:pos="` + strconv.Itoa(docxTextWidth) + `"/></w:tabs>`)
+	}
+	sb.WriteString(fmt.Sprintf(`<w:spacing w:before="%d" w:after="%d"/>`, p.before*20, p.after*20))
+	if p.indent > 0 {
+		sb.WriteString(fmt.Sprintf(`<w:ind w:left="%d" w:hanging="%d"/>`, p.indent, p.indent/2))
+	}
+	if p.align != "" {
+		sb.WriteString(`<w:jc w:val="` + p.align + `"/>`)
+	}
+	sb.WriteString(`</w:pPr>`)
+	for _, r := range runs {
+		sb.WriteString(r)
+	}
+	sb.WriteString(`</w:p>`)
+	return sb.String()
+}
+
+// docxCell ячейка таблицы
+type docxCell struct {
+	text       string
+	background string // "" — без заливки
+	run        docxRun
+	span       int    // объединение столбцов; 0 — одна ячейка
+	content    string // готовая разметка вместо text (фото)
+}
+
+// ooxmlDocument DOCX-документ отчёта: та же раскладка, что у reportDocument
+type ooxmlDocument struct {
+	body   strings.Builder
+	header string
+	footer string
+	toc    bool
+
+	media    []ooxmlPart             // word/media/*, на каждый — связь rIdImg<n>
+	photos   map[*reportPhoto]string // ID связи уже добавленного фото
+	drawings int                     // рисунков в тексте, для уникальных wp:docPr
+}
+
+// heading заголовок первого уровня (попадает в оглавление)
+func (d *ooxmlDocument) heading(text string, size float64, pageBreak bool) {
+	d.body.WriteString(docxPara{style: "Heading1", pageBreak: pageBreak, after: 5}.xml(
+		docxRun{size: size, color: "2E74B5", bold: true}.text(text)))
+}
+
+// table таблица на всю ширину полосы; первая строка повторяется на каждой странице
+func (d *ooxmlDocument) table(columns int, rows [][]docxCell) {
+	border := `w:val="single" w:sz="8" w:space="0" w:color="4F81BD"`
+	d.body.WriteString(`<w:tbl><w:tblPr><w:tblW w:w="5000" w:type="pct"/><w:tblBorders>`)
+	for _, side := range []string{"top", "left", "bottom", "right", "insideH", "insideV"} {
+		d.body.WriteString(`<w:` + side + ` ` + border + `/>`)
+	}
+	d.body.WriteString(`</w:tblBorders><w:tblCellMar><w:top w:w="45" w:type="dxa"/><w:left w:w="45" w:type="dxa"/>` +
+		`<w:bottom w:w="45" w:type="dxa"/><w:right w:w="45" w:type="dxa"/></w:tblCellMar></w:tblPr><w:tblGrid>`)
+	for range columns {
+		d.body.WriteString(`<w:gridCol w:w="` + strconv.Itoa(docxTextWidth/columns) + `"/>`)
+	}
+	d.body.WriteString(`</w:tblGrid>`)
+
+	for i, row := range rows {
+		d.body.WriteString(`<w:tr><w:trPr><w:trHeight w:val="510" w:hRule="atLeast"/>`)
+		if i == 0 {
+			d.body.WriteString(`<w:tblHeader/>`)
+		}
+		d.body.WriteString(`</w:trPr>`)
+		for _, cell := range row {
+			d.body.WriteString(`<w:tc><w:tcPr>`)
+			if cell.span > 1 {
+				d.body.WriteString(`<w:gridSpan w:val="` + strconv.Itoa(cell.span) + `"/>`)
+			}
+			if cell.background != "" {
+				d.body.WriteString(`<w:shd w:val="clear" w:color="auto" w:fill="` + cell.background + `"/>`)
+			}
+			d.body.WriteString(`<w:vAlign w:val="center"/></w:tcPr>`)
+			content := cell.content
+			if content == "" {
+				content = cell.run.text(cell.text)
+			}
+			d.body.WriteString(docxPara{align: "center"}.xml(content))
+			d.body.WriteString(`</w:tc>`)
+		}
+		d.body.WriteString(`</w:tr>`)
+	}
+	d.body.WriteString(`</w:tbl>`)
+}
+
+// departmentSection заголовок, таблица сотрудников и статистика отдела
+func (d *ooxmlDocument) departmentSection(report departmentReport, title string, pageBreak bool) {
+	d.heading(title, 18, pageBreak)
+
+	head := docxRun{size: 12, color: "FFFFFF", bold: true}
+	columns := []string{"ID", "Имя", "Статус", "Оклад"}
+	if report.Photos {
+		columns = append([]string{"Фото"}, columns...)
+	}
+	var header []docxCell
+	for _, txt := range columns {
+		header = append(header, docxCell{text: txt, background: "4F81BD", run: head})
+	}
+	rows := [][]docxCell{header}
+	// Строки данных: чередуем фон и выделяем босса
+	for i, e := range report.Employees {
+		background, run := "", docxRun{}
+		if report.BossID.Valid && int(report.BossID.Int64) == e.ID {
+			background, run = "E6F0FA", docxRun{color: "2E74B5", bold: true}
+		} else if i%2 == 1 {
+			background = "E7E6E6"
+		}
+		var row []docxCell
+		if report.Photos {
+			row = append(row, d.photoCell(e.Photo, background))
+		}
+		for _, txt := range []string{strconv.Itoa(e.ID), e.Name, e.Status, report.FormatSalary(e.Salary)} {
+			row = append(row, docxCell{text: txt, background: background, run: run})
+		}
+		rows = append(rows, row)
+	}
+	if len(report.Employees) == 0 {
+		rows = append(rows, []docxCell{{text: "В отделе нет сотрудников", run: docxRun{color: "888888"}, span: len(columns)}})
+	}
+	d.table(len(columns), rows)
+
+	// Статистика: ожидаемые значения из ОТДЕЛЫ и фактические из СОТРУДНИКИ
+	d.body.WriteString(docxPara{}.xml())
+	d.body.WriteString(docxPara{after: 4}.xml(docxRun{size: 14, color: "4F81BD", bold: true}.text("Статистика по отделу")))
+	for _, group := range []struct {
+		caption string
+		salary  float64
+		count   int
+		color   string
+	}{
+		{"Ожидаемые (по таблице ОТДЕЛЫ):", report.ExpectedSalary, report.ExpectedCount, "4CAF50"},
+		{"Фактические (по таблице СОТРУДНИКИ):", report.ActualSalary, report.ActualCount, "C00000"},
+	} {
+		d.body.WriteString(docxPara{after: 4}.xml(docxRun{size: 11, color: "888888", italic: true}.text(group.caption)))
+		for _, text := range []string{
+			"Суммарная зарплата: " + report.FormatSalary(group.salary),
+			fmt.Sprintf("Количество сотрудников: %d", group.count),
+		} {
+			run := docxRun{color: group.color}
+			d.body.WriteString(docxPara{after: 4, indent: 425}.xml(run.text("•"), run.tab(), run.text(text)))
+		}
+	}
+}
+
+// photoCell ячейка с миниатюрой фото; без фото — прочерк
+func (d *ooxmlDocument) photoCell(photo *reportPhoto, background string) docxCell {
+	if photo == nil {
+		return docxCell{text: "—", background: background, run: docxRun{color: "888888"}}
+	}
+	id, ok := d.photos[photo]
+	if !ok {
+		if d.photos == nil {
+			d.photos = map[*reportPhoto]string{}
+		}
+		n := len(d.media) + 1
+		id = fmt.Sprintf("rIdImg%d", n)
+		d.media = append(d.media, ooxmlPart{fmt.Sprintf("word/media/photo%d.%s", n, photo.format), string(photo.data)})
+		d.photos[photo] = id
+	}
+
+	// Размеры рисунка — в EMU (360000 на сантиметр)
+	w, h := photo.fit(photoThumbSize)
+	cx, cy := int(w*360000), int(h*360000)
+	d.drawings++
+	n := strconv.Itoa(d.drawings)
+	return docxCell{background: background, content: fmt.Sprintf(`<w:r><w:drawing>`+
+		`<wp:inline distT="0" distB="0" distL="0" distR="0"><wp:extent cx="%[1]d" cy="%[2]d"/><wp:docPr id="%[3]s" name="Фото %[3]s"/>`+
+		`<a:graphic xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">`+
+		`<a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">`+
+		`<pic:pic xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture">`+
+		`<pic:nvPicPr><pic:cNvPr id="%[3]s" name="photo%[3]s"/><pic:cNvPicPr/></pic:nvPicPr>`+
+		`<pic:blipFill><a:blip r:embed="%[4]s"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>`+
+		`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%[1]d" cy="%[2]d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr>`+
+		`</pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing></w:r>`, cx, cy, n, id)}
+}
+
+// headerFooter колонтитулы и водяной знак, как у reportDocument.headerFooter
+func (d *ooxmlDocument) headerFooter(meta documentMeta) {
+	small := docxRun{size: 8, color: "888888"}
+
+	header := docxPara{tabRight: true}.xml(small.text(meta.Company), small.tab(), small.text(meta.Title))
+	if meta.Watermark != "" {
+		header = strings.Replace(header, `</w:p>`, ooxmlWatermark(meta.Watermark)+`</w:p>`, 1)
+	}
+	d.header = header
+
+	d.footer = docxPara{tabRight: true}.xml(
+		small.text(fmt.Sprintf("Сформировано %s, %s", meta.GeneratedAt.Format("02.01.2006 15:04"), meta.GeneratedBy)),
+		small.tab(),
+		small.text("Страница "),
+		small.field("PAGE", "1"),
+		small.text(" из "),
+		small.field("NUMPAGES", "1"),
+	)
+}
+
+// ooxmlWatermark диагональная надпись WordArt (VML) в верхнем колонтитуле
+func ooxmlWatermark(text string) string {
+	return `<w:r><w:pict>` +
+		`<v:shapetype id="_x0000_t136" coordsize="21600,21600" o:spt="136" adj="10800" path="m@7,l@8,m@5,21600l@6,21600e">` +
+		`<v:formulas><v:f eqn="sum #0 0 10800"/><v:f eqn="prod #0 2 1"/><v:f eqn="sum 21600 0 @1"/><v:f eqn="sum 0 0 @2"/>` +
+		`<v:f eqn="sum 21600 0 @3"/><v:f eqn="if @0 @3 0"/><v:f eqn="if @0 21600 @1"/><v:f eqn="if @0 0 @2"/>` +
+		`<v:f eqn="if @0 @4 21600"/><v:f eqn="mid @5 @6"/><v:f eqn="mid @8 @5"/><v:f eqn="mid @7 @8"/>` +
+		`<v:f eqn="mid @6 @7"/><v:f eqn="sum @6 0 @5"/></v:formulas>` +
+		`<v:path textpathok="t" o:connecttype="custom" o:connectlocs="@9,0;@10,10800;@11,21600;@12,10800" o:connectangles="270,180,90,0"/>` +
+		`<v:textpath on="t" fitshape="t"/><o:lock v:ext="edit" text="t" shapetype="t"/></v:shapetype>` +
+		`<v:shape id="Watermark" o:spid="_x0000_s2049" type="#_x0000_t136" ` +
+		`style="position:absolute;margin-left:0;margin-top:0;width:468pt;height:117pt;rotation:315;z-index:-251657216;` +
+		`mso-position-horizontal:center;mso-position-horizontal-relative:margin;mso-position-vertical:center;mso-position-vertical-relative:margin" ` +
+		`o:allowincell="f" fillcolor="#` + watermarkColor + `" stroked="f">` +
+		`<v:fill opacity=".5"/><v:textpath style="font-family:&quot;Arial&quot;;font-size:1pt;font-weight:bold" string="` + xmlEscape(text) + `"/>` +
+		`</v:shape></w:pict></w:r>`
+}
+
+// save упаковывает документ: тело, стили, настройки и колонтитулы
+func (d *ooxmlDocument) save() (bytes.Buffer, error) {
+	ns := `xmlns:w="` + nsWordML + `" xmlns:r="` + nsRels + `"`
+	nsVML := ns + ` xmlns:v="urn:schemas-microsoft-com:vml" xmlns:o="urn:schemas-microsoft-com:office:office"`
+	nsDrawing := ns + ` xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"`
+
+	document := xmlProlog + `<w:document ` + nsDrawing + `><w:body>` + d.body.String() +
+		`<w:sectPr><w:headerReference w:type="default" r:id="rId3"/><w:footerReference w:type="default" r:id="rId4"/>` +
+		`<w:pgSz w:w="11906" w:h="16838"/>` +
+		fmt.Sprintf(`<w:pgMar w:top="%d" w:right="%d" w:bottom="%d" w:left="%d" w:header="%d" w:footer="%d" w:gutter="0"/>`,
+			2*twipsPerCm, 2*twipsPerCm, 2*twipsPerCm, 2*twipsPerCm, twipsPerCm, twipsPerCm) +
+		`</w:sectPr></w:body></w:document>`
+
+	// Оглавление заполняет Word при открытии
+	settings := xmlProlog + `<w:settings xmlns:w="` + nsWordML + `">`
+	if d.toc {
+		settings += `<w:updateFields w:val="true"/>`
+	}
+	settings += `</w:settings>`
+
+	styles := xmlProlog + `<w:styles xmlns:w="` + nsWordML + `">` +
+		`<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Arial" w:hAnsi="Arial" w:cs="Arial" w:eastAsia="Arial"/>` +
+		`<w:sz w:val="20"/><w:szCs w:val="20"/><w:lang w:val="ru-RU"/></w:rPr></w:rPrDefault><w:pPrDefault/></w:docDefaults>` +
+		`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:qFormat/></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/>` +
+		`<w:next w:val="Normal"/><w:qFormat/><w:pPr><w:keepNext/><w:outlineLvl w:val="0"/></w:pPr></w:style>` +
+		`<w:style w:type="table" w:default="1" w:styleId="TableNormal"><w:name w:val="Normal Table"/></w:style>` +
+		`</w:styles>`
+
+	// Фото: связи документа и типы содержимого по расширению
+	var mediaRels string
+	for i, m := range d.media {
+		mediaRels += fmt.Sprintf(`<Relationship Id="rIdImg%d" Type="%s/image" Target="%s"/>`, i+1, nsRels, strings.TrimPrefix(m.name, "word/"))
+	}
+
+	parts := []ooxmlPart{
+		{"[Content_Types].xml", xmlProlog + `<Types xmlns="` + nsTypes + `">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Default Extension="png" ContentType="image/png"/>` +
+			`<Default Extension="jpeg" ContentType="image/jpeg"/>` +
+			`<Default Extension="gif" ContentType="image/gif"/>` +
+			`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+			`<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>` +
+			`<Override PartName="/word/settings.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.settings+xml"/>` +
+			`<Override PartName="/word/header1.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.header+xml"/>` +
+			`<Override PartName="/word/footer1.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.footer+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", xmlProlog + `<Relationships xmlns="` + nsPkgRels + `">` +
+			`<Relationship Id="rId1" Type="` + nsRels + `/officeDocument" Target="word/document.xml"/></Relationships>`},
+		{"word/_rels/document.xml.rels", xmlProlog + `<Relationships xmlns="` + nsPkgRels + `">` +
+			`<Relationship Id="rId1" Type="` + nsRels + `/styles" Target="styles.xml"/>` +
+			`<Relationship Id="rId2" Type="` + nsRels + `/settings" Target="settings.xml"/>` +
+			`<Relationship Id="rId3" Type="` + nsRels + `/header" Target="header1.xml"/>` +
+			`<Relationship Id="rId4" Type="` + nsRels + `/footer" Target="footer1.xml"/>` + mediaRels + `</Relationships>`},
+		{"word/document.xml", document},
+		{"word/styles.xml", styles},
+		{"word/settings.xml", settings},
+		{"word/header1.xml", xmlProlog + `<w:hdr ` + nsVML + `>` + d.header + `</w:hdr>`},
+		{"word/footer1.xml", xmlProlog + `<w:ftr ` + ns + `>` + d.footer + `</w:ftr>`},
+	}
+	return writeOOXMLPackage(append(parts, d.media...))
+}
+
+// setupOOXMLDocument DOCX-отчёт по отделу, как setupDocument
+func setupOOXMLDocument(report departmentReport, meta documentMeta) (bytes.Buffer, error) {
+	d := &ooxmlDocument{}
+	d.headerFooter(meta)
+	d.departmentSection(report, fmt.Sprintf("Сотрудники отдела №%d", report.DeptID), false)
+	return d.save()
+}
+
+// setupOOXMLCompanyDocument сводный DOCX-отчёт, как setupCompanyDocument
+func setupOOXMLCompanyDocument(reports []departmentReport, meta documentMeta) (bytes.Buffer, error) {
+	d := &ooxmlDocument{toc: true}
+	d.headerFooter(meta)
+
+	// 1) Титульная страница
+	for _, line := range []struct {
+		text   string
+		run    docxRun
+		before int
+	}{
+		{companyReportTitle, docxRun{size: 26, color: "2E74B5", bold: true}, 200},
+		{fmt.Sprintf("Отделов в отчёте: %d", len(reports)), docxRun{size: 14, color: "4F81BD"}, 12},
+		{"Сформирован: " + meta.GeneratedAt.Format("02.01.2006 15:04"), docxRun{size: 11, color: "888888"}, 120},
+		{"Автор: " + meta.GeneratedBy, docxRun{size: 11, color: "888888"}, 0},
+	} {
+		d.body.WriteString(docxPara{align: "center", before: line.before, after: 5}.xml(line.run.text(line.text)))
+	}
+
+	// 2) Оглавление: Word заполнит поле TOC при открытии (updateFields)
+	d.body.WriteString(docxPara{pageBreak: true, after: 10}.xml(docxRun{size: 18, color: "2E74B5", bold: true}.text("Содержание")))
+	d.body.WriteString(docxPara{}.xml(docxRun{}.field(`TOC \o "1-1" \h \z \u`, "Оглавление обновится при открытии документа")))
+
+	// 3) Разделы отделов, каждый с новой страницы
+	for _, r := range reports {
+		d.departmentSection(r, fmt.Sprintf("Отдел №%d «%s»", r.DeptID, r.DeptName), true)
+	}
+
+	// 4) Сводка по компании; расхождения — красным
+	d.heading("Сводка по компании", 18, true)
+	head := docxRun{size: 12, color: "FFFFFF", bold: true}
+	var rows [][]docxCell
+	row := func(background string, run docxRun, cells ...string) {
+		var r []docxCell
+		for _, txt := range cells {
+			r = append(r, docxCell{text: txt, background: background, run: run})
+		}
+		rows = append(rows, r)
+	}
+	row("4F81BD", head, "ID", "Отдел", "ОТД_РАЗМ", "Сотрудников", "ОТД_СОТР_ЗАРП", "Сумма окладов")
+
+	allVisible := true
+	var expectedSalary, actualSalary float64
+	var expectedCount, actualCount int
+	for i, r := range reports {
+		background, run := "", docxRun{}
+		if i%2 == 1 {
+			background = "E7E6E6"
+		}
+		if r.ExpectedCount != r.ActualCount || r.SalariesVisible && r.ExpectedSalary != r.ActualSalary {
+			run = docxRun{color: "C00000", bold: true}
+		}
+		row(background, run,
+			strconv.Itoa(r.DeptID), r.DeptName,
+			strconv.Itoa(r.ExpectedCount), strconv.Itoa(r.ActualCount),
+			r.FormatSalary(r.ExpectedSalary), r.FormatSalary(r.ActualSalary),
+		)
+
+		allVisible = allVisible && r.SalariesVisible
+		expectedSalary += r.ExpectedSalary
+		actualSalary += r.ActualSalary
+		expectedCount += r.ExpectedCount
+		actualCount += r.ActualCount
+	}
+
+	// Итоги; суммы зарплат — только если видны все отделы
+	expectedTotal, actualTotal := "—", "—"
+	if allVisible {
+		expectedTotal, actualTotal = fmt.Sprintf("%.2f", expectedSalary), fmt.Sprintf("%.2f", actualSalary)
+	}
+	row("E7E6E6", docxRun{bold: true},
+		"", "Итого",
+		strconv.Itoa(expectedCount), strconv.Itoa(actualCount),
+		expectedTotal, actualTotal,
+	)
+	d.table(6, rows)
+
+	return d.save()
+}
+
+// ---------------------------------------------------------------- XLSX
+
+// ooxmlWorkbook реализация workbookWriter: запись SpreadsheetML без unioffice
+type ooxmlWorkbook struct {
+	sheets []*ooxmlSheet
+	styles ooxmlStyles
+}
+
+func (b *ooxmlWorkbook) addSheet(name string) sheetWriter {
+	s := &ooxmlSheet{name: name, styles: &b.styles}
+	b.sheets = append(b.sheets, s)
+	return s
+}
+
+func (b *ooxmlWorkbook) save() (bytes.Buffer, error) {
+	types := xmlProlog + `<Types xmlns="` + nsTypes + `">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`
+	workbook := xmlProlog + `<workbook xmlns="` + nsSheetML + `" xmlns:r="` + nsRels + `"><bookViews><workbookView/></bookViews><sheets>`
+	rels := xmlProlog + `<Relationships xmlns="` + nsPkgRels + `">`
+	var sheets []ooxmlPart
+	for i, s := range b.sheets {
+		n := strconv.Itoa(i + 1)
+		types += `<Override PartName="/xl/worksheets/sheet` + n + `.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`
+		workbook += `<sheet name="` + xmlEscape(s.name) + `" sheetId="` + n + `" r:id="rId` + n + `"/>`
+		rels += `<Relationship Id="rId` + n + `" Type="` + nsRels + `/worksheet" Target="worksheets/sheet` + n + `.xml"/>`
+		sheets = append(sheets, ooxmlPart{"xl/worksheets/sheet" + n + ".xml", s.xml(i == 0)})
+	}
+	types += `</Types>`
+	// Формулы пересчитываются при открытии; до этого видны сохранённые значения
+	workbook += `</sheets><calcPr calcId="191029" fullCalcOnLoad="1"/></workbook>`
+	rels += `<Relationship Id="rId` + strconv.Itoa(len(b.sheets)+1) + `" Type="` + nsRels + `/styles" Target="styles.xml"/></Relationships>`
+
+	parts := []ooxmlPart{
+		{"[Content_Types].xml", types},
+		{"_rels/.rels", xmlProlog + `<Relationships xmlns="` + nsPkgRels + `">` +
+			`<Relationship Id="rId1" Type="` + nsRels + `/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", workbook},
+		{"xl/_rels/workbook.xml.rels", rels},
+		{"xl/styles.xml", b.styles.xml()},
+	}
+	return writeOOXMLPackage(append(parts, sheets...))
+}
+
+// ooxmlSheet реализация sheetWriter
+type ooxmlSheet struct {
+	name   string
+	styles *ooxmlStyles
+	rows   []*strings.Builder
+	col    int // номер следующей ячейки текущей строки, с 0
+
+	widths         []float64
+	header, footer string
+}
+
+func (s *ooxmlSheet) addRow() int {
+	s.rows = append(s.rows, &strings.Builder{})
+	s.col = 0
+	return len(s.rows)
+}
+
+// cell открывает ячейку текущей строки; attrs — дополнительные атрибуты
+func (s *ooxmlSheet) cell(st sheetStyle, attrs string) *strings.Builder {
+	row := s.rows[len(s.rows)-1]
+	ref := columnName(s.col) + strconv.Itoa(len(s.rows))
+	s.col++
+	row.WriteString(`<c r="` + ref + `" s="` + strconv.Itoa(s.styles.get(st)) + `"` + attrs + `>`)
+	return row
+}
+
+func (s *ooxmlSheet) text(v string, st sheetStyle) {
+	row := s.cell(st, ` t="inlineStr"`)
+	row.WriteString(`<is><t xml:space="preserve">` + xmlEscape(v) + `</t></is></c>`)
+}
+
+func (s *ooxmlSheet) number(v float64, st sheetStyle) {
+	row := s.cell(st, "")
+	row.WriteString(`<v>` + strconv.FormatFloat(v, 'f', -1, 64) + `</v></c>`)
+}
+
+func (s *ooxmlSheet) formula(f string, value float64, st sheetStyle) {
+	row := s.cell(st, "")
+	row.WriteString(`<f>` + xmlEscape(f) + `</f><v>` + strconv.FormatFloat(value, 'f', -1, 64) + `</v></c>`)
+}
+
+func (s *ooxmlSheet) skip() {
+	s.col++
+}
+
+func (s *ooxmlSheet) setup(widths []float64, meta documentMeta) {
+	s.widths = widths
+	s.header, s.footer = sheetHeaderFooter(meta, s.name)
+}
+
+// xml лист целиком; первая строка закреплена
+func (s *ooxmlSheet) xml(selected bool) string {
+	var sb strings.Builder
+	sb.WriteString(xmlProlog + `<worksheet xmlns="` + nsSheetML + `" xmlns:r="` + nsRels + `">`)
+	sb.WriteString(`<sheetViews><sheetView workbookViewId="0"`)
+	if selected {
+		sb.WriteString(` tabSelected="1"`)
+	}
+	sb.WriteString(`><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	sb.WriteString(`<sheetFormatPr defaultRowHeight="15"/>`)
+	if len(s.widths) > 0 {
+		sb.WriteString(`<cols>`)
+		for i, w := range s.widths {
+			// Ширина столбца — в символах шрифта по умолчанию (~0.19 см)
+			n := strconv.Itoa(i + 1)
+			sb.WriteString(`<col min="` + n + `" max="` + n + `" width="` + strconv.FormatFloat(w/0.19, 'f', 1, 64) + `" customWidth="1"/>`)
+		}
+		sb.WriteString(`</cols>`)
+	}
+	sb.WriteString(`<sheetData>`)
+	for i, row := range s.rows {
+		sb.WriteString(`<row r="` + strconv.Itoa(i+1) + `">` + row.String() + `</row>`)
+	}
+	sb.WriteString(`</sheetData>`)
+	sb.WriteString(`<pageMargins left="0.7" right="0.7" top="0.75" bottom="0.75" header="0.3" footer="0.3"/>`)
+	sb.WriteString(`<headerFooter><oddHeader>` + xmlEscape(s.header) + `</oddHeader><oddFooter>` + xmlEscape(s.footer) + `</oddFooter></headerFooter>`)
+	sb.WriteString(`</worksheet>`)
+	return sb.String()
+}
+
+// ooxmlStyles стили книги: шрифт, заливка и формат на каждое оформление ячейки
+type ooxmlStyles struct {
+	list  []sheetStyle // индекс в cellXfs минус 1 (0 — стиль по умолчанию)
+	index map[sheetStyle]int
+}
+
+func (s *ooxmlStyles) get(st sheetStyle) int {
+	if i, ok := s.index[st]; ok {
+		return i
+	}
+	if s.index == nil {
+		s.index = map[sheetStyle]int{}
+	}
+	s.list = append(s.list, st)
+	s.index[st] = len(s.list)
+	return len(s.list)
+}
+
+func (s *ooxmlStyles) xml() string {
+	// Шрифт, заливка и формат на каждый стиль: стилей в отчёте единицы
+	var fonts, fills, xfs strings.Builder
+	fonts.WriteString(`<font><sz val="10"/><name val="Arial"/></font>`)
+	fills.WriteString(`<fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>`)
+	xfs.WriteString(`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>`)
+	nFills := 2
+	for i, st := range s.list {
+		fonts.WriteString(`<font>`)
+		if st.bold {
+			fonts.WriteString(`<b/>`)
+		}
+		fonts.WriteString(`<sz val="10"/>`)
+		if st.text != "" {
+			fonts.WriteString(`<color rgb="FF` + st.text + `"/>`)
+		}
+		fonts.WriteString(`<name val="Arial"/></font>`)
+
+		fillID := 0
+		if st.background != "" {
+			fills.WriteString(`<fill><patternFill patternType="solid"><fgColor rgb="FF` + st.background + `"/><bgColor indexed="64"/></patternFill></fill>`)
+			fillID = nFills
+			nFills++
+		}
+		numFmt := 0
+		if st.money {
+			numFmt = 164
+		}
+		xfs.WriteString(fmt.Sprintf(`<xf numFmtId="%d" fontId="%d" fillId="%d" borderId="1" xfId="0" applyNumberFormat="1" applyFont="1" applyFill="1" applyBorder="1"/>`,
+			numFmt, i+1, fillID))
+	}
+
+	thin := `style="thin"><color rgb="FF` + sheetColorHeader + `"/>`
+	return xmlProlog + `<styleSheet xmlns="` + nsSheetML + `">` +
+		`<numFmts count="1"><numFmt numFmtId="164" formatCode="` + sheetMoneyFormat + `"/></numFmts>` +
+		`<fonts count="` + strconv.Itoa(len(s.list)+1) + `">` + fonts.String() + `</fonts>` +
+		`<fills count="` + strconv.Itoa(nFills) + `">` + fills.String() + `</fills>` +
+		`<borders count="2"><border><left/><right/><top/><bottom/><diagonal/></border>` +
+		`<border><left ` + thin + `</left><right ` + thin + `</right><top ` + thin + `</top><bottom ` + thin + `</bottom><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="` + strconv.Itoa(len(s.list)+1) + `">` + xfs.String() + `</cellXfs>` +
+		`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+		`</styleSheet>`
+}
+
+// columnName буква столбца по номеру: 0 -> A, 26 -> AA (обратная columnIndex)
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Чтение XLSX без сторонних библиотек (импорт сотрудников): значения ячеек
+// первого листа как текст. Стили, формулы и даты не интерпретируются —
+// для ячейки с формулой берётся сохранённое значение.
+
+// maxOOXMLPartSize предел распакованного размера части пакета (защита от zip-бомб)
+const maxOOXMLPartSize = 64 << 20
+
+// errSheetTooLong лист длиннее допустимого (см. readXLSXSheet)
+var errSheetTooLong = errors.New("слишком много строк")
+
+// readXLSXSheet читает первый лист книги; records[i] — строка i+1 листа,
+// пропущенные строки и ячейки остаются пустыми. Непустая строка дальше maxRows —
+// errSheetTooLong: разбор останавливается, не дочитывая лист; пустые строки
+// за пределом (оформленные, но не заполненные) пропускаются.
+func readXLSXSheet(data []byte, maxRows int) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	parts := map[string]*zip.File{}
+	for _, f := range zr.File {
+		parts[f.Name] = f
+	}
+
+	sheet, err := firstSheetPath(parts)
+	if err != nil || sheet == "" {
+		return nil, err
+	}
+	var shared []string
+	if f, ok := parts["xl/sharedStrings.xml"]; ok {
+		if shared, err = readSharedStrings(f); err != nil {
+			return nil, err
+		}
+	}
+	f, ok := parts[sheet]
+	if !ok {
+		return nil, fmt.Errorf("нет листа %s", sheet)
+	}
+	return readSheetRecords(f, shared, maxRows)
+}
+
+// firstSheetPath путь к первому листу по xl/workbook.xml и его связям; "" — листов нет
+func firstSheetPath(parts map[string]*zip.File) (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeOOXMLPart(parts, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", nil
+	}
+	var rels struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeOOXMLPart(parts, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	for _, r := range rels.Rels {
+		if r.ID != workbook.Sheets[0].RID {
+			continue
+		}
+		// Путь задаётся относительно xl/ или от корня пакета
+		if strings.HasPrefix(r.Target, "/") {
+			return strings.TrimPrefix(r.Target, "/"), nil
+		}
+		return path.Join("xl", r.Target), nil
+	}
+	return "", fmt.Errorf("не найдена связь %s первого листа", workbook.Sheets[0].RID)
+}
+
+// decodeOOXMLPart разбирает XML-часть пакета name в v
+func decodeOOXMLPart(parts map[string]*zip.File, name string, v interface{}) error {
+	f, ok := parts[name]
+	if !ok {
+		return fmt.Errorf("нет части %s", name)
+	}
+	r, err := openOOXMLPart(f)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := xml.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// openOOXMLPart открывает часть пакета с ограничением распакованного размера
+func openOOXMLPart(f *zip.File) (io.ReadCloser, error) {
+	if f.UncompressedSize64 > maxOOXMLPartSize {
+		return nil, fmt.Errorf("%s: часть больше %d МБ", f.Name, maxOOXMLPartSize>>20)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", f.Name, err)
+	}
+	// Заголовок zip может занижать размер
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(r, maxOOXMLPartSize), r}, nil
+}
+
+// xlsxText строка с форматированием: простой текст <t> или фрагменты <r><t>
+type xlsxText struct {
+	T    string   `xml:"t"`
+	Runs []string `xml:"r>t"`
+}
+
+func (t xlsxText) String() string {
+	return t.T + strings.Join(t.Runs, "")
+}
+
+// readSharedStrings таблица общих строк xl/sharedStrings.xml
+func readSharedStrings(f *zip.File) ([]string, error) {
+	r, err := openOOXMLPart(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var sst struct {
+		Items []xlsxText `xml:"si"`
+	}
+	if err := xml.NewDecoder(r).Decode(&sst); err != nil {
+		return nil, fmt.Errorf("%s: %v", f.Name, err)
+	}
+	shared := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		shared[i] = si.String()
+	}
+	return shared, nil
+}
+
+// xlsxCell ячейка листа: тип t — s (общая строка), inlineStr, str (результат формулы),
+// b (логическое), e (ошибка) или число по умолчанию
+type xlsxCell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Value  string   `xml:"v"`
+	Inline xlsxText `xml:"is"`
+}
+
+// readSheetRecords значения ячеек листа по строкам, не более maxRows;
+// строки разбираются потоково
+func readSheetRecords(f *zip.File, shared []string, maxRows int) ([][]string, error) {
+	r, err := openOOXMLPart(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var records [][]string
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %v", f.Name, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+		var row struct {
+			Num   int        `xml:"r,attr"`
+			Cells []xlsxCell `xml:"c"`
+		}
+		if err := dec.DecodeElement(&row, &start); err != nil {
+			return nil, fmt.Errorf("%s: %v", f.Name, err)
+		}
+
+		rec := []string{}
+		for _, c := range row.Cells {
+			// Без ссылки ячейка следует за предыдущей
+			idx := len(rec)
+			if c.Ref != "" {
+				idx = columnIndex(strings.TrimRight(c.Ref, "0123456789"))
+			}
+			if idx < 0 || idx >= 16384 {
+				return nil, fmt.Errorf("%s: некорректная ячейка %q", f.Name, c.Ref)
+			}
+			for len(rec) <= idx {
+				rec = append(rec, "")
+			}
+			rec[idx], err = c.text(shared)
+			if err != nil {
+				return nil, fmt.Errorf("%s: ячейка %s: %v", f.Name, c.Ref, err)
+			}
+		}
+
+		// Пропущенные строки листа сохраняют нумерацию, как в Excel
+		if row.Num == 0 {
+			row.Num = len(records) + 1
+		}
+		if row.Num <= len(records) || row.Num > 1<<20 {
+			return nil, fmt.Errorf("%s: некорректный номер строки %d", f.Name, row.Num)
+		}
+		if row.Num > maxRows {
+			if strings.Join(rec, "") != "" {
+				return nil, fmt.Errorf("%w: данные в строке %d, не более %d", errSheetTooLong, row.Num, maxRows)
+			}
+			continue
+		}
+		for len(records) < row.Num-1 {
+			records = append(records, nil)
+		}
+		records = append(records, rec)
+	}
+}
+
+// text значение ячейки как текст; числа — без форматирования
+func (c xlsxCell) text(shared []string) (string, error) {
+	switch c.Type {
+	case "s":
+		var i int
+		if _, err := fmt.Sscan(c.Value, &i); err != nil || i < 0 || i >= len(shared) {
+			return "", fmt.Errorf("некорректная ссылка на общую строку %q", c.Value)
+		}
+		return shared[i], nil
+	case "inlineStr":
+		return c.Inline.String(), nil
+	case "b":
+		if c.Value == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	default:
+		return c.Value, nil
+	}
+}
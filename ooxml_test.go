@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ooxmlTestMeta колонтитулы со спецсимволами XML и колонтитулов Excel
+var ooxmlTestMeta = documentMeta{
+	Company:     "ООО «Рога & Копыта» <HQ>",
+	Title:       "Отчёт",
+	GeneratedAt: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+	GeneratedBy: "hr&co",
+	Watermark:   "Конфиденциально <ДСП>",
+}
+
+// ooxmlTestReports отдел с открытыми зарплатами и отдел со скрытыми;
+// в именах кириллица, & и <
+func ooxmlTestReports() []departmentReport {
+	reports := spreadsheetTestReports(true)
+	reports[0].DeptName = "Склад & Логистика <Юг>"
+	reports[0].Employees[0].Name = "Иванов <Иван> & сын"
+	reports[0].BossID.Int64, reports[0].BossID.Valid = 1, true
+	reports[1].Employees[0].Status = "в отпуске & <на больничном>"
+	return reports
+}
+
+// checkWellFormed проверяет, что каждая XML-часть пакета разбирается, и возвращает
+// текст частей без разметки
+func checkWellFormed(t *testing.T, parts map[string][]byte) map[string]string {
+	t.Helper()
+	texts := map[string]string{}
+	for name, data := range parts {
+		if !strings.HasSuffix(name, ".xml") && !strings.HasSuffix(name, ".rels") {
+			continue
+		}
+		var sb strings.Builder
+		dec := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if cd, ok := tok.(xml.CharData); ok {
+				sb.Write(cd)
+			}
+		}
+		texts[name] = sb.String()
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels"} {
+		if _, ok := texts[name]; !ok {
+			t.Errorf("нет части %s", name)
+		}
+	}
+	return texts
+}
+
+func TestOOXMLDocumentWellFormed(t *testing.T) {
+	report := ooxmlTestReports()[0]
+	buf, err := setupOOXMLDocument(report, ooxmlTestMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := checkWellFormed(t, unzipParts(t, buf.Bytes()))
+
+	body := texts["word/document.xml"]
+	for _, want := range []string{"Сотрудники отдела №1", "Иванов <Иван> & сын", "Борис", "1000.00"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("в документе нет %q", want)
+		}
+	}
+	if header := texts["word/header1.xml"]; !strings.Contains(header, "ООО «Рога & Копыта» <HQ>") {
+		t.Errorf("верхний колонтитул %q", header)
+	}
+	if footer := texts["word/footer1.xml"]; !strings.Contains(footer, "hr&co") {
+		t.Errorf("нижний колонтитул %q", footer)
+	}
+}
+
+func TestOOXMLCompanyDocumentWellFormed(t *testing.T) {
+	buf, err := setupOOXMLCompanyDocument(ooxmlTestReports(), ooxmlTestMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := checkWellFormed(t, unzipParts(t, buf.Bytes()))
+
+	body := texts["word/document.xml"]
+	for _, want := range []string{"Отдел №1 «Склад & Логистика <Юг>»", "в отпуске & <на больничном>", "Сводка по компании", "125k–150k"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("в документе нет %q", want)
+		}
+	}
+	// Скрытые зарплаты не выводятся ни точно, ни в итогах
+	for _, leak := range []string{"60000", "130000", "133000"} {
+		if strings.Contains(body, leak) {
+			t.Errorf("в документе скрытая сумма %s", leak)
+		}
+	}
+}
+
+func TestOOXMLWorkbookWellFormed(t *testing.T) {
+	wb := &ooxmlWorkbook{}
+	layoutSpreadsheet(wb, ooxmlTestReports(), ooxmlTestMeta)
+	buf, err := wb.save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := unzipParts(t, buf.Bytes())
+	texts := checkWellFormed(t, parts)
+
+	names, sheets := readTestWorkbook(t, parts)
+	if strings.Join(names, ",") != "Сводка,Отдел 1,Отдел 2" {
+		t.Fatalf("листы %v", names)
+	}
+	summary, dept := sheets[0], sheets[1]
+	if c := summary["B2"]; c.Inline != "Склад & Логистика <Юг>" {
+		t.Errorf("название отдела %+v", c)
+	}
+	if c := dept["B2"]; c.Inline != "Иванов <Иван> & сын" {
+		t.Errorf("имя сотрудника %+v", c)
+	}
+	for ref, want := range map[string]string{"D2": "'Отдел 1'!D6", "G3": "'Отдел 2'!D5", "F4": "SUM(F2:F3)"} {
+		if c := summary[ref]; c.Formula != want {
+			t.Errorf("сводка %s = %+v, ожидалась формула %s", ref, c, want)
+		}
+	}
+	if c := dept["D5"]; c.Formula != "COUNT(A2:A3)" || c.Value != "2" {
+		t.Errorf("число сотрудников %+v", c)
+	}
+
+	// Литеральный & в колонтитуле Excel удваивается, в XML — экранируется
+	if header := texts["xl/worksheets/sheet1.xml"]; !strings.Contains(header, "&LООО «Рога && Копыта» <HQ>") {
+		t.Errorf("колонтитул листа %q", header)
+	}
+}
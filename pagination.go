@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Ограничения размера страницы GET /api/employees
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// Поля сортировки сотрудников
+const (
+	sortByID     = "id"
+	sortByName   = "name"
+	sortBySalary = "salary"
+)
+
+// EmployeeQuery фильтры, сортировка и страница списка сотрудников.
+// Нулевое значение означает "все сотрудники по возрастанию ID".
+type EmployeeQuery struct {
+	Status    string   // точное совпадение СЛУ_СТАТ
+	DeptID    *int     // отдел
+	SalaryMin *float64 // зарплата >= SalaryMin
+	SalaryMax *float64 // зарплата <= SalaryMax
+	Name      string   // подстрока имени
+
+	Sort string // sortByID, sortByName, sortBySalary
+	Desc bool
+
+	Limit  int             // 0 — без ограничения
+	Offset int             // игнорируется при Cursor
+	Cursor *employeeCursor // продолжение после последней записи предыдущей страницы
+}
+
+// PageMeta метаданные страницы в APIResponse.Meta
+type PageMeta struct {
+	Total      int    `json:"total"` // всего записей, удовлетворяющих фильтрам
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"` // пусто на последней странице
+}
+
+// employeeCursor позиция keyset-пагинации: значение поля сортировки и ID последней записи
+type employeeCursor struct {
+	Sort   string  `json:"s"`
+	Desc   bool    `json:"d,omitempty"`
+	ID     int     `json:"i"`
+	Name   string  `json:"n,omitempty"`
+	Salary float64 `json:"z,omitempty"`
+}
+
+// encode непрозрачная строка курсора для клиента
+func (c employeeCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// pageMeta метаданные страницы; курсор выдаётся, если за страницей могут быть записи
+func pageMeta(page []Employee, total int, q EmployeeQuery) PageMeta {
+	meta := PageMeta{Total: total, Limit: q.Limit, Offset: q.Offset}
+	more := q.Cursor != nil || q.Offset+len(page) < total
+	if q.Limit > 0 && len(page) == q.Limit && more {
+		last := page[len(page)-1]
+		// В курсор попадает только поле сортировки: курсор не должен раскрывать зарплату
+		cur := employeeCursor{Sort: q.Sort, Desc: q.Desc, ID: last.ID}
+		switch q.Sort {
+		case sortByName:
+			cur.Name = last.Name
+		case sortBySalary:
+			cur.Salary = last.Salary
+		}
+		meta.NextCursor = cur.encode()
+	}
+	return meta
+}
+
+// decodeEmployeeCursor разбирает курсор и проверяет, что он выдан для той же сортировки
+func decodeEmployeeCursor(s string, q EmployeeQuery) (*employeeCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный cursor")
+	}
+	var c employeeCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("некорректный cursor")
+	}
+	if c.Sort != q.Sort || c.Desc != q.Desc {
+		return nil, fmt.Errorf("cursor выдан для другой сортировки")
+	}
+	return &c, nil
+}
+
+// parseEmployeeQuery читает параметры запроса:
+// limit, offset, cursor, status, dept_id, salary_min, salary_max, name, sort (id|name|salary, "-" — по убыванию), order (asc|desc)
+func parseEmployeeQuery(c *gin.Context) (EmployeeQuery, error) {
+	q := EmployeeQuery{
+		Status: strings.TrimSpace(c.Query("status")),
+		Name:   strings.TrimSpace(c.Query("name")),
+		Sort:   sortByID,
+		Limit:  defaultPageLimit,
+	}
+
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return q, fmt.Errorf("некорректный limit: от 1 до %d", maxPageLimit)
+		}
+		q.Limit = n
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return q, fmt.Errorf("некорректный offset")
+		}
+		q.Offset = n
+	}
+	if v := c.Query("dept_id"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return q, fmt.Errorf("некорректный dept_id")
+		}
+		q.DeptID = &n
+	}
+	for name, dst := range map[string]**float64{
+		"salary_min": &q.SalaryMin,
+		"salary_max": &q.SalaryMax,
+	} {
+		if v := c.Query(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return q, fmt.Errorf("некорректный %s", name)
+			}
+			*dst = &f
+		}
+	}
+	if q.SalaryMin != nil && q.SalaryMax != nil && *q.SalaryMin > *q.SalaryMax {
+		return q, fmt.Errorf("salary_min больше salary_max")
+	}
+
+	if v := c.Query("sort"); v != "" {
+		if strings.HasPrefix(v, "-") {
+			q.Desc = true
+			v = v[1:]
+		}
+		switch v {
+		case sortByID, sortByName, sortBySalary:
+			q.Sort = v
+		default:
+			return q, fmt.Errorf("некорректный sort: id, name или salary")
+		}
+	}
+	switch c.Query("order") {
+	case "":
+	case "asc":
+		q.Desc = false
+	case "desc":
+		q.Desc = true
+	default:
+		return q, fmt.Errorf("некорректный order: asc или desc")
+	}
+
+	if v := c.Query("cursor"); v != "" {
+		cur, err := decodeEmployeeCursor(v, q)
+		if err != nil {
+			return q, err
+		}
+		q.Cursor = cur
+		q.Offset = 0
+	}
+	return q, nil
+}
+
+// employeeLess порядок сортировки q; при равенстве ключа — по ID
+func employeeLess(a, b Employee, q EmployeeQuery) bool {
+	less, equal := a.ID < b.ID, a.ID == b.ID
+	switch q.Sort {
+	case sortByName:
+		if a.Name != b.Name {
+			less, equal = a.Name < b.Name, false
+		}
+	case sortBySalary:
+		if a.Salary != b.Salary {
+			less, equal = a.Salary < b.Salary, false
+		}
+	}
+	if equal {
+		return false
+	}
+	if q.Desc {
+		return !less
+	}
+	return less
+}
+
+// matchEmployee проверяет фильтры q (без курсора)
+func matchEmployee(e Employee, q EmployeeQuery) bool {
+	switch {
+	case q.Status != "" && e.Status != q.Status:
+		return false
+	case q.DeptID != nil && e.DeptID != *q.DeptID:
+		return false
+	case q.SalaryMin != nil && e.Salary < *q.SalaryMin:
+		return false
+	case q.SalaryMax != nil && e.Salary > *q.SalaryMax:
+		return false
+	case q.Name != "" && !strings.Contains(strings.ToLower(e.Name), strings.ToLower(q.Name)):
+		return false
+	}
+	return true
+}
+
+// applyEmployeeQuery фильтрует, сортирует и нарезает список в памяти.
+// Возвращает страницу и общее число записей, удовлетворяющих фильтрам.
+func applyEmployeeQuery(all []Employee, q EmployeeQuery) ([]Employee, int) {
+	var matched []Employee
+	for _, e := range all {
+		if matchEmployee(e, q) {
+			matched = append(matched, e)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return employeeLess(matched[i], matched[j], q) })
+	total := len(matched)
+
+	start := q.Offset
+	if q.Cursor != nil {
+		last := Employee{ID: q.Cursor.ID, Name: q.Cursor.Name, Salary: q.Cursor.Salary}
+		start = sort.Search(len(matched), func(i int) bool { return employeeLess(last, matched[i], q) })
+	}
+	if start > len(matched) {
+		start = len(matched)
+	}
+	page := matched[start:]
+	if q.Limit > 0 && len(page) > q.Limit {
+		page = page[:q.Limit]
+	}
+	return page, total
+}
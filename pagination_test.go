@@ -0,0 +1,148 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEmployeeCursorRoundTrip(t *testing.T) {
+	q := EmployeeQuery{Sort: sortByName, Desc: true}
+	cur := employeeCursor{Sort: sortByName, Desc: true, ID: 42, Name: "Иванов"}
+
+	got, err := decodeEmployeeCursor(cur.encode(), q)
+	if err != nil || *got != cur {
+		t.Fatalf("курсор %+v: %v", got, err)
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"другое направление", employeeCursor{Sort: sortByName, ID: 42}.encode()},
+		{"другое поле", employeeCursor{Sort: sortBySalary, Desc: true, ID: 42}.encode()},
+		{"не base64", "!!!"},
+		{"не JSON", "bm90LWpzb24"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeEmployeeCursor(tt.cursor, q); err == nil {
+				t.Fatal("курсор принят")
+			}
+		})
+	}
+}
+
+func TestPageMetaCursor(t *testing.T) {
+	page := []Employee{{ID: 1, Name: "А", Salary: 10}, {ID: 2, Name: "Б", Salary: 20}}
+
+	tests := []struct {
+		name   string
+		total  int
+		q      EmployeeQuery
+		cursor bool
+	}{
+		{"есть следующая страница", 5, EmployeeQuery{Limit: 2}, true},
+		{"последняя страница", 2, EmployeeQuery{Limit: 2}, false},
+		{"неполная страница", 5, EmployeeQuery{Limit: 3}, false},
+		{"без ограничения", 5, EmployeeQuery{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := pageMeta(page, tt.total, tt.q)
+			if (meta.NextCursor != "") != tt.cursor {
+				t.Fatalf("next_cursor = %q", meta.NextCursor)
+			}
+		})
+	}
+
+	// Курсор сортировки по имени не раскрывает зарплату
+	meta := pageMeta(page, 5, EmployeeQuery{Limit: 2, Sort: sortByName})
+	cur, err := decodeEmployeeCursor(meta.NextCursor, EmployeeQuery{Sort: sortByName})
+	if err != nil || cur.Salary != 0 || cur.Name != "Б" || cur.ID != 2 {
+		t.Fatalf("курсор %+v: %v", cur, err)
+	}
+}
+
+func TestApplyEmployeeQueryKeyset(t *testing.T) {
+	// Равные зарплаты: порядок внутри них — по ID, страницы не теряют и не повторяют записи
+	all := []Employee{
+		{ID: 1, Salary: 300}, {ID: 2, Salary: 100}, {ID: 3, Salary: 200},
+		{ID: 4, Salary: 100}, {ID: 5, Salary: 200}, {ID: 6, Salary: 100},
+	}
+
+	tests := []struct {
+		name string
+		desc bool
+		want []int
+	}{
+		{"по возрастанию", false, []int{2, 4, 6, 3, 5, 1}},
+		{"по убыванию", true, []int{1, 5, 3, 6, 4, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := EmployeeQuery{Sort: sortBySalary, Desc: tt.desc, Limit: 2}
+			var got []int
+			for range len(all) {
+				page, total := applyEmployeeQuery(all, q)
+				if total != len(all) {
+					t.Fatalf("total = %d", total)
+				}
+				for _, e := range page {
+					got = append(got, e.ID)
+				}
+				meta := pageMeta(page, total, q)
+				if meta.NextCursor == "" {
+					break
+				}
+				cur, err := decodeEmployeeCursor(meta.NextCursor, q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				q.Cursor = cur
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ID по страницам %v, ожидалось %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("ID по страницам %v, ожидалось %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseEmployeeQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		ok    bool
+		check func(EmployeeQuery) bool
+	}{
+		{"", true, func(q EmployeeQuery) bool { return q.Sort == sortByID && q.Limit == defaultPageLimit }},
+		{"sort=-salary", true, func(q EmployeeQuery) bool { return q.Sort == sortBySalary && q.Desc }},
+		{"sort=name&order=desc", true, func(q EmployeeQuery) bool { return q.Sort == sortByName && q.Desc }},
+		{"dept_id=3&status=active", true, func(q EmployeeQuery) bool { return *q.DeptID == 3 && q.Status == "active" }},
+		{"limit=0", false, nil},
+		{"limit=1001", false, nil},
+		{"offset=-1", false, nil},
+		{"sort=age", false, nil},
+		{"order=up", false, nil},
+		{"salary_min=10&salary_max=5", false, nil},
+		{"cursor=abc", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/api/employees?"+tt.query, nil)
+			q, err := parseEmployeeQuery(c)
+			if (err == nil) != tt.ok {
+				t.Fatalf("ошибка %v", err)
+			}
+			if tt.ok && !tt.check(q) {
+				t.Fatalf("запрос %+v", q)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/unidoc/unipdf/v4/creator"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// pdfFontFiles содержимое TTF-шрифтов из DocumentsConfig, читается один раз.
+// Встроенные шрифты PDF не содержат кириллицы, поэтому шрифт задаётся файлом.
+var pdfFontFiles = sync.OnceValues(func() ([2][]byte, error) {
+	var files [2][]byte
+	for i, path := range []string{documentSettings.PDFFont, documentSettings.PDFFontBold} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return files, fmt.Errorf("ошибка чтения шрифта PDF: %v", err)
+		}
+		files[i] = data
+	}
+	return files, nil
+})
+
+// loadPDFFonts обычный и полужирный шрифты для одного документа.
+// Шрифт хранит состояние кодировщика, поэтому у каждого документа свой экземпляр.
+func loadPDFFonts() (regular, bold *model.PdfFont, err error) {
+	files, err := pdfFontFiles()
+	if err != nil {
+		return nil, nil, err
+	}
+	if regular, err = model.NewCompositePdfFontFromTTF(bytes.NewReader(files[0])); err != nil {
+		return nil, nil, fmt.Errorf("ошибка загрузки шрифта %s: %v", documentSettings.PDFFont, err)
+	}
+	if bold, err = model.NewCompositePdfFontFromTTF(bytes.NewReader(files[1])); err != nil {
+		return nil, nil, fmt.Errorf("ошибка загрузки шрифта %s: %v", documentSettings.PDFFontBold, err)
+	}
+	return regular, bold, nil
+}
+
+// setupPDFDocument формирует PDF-отчёт по отделу с тем же содержимым и оформлением, что setupDocument
+func setupPDFDocument(report departmentReport, meta documentMeta) (bytes.Buffer, error) {
+	var (
+		backgroundDefault     = creator.ColorWhite
+		alternativeBackground = creator.ColorRGBFromHex("#E7E6E6")
+		backgroundHighlight   = creator.ColorRGBFromHex("#E6F0FA") // светло-синий фон для выделения руководителя
+
+		borderColor = creator.ColorRGBFromHex("#4F81BD")
+
+		textColorHead           = creator.ColorWhite
+		textColorHighlight      = creator.ColorRGBFromHex("#2E74B5")
+		textColorStat           = creator.ColorRGBFromHex("#888888")
+		textColorStatHeading    = creator.ColorRGBFromHex("#4F81BD")
+		textColorStatHighlight1 = creator.ColorRGBFromHex("#4CAF50")
+		textColorStatHighlight2 = creator.ColorRGBFromHex("#C00000")
+		watermarkOutline        = creator.ColorRGBFromHex("#" + watermarkColor)
+	)
+
+	regular, bold, err := loadPDFFonts()
+	if err != nil {
+		return bytes.Buffer{}, err
+	}
+
+	c := creator.New()
+	c.SetPageSize(creator.PageSizeA4)
+	c.SetPageMargins(50, 50, 50, 50)
+
+	paragraph := func(text string, font *model.PdfFont, size float64, col creator.Color) *creator.StyledParagraph {
+		p := c.NewStyledParagraph()
+		chunk := p.Append(text)
+		chunk.Style.Font = font
+		chunk.Style.FontSize = size
+		chunk.Style.Color = col
+		return p
+	}
+
+	// Колонтитулы: компания и отдел сверху, время, автор и номер страницы снизу
+	edgeLine := func(block *creator.Block, y float64, left, right string) {
+		for _, part := range []struct {
+			text  string
+			align creator.TextAlignment
+		}{
+			{left, creator.TextAlignmentLeft},
+			{right, creator.TextAlignmentRight},
+		} {
+			p := paragraph(part.text, regular, 8, textColorStat)
+			p.SetTextAlignment(part.align)
+			p.SetWidth(block.Width() - 100)
+			p.SetPos(50, y)
+			_ = block.Draw(p)
+		}
+	}
+	c.DrawHeader(func(block *creator.Block, args creator.HeaderFunctionArgs) {
+		edgeLine(block, 25, meta.Company, meta.Title)
+	})
+	c.DrawFooter(func(block *creator.Block, args creator.FooterFunctionArgs) {
+		edgeLine(block, 20,
+			fmt.Sprintf("Сформировано %s, %s", meta.GeneratedAt.Format("02.01.2006 15:04"), meta.GeneratedBy),
+			fmt.Sprintf("Страница %d из %d", args.PageNum, args.TotalPages))
+	})
+
+	// Водяной знак: контур текста по диагонали поверх страницы, чтобы не закрывать таблицу
+	if meta.Watermark != "" {
+		c.PageFinalize(func(args creator.PageFinalizeFunctionArgs) error {
+			p := c.NewStyledParagraph()
+			chunk := p.Append(meta.Watermark)
+			chunk.Style.Font = bold
+			chunk.Style.FontSize = 60
+			chunk.Style.RenderingMode = creator.TextRenderingModeStroke
+			chunk.Style.OutlineColor = watermarkOutline
+			chunk.Style.OutlineSize = 1
+			p.SetEnableWrap(false)
+			p.SetAngle(45)
+			p.SetPos(args.PageWidth/2-180, args.PageHeight/2+180)
+			return c.Draw(p)
+		})
+	}
+
+	// Заголовок
+	title := paragraph(fmt.Sprintf("Сотрудники отдела №%d", report.DeptID), bold, 18, textColorHighlight)
+	title.SetMargins(0, 0, 0, 12)
+	if err := c.Draw(title); err != nil {
+		return bytes.Buffer{}, fmt.Errorf("ошибка создания PDF: %v", err)
+	}
+
+	// Таблица: шапка повторяется на каждой странице
+	tbl := c.NewTable(4)
+	if err := tbl.SetColumnWidths(0.12, 0.48, 0.18, 0.22); err != nil {
+		return bytes.Buffer{}, fmt.Errorf("ошибка создания PDF: %v", err)
+	}
+	addCell := func(text string, font *model.PdfFont, size float64, textColor, background creator.Color, colspan int) error {
+		cell := tbl.MultiColCell(colspan)
+		cell.SetBorder(creator.CellBorderSideAll, creator.CellBorderStyleSingle, 1)
+		cell.SetBorderColor(borderColor)
+		cell.SetBackgroundColor(background)
+		cell.SetHorizontalAlignment(creator.CellHorizontalAlignmentCenter)
+		cell.SetVerticalAlignment(creator.CellVerticalAlignmentMiddle)
+		p := paragraph(text, font, size, textColor)
+		p.SetMargins(3, 3, 5, 5)
+		return cell.SetContent(p)
+	}
+
+	for _, txt := range []string{"ID", "Имя", "Статус", "Оклад"} {
+		if err := addCell(txt, bold, 12, textColorHead, borderColor, 1); err != nil {
+			return bytes.Buffer{}, fmt.Errorf("ошибка создания PDF: %v", err)
+		}
+	}
+	if err := tbl.SetHeaderRows(1, 1); err != nil {
+		return bytes.Buffer{}, fmt.Errorf("ошибка создания PDF: %v", err)
+	}
+
+	// Строки данных: чередуем фон и выделяем босса
+	for i, e := range report.Employees {
+		font, textColor, background := regular, creator.Color(creator.ColorBlack), backgroundDefault
+		if report.BossID.Valid && int(report.BossID.Int64) == e.ID {
+			font, textColor, background = bold, textColorHighlight, backgroundHighlight
+		} else if i%2 == 1 {
+			background = alternativeBackground
+		}
+		for _, txt := range []string{strconv.Itoa(e.ID), e.Name, e.Status, report.FormatSalary(e.Salary)} {
+			if err := addCell(txt, font, 10, textColor, background, 1); err != nil {
+				return bytes.Buffer{}, fmt.Errorf("ошибка создания PDF: %v", err)
+			}
+		}
+	}
+	// Пустой отдел: одна строка-пояснение на всю ширину таблицы
+	if len(report.Employees) == 0 {
+		if err := addCell("В отделе нет сотрудников", regular, 10, textColorStat, backgroundDefault, 4); err != nil {
+			return bytes.Buffer{}, fmt.Errorf("ошибка создания PDF: %v", err)
+		}
+	}
+	tbl.SetMargins(0, 0, 0, 16)
+	if err := c.Draw(tbl); err != nil {
+		return bytes.Buffer{}, fmt.Errorf("ошибка создания PDF: %v", err)
+	}
+
+	// Статистика: ожидаемые значения из ОТДЕЛЫ и фактические из СОТРУДНИКИ
+	heading := paragraph("Статистика по отделу", bold, 14, textColorStatHeading)
+	heading.SetMargins(0, 0, 0, 4)
+	blocks := []creator.Drawable{heading}
+	for _, group := range []struct {
+		caption string
+		salary  float64
+		count   int
+		color   creator.Color
+	}{
+		{"Ожидаемые (по таблице ОТДЕЛЫ):", report.ExpectedSalary, report.ExpectedCount, textColorStatHighlight1},
+		{"Фактические (по таблице СОТРУДНИКИ):", report.ActualSalary, report.ActualCount, textColorStatHighlight2},
+	} {
+		caption := paragraph(group.caption, regular, 11, textColorStat)
+		caption.SetMargins(0, 0, 4, 2)
+		blocks = append(blocks, caption)
+		for _, text := range []string{
+			"Суммарная зарплата: " + report.FormatSalary(group.salary),
+			fmt.Sprintf("Количество сотрудников: %d", group.count),
+		} {
+			item := paragraph("•  "+text, regular, 10, group.color)
+			item.SetMargins(21, 0, 2, 2)
+			blocks = append(blocks, item)
+		}
+	}
+	for _, b := range blocks {
+		if err := c.Draw(b); err != nil {
+			return bytes.Buffer{}, fmt.Errorf("ошибка создания PDF: %v", err)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := c.Write(buf); err != nil {
+		return bytes.Buffer{}, fmt.Errorf("ошибка сохранения PDF: %v", err)
+	}
+	return *buf, nil
+}
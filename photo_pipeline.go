@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Обработка загружаемых фото сотрудников: формат определяется по содержимому,
+// изображение поворачивается по EXIF, обрезается до квадрата по центру
+// и перекодируется в JPEG (метаданные, в том числе GPS, не переносятся).
+
+// Ограничения загружаемых фото
+const (
+	maxPhotoSize     = 10 << 20   // байт
+	maxPhotoPixels   = 40_000_000 // защита от «бомб»: декодирование требует ~4 байта на пиксель
+	minPhotoSide     = 64         // пикселей по меньшей стороне
+	photoJPEGQuality = 88
+)
+
+// Размеры хранимых фото (?size=): сторона квадрата в пикселях
+const (
+	photoSizeThumb    = "thumb"
+	photoSizeMedium   = "medium"
+	photoSizeOriginal = "original" // исходное разрешение, но не больше 2048
+)
+
+var photoSizes = map[string]int{
+	photoSizeThumb:    128,
+	photoSizeMedium:   512,
+	photoSizeOriginal: 2048,
+}
+
+// photoContentTypes форматы, принимаемые по сигнатуре содержимого
+var photoContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+	"image/bmp":  true,
+}
+
+// errInvalidPhoto загруженный файл не подходит как фото сотрудника (HTTP 400)
+var errInvalidPhoto = errors.New("некорректное фото")
+
+// processedPhoto фото сотрудника, готовое к сохранению: JPEG на каждый размер
+type processedPhoto map[string][]byte
+
+// processUploadedPhoto проверяет и обрабатывает загруженное фото.
+// Ошибки содержимого оборачивают errInvalidPhoto.
+func processUploadedPhoto(fileHeader *multipart.FileHeader) (processedPhoto, error) {
+	if fileHeader.Size > maxPhotoSize {
+		return nil, fmt.Errorf("%w: файл больше %d МБ", errInvalidPhoto, maxPhotoSize>>20)
+	}
+	src, err := fileHeader.Open()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть загруженный файл: %v", err)
+	}
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, maxPhotoSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+	if len(data) > maxPhotoSize {
+		return nil, fmt.Errorf("%w: файл больше %d МБ", errInvalidPhoto, maxPhotoSize>>20)
+	}
+	return processPhoto(data)
+}
+
+// processPhoto декодирует изображение и готовит все размеры
+func processPhoto(data []byte) (processedPhoto, error) {
+	// 1) Формат — по содержимому, а не по имени файла
+	if ct := http.DetectContentType(data); !photoContentTypes[ct] {
+		return nil, fmt.Errorf("%w: неподдерживаемый формат %s (JPEG, PNG, GIF, WebP, BMP)", errInvalidPhoto, ct)
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidPhoto, err)
+	}
+	if cfg.Width*cfg.Height > maxPhotoPixels {
+		return nil, fmt.Errorf("%w: изображение больше %d Мп", errInvalidPhoto, maxPhotoPixels/1_000_000)
+	}
+	if min(cfg.Width, cfg.Height) < minPhotoSide {
+		return nil, fmt.Errorf("%w: изображение меньше %d×%d", errInvalidPhoto, minPhotoSide, minPhotoSide)
+	}
+
+	// 2) Декодируем; ориентация — из EXIF (только JPEG)
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidPhoto, err)
+	}
+	orientation := 1
+	if format == "jpeg" {
+		orientation = exifOrientation(data)
+	}
+
+	// 3) Квадрат по центру: обрезка не зависит от поворота, поэтому
+	// поворачиваем уже уменьшенное изображение
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	square := image.Rect(0, 0, side, side).Add(b.Min).Add(image.Pt((b.Dx()-side)/2, (b.Dy()-side)/2))
+
+	photo := processedPhoto{}
+	for size, limit := range photoSizes {
+		n := min(side, limit)
+		dst := image.NewRGBA(image.Rect(0, 0, n, n))
+		// Прозрачные области PNG/GIF — на белом фоне
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, square, draw.Over, nil)
+
+		buf := &bytes.Buffer{}
+		if err := jpeg.Encode(buf, orient(dst, orientation), &jpeg.Options{Quality: photoJPEGQuality}); err != nil {
+			return nil, fmt.Errorf("ошибка кодирования фото: %v", err)
+		}
+		photo[size] = buf.Bytes()
+	}
+	return photo, nil
+}
+
+// orient поворачивает квадратное изображение по EXIF Orientation (1–8)
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	n := img.Bounds().Dx()
+	dst := image.NewRGBA(img.Bounds())
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			// Точка источника для точки (x, y) результата
+			var sx, sy int
+			switch orientation {
+			case 2: // отражение по горизонтали
+				sx, sy = n-1-x, y
+			case 3: // поворот на 180°
+				sx, sy = n-1-x, n-1-y
+			case 4: // отражение по вертикали
+				sx, sy = x, n-1-y
+			case 5: // транспонирование
+				sx, sy = y, x
+			case 6: // поворот на 90° по часовой
+				sx, sy = y, n-1-x
+			case 7: // поперечное транспонирование
+				sx, sy = n-1-y, n-1-x
+			case 8: // поворот на 90° против часовой
+				sx, sy = n-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], img.Pix[img.PixOffset(sx, sy):img.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+// exifOrientation тег Orientation (0x0112) из EXIF JPEG; 1, если его нет
+func exifOrientation(data []byte) int {
+	// Маркеры JPEG до начала данных изображения (SOS)
+	for i := 2; i+4 <= len(data) && data[0] == 0xFF && data[1] == 0xD8; {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation Orientation из IFD0 заголовка TIFF внутри EXIF
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for k := 0; k < count; k++ {
+		entry := ifd + 2 + k*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if v := int(order.Uint16(tiff[entry+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// withEXIFOrientation вставляет в JPEG сегмент APP1 с тегом Orientation
+func withEXIFOrientation(data []byte, orientation uint16, order binary.ByteOrder) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8) // IFD0 сразу после заголовка
+	order.PutUint16(tiff[8:], 1) // одна запись
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+
+	out := append([]byte{}, data[:2]...) // SOI
+	out = append(out, app1...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+var (
+	testRed   = color.RGBA{R: 255, A: 255}
+	testGreen = color.RGBA{G: 255, A: 255}
+	testBlue  = color.RGBA{B: 255, A: 255}
+)
+
+// quadrantsJPEG квадратный JPEG без симметрий: верхняя левая четверть красная,
+// верхняя правая зелёная, нижняя половина синяя
+func quadrantsJPEG(t *testing.T, side int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, side, side))
+	for y := 0; y < side; y++ {
+		for x := 0; x < side; x++ {
+			switch {
+			case y >= side/2:
+				img.Set(x, y, testBlue)
+			case x < side/2:
+				img.Set(x, y, testRed)
+			default:
+				img.Set(x, y, testGreen)
+			}
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestEXIFOrientation(t *testing.T) {
+	plain := quadrantsJPEG(t, 64)
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"без EXIF", plain, 1},
+		{"Intel, поворот на 90°", withEXIFOrientation(plain, 6, binary.LittleEndian), 6},
+		{"Motorola, поворот на 180°", withEXIFOrientation(plain, 3, binary.BigEndian), 3},
+		{"значение вне 1–8", withEXIFOrientation(plain, 9, binary.LittleEndian), 1},
+		{"обрезанный файл", withEXIFOrientation(plain, 6, binary.LittleEndian)[:20], 1},
+		{"не JPEG", []byte("\x89PNG\r\n\x1a\n"), 1},
+		{"пустой", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exifOrientation(tt.data); got != tt.want {
+				t.Fatalf("exifOrientation = %d, ожидалось %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessPhotoOrientation(t *testing.T) {
+	r, g, b := testRed, testGreen, testBlue
+
+	// Цвета углов после поворота: верхний левый, верхний правый, нижний левый, нижний правый
+	tests := []struct {
+		orientation uint16
+		corners     [4]color.RGBA
+	}{
+		{1, [4]color.RGBA{r, g, b, b}},
+		{2, [4]color.RGBA{g, r, b, b}}, // отражение по горизонтали
+		{3, [4]color.RGBA{b, b, g, r}}, // поворот на 180°
+		{4, [4]color.RGBA{b, b, r, g}}, // отражение по вертикали
+		{5, [4]color.RGBA{r, b, g, b}}, // транспонирование
+		{6, [4]color.RGBA{b, r, b, g}}, // поворот на 90° по часовой
+		{7, [4]color.RGBA{b, g, b, r}}, // поперечное транспонирование
+		{8, [4]color.RGBA{g, b, r, b}}, // поворот на 90° против часовой
+	}
+	for _, tt := range tests {
+		data := withEXIFOrientation(quadrantsJPEG(t, 128), tt.orientation, binary.LittleEndian)
+		photo, err := processPhoto(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := jpeg.Decode(bytes.NewReader(photo[photoSizeOriginal]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bounds := img.Bounds()
+		points := [4]image.Point{
+			{bounds.Min.X + 8, bounds.Min.Y + 8},
+			{bounds.Max.X - 8, bounds.Min.Y + 8},
+			{bounds.Min.X + 8, bounds.Max.Y - 8},
+			{bounds.Max.X - 8, bounds.Max.Y - 8},
+		}
+		for i, p := range points {
+			if got := img.At(p.X, p.Y); !similarColor(got, tt.corners[i]) {
+				t.Errorf("ориентация %d: угол %v цвета %v, ожидался %v", tt.orientation, p, got, tt.corners[i])
+			}
+		}
+	}
+}
+
+func TestProcessPhotoSizes(t *testing.T) {
+	// PNG 600×300 с прозрачной левой половиной
+	img := image.NewNRGBA(image.Rect(0, 0, 600, 300))
+	for y := 0; y < 300; y++ {
+		for x := 300; x < 600; x++ {
+			img.Set(x, y, color.NRGBA{G: 255, A: 255})
+		}
+	}
+	buf := &bytes.Buffer{}
+	png.Encode(buf, img)
+
+	photo, err := processPhoto(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Квадрат по центру 300×300; размеры не больше исходного
+	for size, want := range map[string]int{photoSizeThumb: 128, photoSizeMedium: 300, photoSizeOriginal: 300} {
+		out, err := jpeg.Decode(bytes.NewReader(photo[size]))
+		if err != nil {
+			t.Fatalf("%s: %v", size, err)
+		}
+		if b := out.Bounds(); b.Dx() != want || b.Dy() != want {
+			t.Fatalf("%s: %v, ожидалось %d×%d", size, b, want, want)
+		}
+		if size == photoSizeOriginal && !similarColor(out.At(10, 150), color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+			t.Fatalf("прозрачная область %v, ожидался белый", out.At(10, 150))
+		}
+	}
+}
+
+func TestProcessPhotoRejects(t *testing.T) {
+	small := &bytes.Buffer{}
+	png.Encode(small, image.NewRGBA(image.Rect(0, 0, 32, 200)))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"текст", []byte("not an image at all")},
+		{"меньше 64 пикселей", small.Bytes()},
+		{"обрезанный JPEG", quadrantsJPEG(t, 128)[:100]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := processPhoto(tt.data); !errors.Is(err, errInvalidPhoto) {
+				t.Fatalf("ошибка %v, ожидалась errInvalidPhoto", err)
+			}
+		})
+	}
+}
+
+// similarColor цвета совпадают с точностью до потерь JPEG
+func similarColor(a color.Color, b color.RGBA) bool {
+	r, g, bl, _ := a.RGBA()
+	near := func(v uint32, want uint8) bool {
+		d := int(v>>8) - int(want)
+		return d > -40 && d < 40
+	}
+	return near(r, b.R) && near(g, b.G) && near(bl, b.B)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/unidoc/unioffice/v2/common/license"
+	pdflicense "github.com/unidoc/unipdf/v4/common/license"
+)
+
+// errRenderUnavailable формат не поддерживается текущим рендерером (HTTP 503)
+var errRenderUnavailable = errors.New("формат недоступен")
+
+// Причины errRenderUnavailable
+var (
+	errNoUnidoc   = fmt.Errorf("%w: лицензия unidoc не активирована (documents.renderer)", errRenderUnavailable)
+	errNoPDFFonts = fmt.Errorf("%w: не найдены шрифты PDF (documents.pdf_font, documents.pdf_font_bold)", errRenderUnavailable)
+)
+
+// DocumentRenderer формирует выгружаемые документы отчётов.
+// unidocRenderer — полная реализация на unioffice/unipdf, требует лицензию;
+// ooxmlRenderer — запись DOCX/XLSX без сторонних библиотек, PDF и шаблоны
+// ему недоступны (errRenderUnavailable).
+type DocumentRenderer interface {
+	Name() string
+	DepartmentDOCX(report departmentReport, meta documentMeta) (bytes.Buffer, error)
+	DepartmentPDF(report departmentReport, meta documentMeta) (bytes.Buffer, error)
+	DepartmentsXLSX(reports []departmentReport, meta documentMeta) (bytes.Buffer, error)
+	CompanyDOCX(reports []departmentReport, meta documentMeta) (bytes.Buffer, error)
+	// TemplateDOCX заполняет загруженный шаблон отчёта
+	TemplateDOCX(content []byte, report departmentReport, meta documentMeta) (bytes.Buffer, error)
+	ValidateTemplate(content []byte) error
+}
+
+// documentRenderer рендерер, выбранный при запуске (setupDocumentRenderer)
+var documentRenderer DocumentRenderer = ooxmlRenderer{}
+
+// Рендереры (documents.renderer)
+const (
+	rendererAuto   = "auto"   // unidoc, если лицензия активирована, иначе ooxml
+	rendererUnidoc = "unidoc" // только unidoc; без лицензии сервер не запускается
+	rendererOOXML  = "ooxml"  // только встроенная запись OOXML
+)
+
+// setupDocumentRenderer выбирает рендерер по documents.renderer.
+// В режиме auto ошибка лицензии не мешает запуску: документы
+// формируются встроенным ooxmlRenderer. Шрифты PDF проверяются только
+// для unidoc; в режиме auto без них отключается только PDF.
+func setupDocumentRenderer(cfg Config) (DocumentRenderer, error) {
+	switch cfg.Documents.Renderer {
+	case rendererOOXML:
+		return ooxmlRenderer{}, nil
+	case rendererUnidoc:
+		if err := activateUnidoc(cfg.Unidoc.Key); err != nil {
+			return nil, err
+		}
+		if err := checkPDFFonts(cfg.Documents); err != nil {
+			return nil, err
+		}
+		return unidocRenderer{pdf: true}, nil
+	}
+
+	if cfg.Unidoc.Key == "" {
+		log.Println("unidoc.key не задан: документы формируются без unidoc, PDF и шаблоны недоступны")
+		return ooxmlRenderer{}, nil
+	}
+	if err := activateUnidoc(cfg.Unidoc.Key); err != nil {
+		log.Printf("%v: документы формируются без unidoc, PDF и шаблоны недоступны", err)
+		return ooxmlRenderer{}, nil
+	}
+	if err := checkPDFFonts(cfg.Documents); err != nil {
+		log.Printf("%v: PDF недоступен", err)
+		return unidocRenderer{pdf: false}, nil
+	}
+	return unidocRenderer{pdf: true}, nil
+}
+
+// checkPDFFonts проверяет, что файлы шрифтов PDF заданы и читаются
+func checkPDFFonts(cfg DocumentsConfig) error {
+	var errs []error
+	for _, f := range []struct{ field, path string }{
+		{"documents.pdf_font", cfg.PDFFont},
+		{"documents.pdf_font_bold", cfg.PDFFontBold},
+	} {
+		if f.path == "" {
+			errs = append(errs, fmt.Errorf("%s: не задан", f.field))
+		} else if _, err := os.Stat(f.path); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", f.field, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// activateUnidoc загружает API-ключ unidoc: один ключ для unioffice (DOCX) и unipdf (PDF)
+func activateUnidoc(key string) error {
+	if err := license.SetMeteredKey(key); err != nil {
+		return fmt.Errorf("ошибка установки лицензии unidoc: %v", err)
+	}
+	if err := pdflicense.SetMeteredKey(key); err != nil {
+		return fmt.Errorf("ошибка установки лицензии unipdf: %v", err)
+	}
+	return nil
+}
+
+// unidocRenderer документы средствами unioffice и unipdf
+type unidocRenderer struct {
+	pdf bool // шрифты PDF найдены (checkPDFFonts)
+}
+
+func (unidocRenderer) Name() string { return rendererUnidoc }
+
+func (unidocRenderer) DepartmentDOCX(report departmentReport, meta documentMeta) (bytes.Buffer, error) {
+	return setupDocument(report, meta)
+}
+
+func (r unidocRenderer) DepartmentPDF(report departmentReport, meta documentMeta) (bytes.Buffer, error) {
+	if !r.pdf {
+		return bytes.Buffer{}, errNoPDFFonts
+	}
+	return setupPDFDocument(report, meta)
+}
+
+func (unidocRenderer) DepartmentsXLSX(reports []departmentReport, meta documentMeta) (bytes.Buffer, error) {
+	return setupSpreadsheet(reports, meta)
+}
+
+func (unidocRenderer) CompanyDOCX(reports []departmentReport, meta documentMeta) (bytes.Buffer, error) {
+	return setupCompanyDocument(reports, meta)
+}
+
+func (unidocRenderer) TemplateDOCX(content []byte, report departmentReport, meta documentMeta) (bytes.Buffer, error) {
+	return fillReportTemplate(content, report, meta)
+}
+
+func (unidocRenderer) ValidateTemplate(content []byte) error {
+	return validateReportTemplate(content)
+}
+
+// ooxmlRenderer документы без лицензии: DOCX и XLSX пишутся напрямую (ooxml.go)
+type ooxmlRenderer struct{}
+
+func (ooxmlRenderer) Name() string { return rendererOOXML }
+
+func (ooxmlRenderer) DepartmentDOCX(report departmentReport, meta documentMeta) (bytes.Buffer, error) {
+	return setupOOXMLDocument(report, meta)
+}
+
+func (ooxmlRenderer) DepartmentPDF(departmentReport, documentMeta) (bytes.Buffer, error) {
+	return bytes.Buffer{}, errNoUnidoc
+}
+
+func (ooxmlRenderer) DepartmentsXLSX(reports []departmentReport, meta documentMeta) (bytes.Buffer, error) {
+	book := &ooxmlWorkbook{}
+	layoutSpreadsheet(book, reports, meta)
+	return book.save()
+}
+
+func (ooxmlRenderer) CompanyDOCX(reports []departmentReport, meta documentMeta) (bytes.Buffer, error) {
+	return setupOOXMLCompanyDocument(reports, meta)
+}
+
+func (ooxmlRenderer) TemplateDOCX([]byte, departmentReport, documentMeta) (bytes.Buffer, error) {
+	return bytes.Buffer{}, errNoUnidoc
+}
+
+func (ooxmlRenderer) ValidateTemplate([]byte) error {
+	return errNoUnidoc
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Асинхронное формирование отчётов: POST /api/reports ставит задание в очередь,
+// ограниченный пул воркеров формирует документ, а результат хранится в BlobStore
+// до истечения reports.ttl, но не больше reports.max_storage_mb в сумме.
+// Состояние заданий хранится в памяти процесса: после перезапуска задания теряются,
+// а несколько экземпляров API не видят задания друг друга.
+// Синхронные эндпоинты отчётов используют те же reportTask.
+
+// Виды отчётов (ReportJob.Kind)
+const (
+	reportKindDepartment = "department" // отчёт по отделу, как /employeesByDepart/:id/document
+	reportKindCompany    = "company"    // сводный отчёт, как /reports/company
+	reportKindExport     = "export"     // выгрузка отделов в XLSX, как /departments/export
+)
+
+// Состояния задания (ReportJob.Status)
+const (
+	reportJobQueued  = "queued"
+	reportJobRunning = "running"
+	reportJobDone    = "done"
+	reportJobFailed  = "failed"
+)
+
+// Интервал удаления просроченных заданий
+const reportJobsCleanupInterval = time.Minute
+
+// errReportQueueFull очередь заданий заполнена (HTTP 503)
+var errReportQueueFull = errors.New("очередь формирования отчётов заполнена, повторите запрос позже")
+
+// reportTask проверенный запрос отчёта: отделы, права на зарплаты и оформление
+// фиксируются в момент запроса, документ формируется позже (run)
+type reportTask struct {
+	kind        string
+	filename    string
+	contentType string
+	departments []Department // в порядке вывода
+	photos      bool         // фото сотрудников в отчёте (attachPhotos)
+	visibility  salaryVisibility
+	meta        documentMeta
+	render      func([]departmentReport, documentMeta) (bytes.Buffer, error)
+}
+
+// run собирает отчёты отделов и формирует документ.
+// progress (может быть nil) вызывается после каждого отдела.
+func (t reportTask) run(ctx context.Context, employees EmployeeRepository, progress func(done, total int)) (bytes.Buffer, error) {
+	reports := make([]departmentReport, 0, len(t.departments))
+	for i, d := range t.departments {
+		report, err := buildDepartmentReport(ctx, employees, d, t.visibility)
+		if err != nil {
+			return bytes.Buffer{}, err
+		}
+		if t.photos {
+			if err := attachPhotos(ctx, &report); err != nil {
+				return bytes.Buffer{}, err
+			}
+		}
+		reports = append(reports, report)
+		if progress != nil {
+			// Последний шаг — сам документ
+			progress(i+1, len(t.departments)+1)
+		}
+	}
+	return t.render(reports, t.meta)
+}
+
+// reportJobs очередь и хранилище заданий на формирование отчётов
+type reportJobs struct {
+	employees EmployeeRepository
+	store     BlobStore // готовые документы (newReportStore)
+	ttl       time.Duration
+	maxBytes  int64       // предел суммарного размера готовых документов
+	queue     chan string // ID заданий в порядке постановки
+
+	mu    sync.Mutex
+	jobs  map[string]*ReportJob
+	tasks map[string]reportTask // ещё не выполненные задания
+	used  int64                 // байт готовых документов в store, включая сохраняемые
+}
+
+// newReportJobs создаёт очередь на cfg.Queue заданий; воркеры запускает start
+func newReportJobs(employees EmployeeRepository, store BlobStore, cfg ReportsConfig) *reportJobs {
+	return &reportJobs{
+		employees: employees,
+		store:     store,
+		ttl:       cfg.TTL,
+		maxBytes:  int64(cfg.MaxStorageMB) << 20,
+		queue:     make(chan string, cfg.Queue),
+		jobs:      map[string]*ReportJob{},
+		tasks:     map[string]reportTask{},
+	}
+}
+
+// start запускает workers воркеров и удаление просроченных заданий до отмены ctx
+func (j *reportJobs) start(ctx context.Context, workers int) {
+	for range workers {
+		go j.worker(ctx)
+	}
+	go j.cleanup(ctx)
+}
+
+// submit ставит задание в очередь; errReportQueueFull, если мест нет
+func (j *reportJobs) submit(task reportTask) (ReportJob, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return ReportJob{}, err
+	}
+	job := &ReportJob{
+		ID:        hex.EncodeToString(raw),
+		Kind:      task.kind,
+		Status:    reportJobQueued,
+		Filename:  task.filename,
+		CreatedBy: task.meta.GeneratedBy,
+		CreatedAt: time.Now(),
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	select {
+	case j.queue <- job.ID:
+	default:
+		return ReportJob{}, errReportQueueFull
+	}
+	// Воркер не увидит задание раньше: ему нужен тот же мьютекс
+	j.jobs[job.ID] = job
+	j.tasks[job.ID] = task
+	return *job, nil
+}
+
+// get копия задания id
+func (j *reportJobs) get(id string) (ReportJob, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job, ok := j.jobs[id]
+	if !ok {
+		return ReportJob{}, false
+	}
+	return *job, true
+}
+
+// update меняет задание id под мьютексом
+func (j *reportJobs) update(id string, change func(job *ReportJob)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if job, ok := j.jobs[id]; ok {
+		change(job)
+	}
+}
+
+// worker выполняет задания из очереди по одному
+func (j *reportJobs) worker(ctx context.Context) {
+	for {
+		var id string
+		select {
+		case <-ctx.Done():
+			return
+		case id = <-j.queue:
+		}
+
+		j.mu.Lock()
+		task := j.tasks[id]
+		delete(j.tasks, id)
+		j.mu.Unlock()
+
+		started := time.Now()
+		j.update(id, func(job *ReportJob) {
+			job.Status = reportJobRunning
+			job.StartedAt = &started
+		})
+
+		buf, err := j.run(ctx, id, task)
+		key := ""
+		if err == nil {
+			key, err = j.save(ctx, id, task.contentType, buf.Bytes())
+		}
+
+		finished := time.Now()
+		expires := finished.Add(j.ttl)
+		j.update(id, func(job *ReportJob) {
+			job.FinishedAt, job.ExpiresAt = &finished, &expires
+			if err != nil {
+				job.Status, job.Error = reportJobFailed, err.Error()
+				return
+			}
+			job.Status, job.Progress = reportJobDone, 100
+			job.contentType, job.key, job.Size = task.contentType, key, buf.Len()
+		})
+		if err != nil {
+			log.Printf("Ошибка формирования отчёта %s (%s): %v", id, task.kind, err)
+		}
+	}
+}
+
+// run формирует документ задания; паника рендерера завершает только это задание
+func (j *reportJobs) run(ctx context.Context, id string, task reportTask) (buf bytes.Buffer, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("ошибка создания документа: %v", r)
+		}
+	}()
+	buf, err = task.run(ctx, j.employees, func(done, total int) {
+		j.update(id, func(job *ReportJob) { job.Progress = done * 100 / total })
+	})
+	if err != nil && !errors.Is(err, errRenderUnavailable) {
+		err = fmt.Errorf("ошибка создания документа: %v", err)
+	}
+	return buf, err
+}
+
+// save сохраняет документ задания id в store. Если предел reports.max_storage_mb
+// превышен, сначала удаляются самые давно готовые отчёты.
+func (j *reportJobs) save(ctx context.Context, id, contentType string, data []byte) (string, error) {
+	size := int64(len(data))
+	if size > j.maxBytes {
+		return "", fmt.Errorf("отчёт больше reports.max_storage_mb (%d МБ)", j.maxBytes>>20)
+	}
+
+	j.mu.Lock()
+	var done []*ReportJob
+	for _, job := range j.jobs {
+		if job.key != "" {
+			done = append(done, job)
+		}
+	}
+	sort.Slice(done, func(a, b int) bool { return done[a].FinishedAt.Before(*done[b].FinishedAt) })
+	var evicted []*ReportJob
+	for _, job := range done {
+		if j.used+size <= j.maxBytes {
+			break
+		}
+		evicted = append(evicted, j.remove(job))
+	}
+	j.used += size // место занято до конца записи, чтобы параллельные воркеры его учли
+	j.mu.Unlock()
+
+	j.deleteContent(ctx, evicted)
+	key := "report_" + id
+	if err := j.store.Put(ctx, key, data, contentType); err != nil {
+		j.mu.Lock()
+		j.used -= size
+		j.mu.Unlock()
+		return "", fmt.Errorf("ошибка сохранения отчёта: %v", err)
+	}
+	return key, nil
+}
+
+// remove удаляет задание из списка и возвращает его; вызывается под мьютексом
+func (j *reportJobs) remove(job *ReportJob) *ReportJob {
+	delete(j.jobs, job.ID)
+	if job.key != "" {
+		j.used -= int64(job.Size)
+	}
+	return job
+}
+
+// deleteContent удаляет документы заданий из store
+func (j *reportJobs) deleteContent(ctx context.Context, jobs []*ReportJob) {
+	for _, job := range jobs {
+		if job.key == "" {
+			continue
+		}
+		if err := j.store.Delete(ctx, job.key); err != nil && !errors.Is(err, ErrNotFound) {
+			log.Printf("Ошибка удаления отчёта %s: %v", job.ID, err)
+		}
+	}
+}
+
+// open документ готового задания; ErrNotFound, если он удалён (срок или предел объёма)
+func (j *reportJobs) open(ctx context.Context, job ReportJob) (io.ReadCloser, BlobInfo, error) {
+	if job.key == "" {
+		return nil, BlobInfo{}, ErrNotFound
+	}
+	return j.store.Get(ctx, job.key)
+}
+
+// cleanup удаляет завершённые задания с истёкшим сроком хранения вместе с документами
+func (j *reportJobs) cleanup(ctx context.Context) {
+	ticker := time.NewTicker(reportJobsCleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			var expired []*ReportJob
+			j.mu.Lock()
+			for _, job := range j.jobs {
+				if job.ExpiresAt != nil && now.After(*job.ExpiresAt) {
+					expired = append(expired, j.remove(job))
+				}
+			}
+			j.mu.Unlock()
+			j.deleteContent(ctx, expired)
+		}
+	}
+}
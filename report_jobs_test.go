@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestReportJobs очередь с локальным хранилищем во временной директории; workers == 0 — без воркеров
+func newTestReportJobs(t *testing.T, repos Repositories, workers int, cfg ReportsConfig) *reportJobs {
+	t.Helper()
+	if cfg.Queue == 0 {
+		cfg.Queue = 10
+	}
+	if cfg.MaxStorageMB == 0 {
+		cfg.MaxStorageMB = 1
+	}
+	cfg.TTL = time.Hour
+	jobs := newReportJobs(repos.Employees, localBlobStore{dir: t.TempDir()}, cfg)
+	if workers > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		jobs.start(ctx, workers)
+	}
+	return jobs
+}
+
+// waitReportJob ждёт завершения задания id
+func waitReportJob(t *testing.T, jobs *reportJobs, id string) ReportJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, ok := jobs.get(id)
+		if !ok {
+			t.Fatalf("задание %s пропало", id)
+		}
+		if job.Status == reportJobDone || job.Status == reportJobFailed {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("задание %s не завершилось: %+v", id, job)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// textReportTask задание, которое выводит имена сотрудников отделов по строке
+func textReportTask(departments []Department) reportTask {
+	return reportTask{
+		kind:        reportKindCompany,
+		filename:    "report.txt",
+		contentType: "text/plain",
+		departments: departments,
+		visibility:  salaryVisibility{all: true},
+		meta:        documentMeta{GeneratedBy: "anna"},
+		render: func(reports []departmentReport, meta documentMeta) (bytes.Buffer, error) {
+			var buf bytes.Buffer
+			for _, r := range reports {
+				for _, e := range r.Employees {
+					buf.WriteString(e.Name + "\n")
+				}
+			}
+			return buf, nil
+		},
+	}
+}
+
+func TestReportJobWorker(t *testing.T) {
+	repos := newMemoryRepositories()
+	ctx := context.Background()
+	ids := seedDepartments(t, repos, "Первый", "Второй")
+	repos.Employees.Create(ctx, Employee{Name: "Анна", DeptID: ids[0]})
+	repos.Employees.Create(ctx, Employee{Name: "Борис", DeptID: ids[1]})
+	deps, _ := repos.Departments.List(ctx)
+	jobs := newTestReportJobs(t, repos, 2, ReportsConfig{})
+
+	queued, err := jobs.submit(textReportTask(deps))
+	if err != nil || queued.Status != reportJobQueued || queued.CreatedBy != "anna" {
+		t.Fatalf("задание %+v: %v", queued, err)
+	}
+	job := waitReportJob(t, jobs, queued.ID)
+	if job.Status != reportJobDone || job.Progress != 100 || job.ExpiresAt == nil {
+		t.Fatalf("задание %+v", job)
+	}
+
+	r, info, err := jobs.open(ctx, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, _ := io.ReadAll(r)
+	if string(data) != "Анна\nБорис\n" || info.Size != int64(job.Size) {
+		t.Fatalf("отчёт %q (%d байт, задание %d)", data, info.Size, job.Size)
+	}
+}
+
+func TestReportJobFailures(t *testing.T) {
+	repos := newMemoryRepositories()
+	deps := []Department{{ID: seedDepartments(t, repos, "Отдел")[0]}}
+	jobs := newTestReportJobs(t, repos, 1, ReportsConfig{MaxStorageMB: 1})
+
+	tests := []struct {
+		name   string
+		render func([]departmentReport, documentMeta) (bytes.Buffer, error)
+		errMsg string
+	}{
+		{"паника рендерера", func([]departmentReport, documentMeta) (bytes.Buffer, error) {
+			panic("сбой")
+		}, "сбой"},
+		{"ошибка рендерера", func([]departmentReport, documentMeta) (bytes.Buffer, error) {
+			return bytes.Buffer{}, errors.New("нет шрифта")
+		}, "нет шрифта"},
+		{"больше предела хранилища", func([]departmentReport, documentMeta) (bytes.Buffer, error) {
+			return *bytes.NewBuffer(make([]byte, 2<<20)), nil
+		}, "max_storage_mb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := textReportTask(deps)
+			task.render = tt.render
+			queued, err := jobs.submit(task)
+			if err != nil {
+				t.Fatal(err)
+			}
+			job := waitReportJob(t, jobs, queued.ID)
+			if job.Status != reportJobFailed || !strings.Contains(job.Error, tt.errMsg) {
+				t.Fatalf("задание %+v", job)
+			}
+			if _, _, err := jobs.open(context.Background(), job); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("у неудачного задания есть документ: %v", err)
+			}
+		})
+	}
+}
+
+func TestReportJobQueueFull(t *testing.T) {
+	repos := newMemoryRepositories()
+	jobs := newTestReportJobs(t, repos, 0, ReportsConfig{Queue: 1})
+	if _, err := jobs.submit(textReportTask(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jobs.submit(textReportTask(nil)); !errors.Is(err, errReportQueueFull) {
+		t.Fatalf("ошибка %v, ожидалась errReportQueueFull", err)
+	}
+}
+
+func TestReportJobStorageCap(t *testing.T) {
+	repos := newMemoryRepositories()
+	jobs := newTestReportJobs(t, repos, 0, ReportsConfig{MaxStorageMB: 1})
+	ctx := context.Background()
+
+	// Три отчёта по 400 КБ в 1 МБ не помещаются: удаляется самый старый
+	for i, id := range []string{"a", "b", "c"} {
+		key, err := jobs.save(ctx, id, "text/plain", make([]byte, 400<<10))
+		if err != nil {
+			t.Fatal(err)
+		}
+		finished := time.Now().Add(time.Duration(i) * time.Second)
+		jobs.mu.Lock()
+		jobs.jobs[id] = &ReportJob{ID: id, Status: reportJobDone, key: key, Size: 400 << 10, FinishedAt: &finished}
+		jobs.mu.Unlock()
+	}
+
+	if _, ok := jobs.get("a"); ok {
+		t.Fatal("самый старый отчёт не удалён")
+	}
+	if _, _, err := jobs.store.Get(ctx, "report_a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("документ удалённого задания остался в хранилище: %v", err)
+	}
+	for _, id := range []string{"b", "c"} {
+		job, ok := jobs.get(id)
+		if !ok {
+			t.Fatalf("задание %s удалено", id)
+		}
+		r, _, err := jobs.open(ctx, job)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Close()
+	}
+	if jobs.used != 800<<10 {
+		t.Fatalf("учтено %d байт, ожидалось %d", jobs.used, 800<<10)
+	}
+}
+
+func TestNewReportStoreRemovesStaleReports(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Reports.Dir = t.TempDir()
+	store, err := newReportStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	store.Put(ctx, "report_old", []byte("x"), "text/plain")
+	store.Put(ctx, "other", []byte("x"), "text/plain")
+
+	// Задания прошлого запуска потеряны, их документы никто не скачает
+	if _, err := newReportStore(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Stat(ctx, "report_old"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("старый отчёт не удалён: %v", err)
+	}
+	if _, err := store.Stat(ctx, "other"); err != nil {
+		t.Fatalf("удалён посторонний файл: %v", err)
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unioffice/v2/document"
+	"github.com/unidoc/unioffice/v2/schema/soo/wml"
+)
+
+// maxTemplateSize ограничение размера загружаемого шаблона
+const maxTemplateSize = 5 << 20 // байт
+
+// templateNamePattern допустимое имя шаблона (часть URL)
+var templateNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// placeholderPattern плейсхолдер шаблона: {{dept_name}}, {{ employee.salary }}
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([a-z_.]+)\s*\}\}`)
+
+// Плейсхолдеры шаблона отчёта по отделу. Поля employee.* допустимы только
+// в строке таблицы: такая строка повторяется для каждого сотрудника отдела.
+var (
+	templateFields = map[string]bool{
+		"dept_id":         true,
+		"dept_name":       true,
+		"boss_name":       true,
+		"expected_salary": true, // ОТДЕЛЫ.ОТД_СОТР_ЗАРП
+		"expected_count":  true, // ОТДЕЛЫ.ОТД_РАЗМ
+		"actual_salary":   true,
+		"actual_count":    true,
+		"generated_at":    true,
+		"generated_by":    true,
+		"company":         true,
+	}
+	templateRowFields = map[string]bool{
+		"employee.id":      true,
+		"employee.name":    true,
+		"employee.status":  true,
+		"employee.salary":  true,
+		"employee.is_boss": true,
+	}
+)
+
+// templateRowPrefix признак повторяемой строки таблицы
+const templateRowPrefix = "employee."
+
+// Пространства имён, которые могут встретиться внутри строки таблицы.
+// Нужны при копировании строки через encoding/xml (см. cloneTemplateRow).
+var templateRowNamespaces = []xml.Attr{
+	{Name: xml.Name{Local: "xmlns:w"}, Value: "http://schemas.openxmlformats.org/wordprocessingml/2006/main"},
+	{Name: xml.Name{Local: "xmlns:r"}, Value: "http://schemas.openxmlformats.org/officeDocument/2006/relationships"},
+	{Name: xml.Name{Local: "xmlns:wp"}, Value: "http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"},
+	{Name: xml.Name{Local: "xmlns:a"}, Value: "http://schemas.openxmlformats.org/drawingml/2006/main"},
+	{Name: xml.Name{Local: "xmlns:pic"}, Value: "http://schemas.openxmlformats.org/drawingml/2006/picture"},
+	{Name: xml.Name{Local: "xmlns:mc"}, Value: "http://schemas.openxmlformats.org/markup-compatibility/2006"},
+	{Name: xml.Name{Local: "xmlns:w14"}, Value: "http://schemas.microsoft.com/office/word/2010/wordml"},
+}
+
+// errTemplateNotFound ошибка для отсутствующего шаблона (version 0 — последняя версия)
+func errTemplateNotFound(name string, version int) error {
+	if version == 0 {
+		return fmt.Errorf("шаблон %q не найден", name)
+	}
+	return fmt.Errorf("шаблон %q версии %d не найден", name, version)
+}
+
+// templateValues значения плейсхолдеров уровня документа
+func templateValues(report departmentReport, meta documentMeta) map[string]string {
+	values := map[string]string{
+		"dept_id":         strconv.Itoa(report.DeptID),
+		"dept_name":       report.DeptName,
+		"boss_name":       "",
+		"expected_salary": report.FormatSalary(report.ExpectedSalary),
+		"expected_count":  strconv.Itoa(report.ExpectedCount),
+		"actual_salary":   report.FormatSalary(report.ActualSalary),
+		"actual_count":    strconv.Itoa(report.ActualCount),
+		"generated_at":    meta.GeneratedAt.Format("02.01.2006 15:04"),
+		"generated_by":    meta.GeneratedBy,
+		"company":         meta.Company,
+	}
+	for _, e := range report.Employees {
+		if report.BossID.Valid && int(report.BossID.Int64) == e.ID {
+			values["boss_name"] = e.Name
+		}
+	}
+	return values
+}
+
+// templateRowValues значения плейсхолдеров строки сотрудника e
+// (плюс плейсхолдеры уровня документа из values)
+func templateRowValues(report departmentReport, e emp, values map[string]string) map[string]string {
+	row := map[string]string{
+		"employee.id":      strconv.Itoa(e.ID),
+		"employee.name":    e.Name,
+		"employee.status":  e.Status,
+		"employee.salary":  report.FormatSalary(e.Salary),
+		"employee.is_boss": "",
+	}
+	if report.BossID.Valid && int(report.BossID.Int64) == e.ID {
+		row["employee.is_boss"] = "руководитель"
+	}
+	for k, v := range values {
+		row[k] = v
+	}
+	return row
+}
+
+// readTemplate открывает DOCX-шаблон
+func readTemplate(content []byte) (*document.Document, error) {
+	doc, err := document.Read(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("файл не является документом DOCX: %v", err)
+	}
+	return doc, nil
+}
+
+// validateReportTemplate проверяет, что шаблон открывается и содержит только
+// известные плейсхолдеры, а поля employee.* стоят в строках таблиц
+func validateReportTemplate(content []byte) error {
+	doc, err := readTemplate(content)
+	if err != nil {
+		return err
+	}
+	defer doc.Close()
+	return validateTemplateDocument(doc)
+}
+
+// validateTemplateDocument проверки validateReportTemplate для открытого шаблона
+func validateTemplateDocument(doc *document.Document) error {
+	inRows := map[*wml.CT_P]bool{}
+	for _, tbl := range doc.Tables() {
+		for _, row := range tbl.Rows() {
+			for _, p := range rowParagraphs(row) {
+				inRows[p.X()] = true
+			}
+		}
+	}
+
+	var unknown, outside []string
+	for _, p := range templateParagraphs(doc) {
+		for _, m := range placeholderPattern.FindAllStringSubmatch(paragraphText(p), -1) {
+			name := m[1]
+			switch {
+			case templateFields[name]:
+			case templateRowFields[name] && inRows[p.X()]:
+			case templateRowFields[name]:
+				outside = append(outside, name)
+			default:
+				unknown = append(unknown, name)
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("неизвестные плейсхолдеры: %s", strings.Join(uniqueSorted(unknown), ", "))
+	}
+	if len(outside) > 0 {
+		return fmt.Errorf("плейсхолдеры %s допустимы только в строке таблицы", strings.Join(uniqueSorted(outside), ", "))
+	}
+	return nil
+}
+
+// fillReportTemplate формирует DOCX-отчёт по отделу из шаблона content.
+// Колонтитулы задаёт шаблон; из meta добавляется только водяной знак.
+func fillReportTemplate(content []byte, report departmentReport, meta documentMeta) (bytes.Buffer, error) {
+	doc, err := readTemplate(content)
+	if err != nil {
+		return bytes.Buffer{}, err
+	}
+	defer doc.Close()
+
+	if err := fillTemplateDocument(doc, report, meta); err != nil {
+		return bytes.Buffer{}, err
+	}
+	if meta.Watermark != "" {
+		addWatermark(doc, meta.Watermark)
+	}
+	buf := &bytes.Buffer{}
+	if err := doc.Save(buf); err != nil {
+		return bytes.Buffer{}, fmt.Errorf("ошибка сохранения документа: %v", err)
+	}
+	return *buf, nil
+}
+
+// fillTemplateDocument заполняет открытый шаблон: строки таблиц с полями employee.*
+// повторяются для каждого сотрудника (в отделе без сотрудников удаляются),
+// остальные плейсхолдеры заменяются во всём документе, включая колонтитулы.
+func fillTemplateDocument(doc *document.Document, report departmentReport, meta documentMeta) error {
+	values := templateValues(report, meta)
+
+	// 1) Размножаем повторяемые строки; копия строки -> индекс сотрудника
+	clones := map[*wml.CT_Row]int{}
+	for _, tbl := range doc.Tables() {
+		templates := map[*wml.CT_Row]bool{}
+		for _, row := range tbl.Rows() {
+			if isTemplateRow(row) {
+				templates[row.X()] = true
+			}
+		}
+		if len(templates) == 0 {
+			continue
+		}
+		for _, group := range tbl.X().EG_ContentRowContent {
+			choice := group.ContentRowContentChoice
+			if choice == nil {
+				continue
+			}
+			rows := make([]*wml.CT_Row, 0, len(choice.Tr))
+			for _, tr := range choice.Tr {
+				if !templates[tr] {
+					rows = append(rows, tr)
+					continue
+				}
+				for i := range report.Employees {
+					clone, err := cloneTemplateRow(tr)
+					if err != nil {
+						return err
+					}
+					clones[clone] = i
+					rows = append(rows, clone)
+				}
+			}
+			choice.Tr = rows
+		}
+	}
+
+	// 2) Заполняем строки сотрудников; значения строки включают и поля документа,
+	// поэтому шаг 3 эти абзацы пропускает: иначе плейсхолдер в подставленном
+	// значении (например, имя «{{company}}») раскрылся бы повторно
+	filled := map[*wml.CT_P]bool{}
+	for _, tbl := range doc.Tables() {
+		for _, row := range tbl.Rows() {
+			i, ok := clones[row.X()]
+			if !ok {
+				continue
+			}
+			rowValues := templateRowValues(report, report.Employees[i], values)
+			for _, p := range rowParagraphs(row) {
+				fillParagraph(p, rowValues)
+				filled[p.X()] = true
+			}
+		}
+	}
+
+	// 3) Плейсхолдеры уровня документа
+	for _, p := range templateParagraphs(doc) {
+		if !filled[p.X()] {
+			fillParagraph(p, values)
+		}
+	}
+	return nil
+}
+
+// templateParagraphs абзацы тела документа (включая таблицы) и колонтитулов
+func templateParagraphs(doc *document.Document) []document.Paragraph {
+	paragraphs := doc.Paragraphs()
+	for _, h := range doc.Headers() {
+		paragraphs = append(paragraphs, h.Paragraphs()...)
+	}
+	for _, f := range doc.Footers() {
+		paragraphs = append(paragraphs, f.Paragraphs()...)
+	}
+	return paragraphs
+}
+
+// isTemplateRow строка таблицы с полями employee.*
+func isTemplateRow(row document.Row) bool {
+	for _, p := range rowParagraphs(row) {
+		for _, m := range placeholderPattern.FindAllStringSubmatch(paragraphText(p), -1) {
+			if strings.HasPrefix(m[1], templateRowPrefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rowParagraphs абзацы всех ячеек строки таблицы
+func rowParagraphs(row document.Row) []document.Paragraph {
+	var paragraphs []document.Paragraph
+	for _, cell := range row.Cells() {
+		paragraphs = append(paragraphs, cell.Paragraphs()...)
+	}
+	return paragraphs
+}
+
+// paragraphText текст абзаца
+func paragraphText(p document.Paragraph) string {
+	var sb strings.Builder
+	for _, r := range p.Runs() {
+		sb.WriteString(r.Text())
+	}
+	return sb.String()
+}
+
+// fillParagraph заменяет плейсхолдеры абзаца значениями values за один проход:
+// плейсхолдеры в подставленных значениях не раскрываются
+func fillParagraph(p document.Paragraph, values map[string]string) {
+	runs := p.Runs()
+	text := paragraphText(p)
+	if len(runs) == 0 || !placeholderPattern.MatchString(text) {
+		return
+	}
+
+	// Все плейсхолдеры внутри одного run: заменяем на месте, оформление сохраняется
+	inRuns := 0
+	for _, r := range runs {
+		inRuns += len(placeholderPattern.FindAllStringIndex(r.Text(), -1))
+	}
+	if inRuns == len(placeholderPattern.FindAllStringIndex(text, -1)) {
+		for _, r := range runs {
+			text := r.Text()
+			if replaced := replacePlaceholders(text, values); replaced != text {
+				r.ClearContent()
+				r.AddText(replaced)
+			}
+		}
+		return
+	}
+
+	// Word разбил плейсхолдер на несколько run (правка, проверка орфографии):
+	// собираем текст абзаца в первый run с его оформлением
+	runs[0].ClearContent()
+	runs[0].AddText(replacePlaceholders(text, values))
+	for _, r := range runs[1:] {
+		p.RemoveRun(r)
+	}
+}
+
+// replacePlaceholders подставляет значения; неизвестные плейсхолдеры остаются как есть
+func replacePlaceholders(text string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(m string) string {
+		if v, ok := values[placeholderPattern.FindStringSubmatch(m)[1]]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// cloneTemplateRow глубокая копия строки таблицы (через сериализацию XML)
+func cloneTemplateRow(tr *wml.CT_Row) (*wml.CT_Row, error) {
+	buf := &bytes.Buffer{}
+	enc := xml.NewEncoder(buf)
+	start := xml.StartElement{Name: xml.Name{Local: "w:tr"}, Attr: templateRowNamespaces}
+	if err := enc.EncodeElement(tr, start); err != nil {
+		return nil, fmt.Errorf("ошибка копирования строки шаблона: %v", err)
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, fmt.Errorf("ошибка копирования строки шаблона: %v", err)
+	}
+	clone := wml.NewCT_Row()
+	if err := xml.Unmarshal(buf.Bytes(), clone); err != nil {
+		return nil, fmt.Errorf("ошибка копирования строки шаблона: %v", err)
+	}
+	return clone, nil
+}
+
+// uniqueSorted уникальные строки по возрастанию
+func uniqueSorted(items []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, s := range items {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
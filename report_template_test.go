@@ -0,0 +1,248 @@
+package main
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unidoc/unioffice/v2/document"
+)
+
+// Шаблоны собираются в памяти: чтение и сохранение DOCX через unioffice
+// требуют лицензию, заполнение и проверка — нет
+
+// newTemplateDoc шаблон: заголовок, таблица с шапкой и строкой сотрудника
+// (имя жирным), верхний и нижний колонтитулы
+func newTemplateDoc(rowFields ...string) *document.Document {
+	doc := document.New()
+	doc.AddParagraph().AddRun().AddText("Отдел {{dept_name}} ({{ dept_id }}), руководитель {{boss_name}}")
+
+	tbl := doc.AddTable()
+	head := tbl.AddRow()
+	for _, txt := range []string{"Имя", "Оклад", "Роль"} {
+		head.AddCell().AddParagraph().AddRun().AddText(txt)
+	}
+	row := tbl.AddRow()
+	for i, field := range rowFields {
+		r := row.AddCell().AddParagraph().AddRun()
+		r.Properties().SetBold(i == 0)
+		r.AddText(field)
+	}
+
+	doc.AddHeader().AddParagraph().AddRun().AddText("{{company}}")
+	doc.AddFooter().AddParagraph().AddRun().AddText("Сформировано {{generated_at}}, {{generated_by}}")
+	return doc
+}
+
+// tableTexts текст ячеек первой таблицы по строкам
+func tableTexts(doc *document.Document) [][]string {
+	var rows [][]string
+	for _, row := range doc.Tables()[0].Rows() {
+		var cells []string
+		for _, cell := range row.Cells() {
+			var sb strings.Builder
+			for _, p := range cell.Paragraphs() {
+				sb.WriteString(paragraphText(p))
+			}
+			cells = append(cells, sb.String())
+		}
+		rows = append(rows, cells)
+	}
+	return rows
+}
+
+func templateTestReport() departmentReport {
+	return departmentReport{
+		DeptID:   3,
+		DeptName: "Склад",
+		BossID:   sql.NullInt64{Int64: 2, Valid: true},
+		Employees: []emp{
+			{ID: 1, Name: "Анна {{company}}", Salary: 1000},
+			{ID: 2, Name: "Борис", Salary: 2000},
+		},
+		SalariesVisible: true,
+		FormatSalary:    salaryVisibility{all: true}.formatter(3),
+	}
+}
+
+var templateTestMeta = documentMeta{
+	Company:     "ООО «Рога & Копыта»",
+	GeneratedAt: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+	GeneratedBy: "hr",
+}
+
+func TestFillTemplateDocument(t *testing.T) {
+	doc := newTemplateDoc("{{employee.name}}", "{{employee.salary}}", "{{ employee.is_boss }}")
+	if err := fillTemplateDocument(doc, templateTestReport(), templateTestMeta); err != nil {
+		t.Fatal(err)
+	}
+
+	// Строка сотрудника повторена для каждого; плейсхолдер в имени не раскрывается
+	want := [][]string{
+		{"Имя", "Оклад", "Роль"},
+		{"Анна {{company}}", "1000.00", ""},
+		{"Борис", "2000.00", "руководитель"},
+	}
+	if got := tableTexts(doc); !reflect.DeepEqual(got, want) {
+		t.Fatalf("таблица %q, ожидалась %q", got, want)
+	}
+	// Оформление строки шаблона сохраняется в копиях
+	for _, row := range doc.Tables()[0].Rows()[1:] {
+		runs := row.Cells()[0].Paragraphs()[0].Runs()
+		if len(runs) != 1 || !runs[0].Properties().IsBold() {
+			t.Errorf("имя сотрудника %q потеряло оформление", paragraphText(row.Cells()[0].Paragraphs()[0]))
+		}
+	}
+
+	if got := paragraphText(doc.Paragraphs()[0]); got != "Отдел Склад (3), руководитель Борис" {
+		t.Errorf("заголовок %q", got)
+	}
+	if got := paragraphText(doc.Headers()[0].Paragraphs()[0]); got != templateTestMeta.Company {
+		t.Errorf("верхний колонтитул %q", got)
+	}
+	if got := paragraphText(doc.Footers()[0].Paragraphs()[0]); got != "Сформировано 01.03.2024 10:00, hr" {
+		t.Errorf("нижний колонтитул %q", got)
+	}
+}
+
+func TestFillTemplateDocumentEmptyDepartment(t *testing.T) {
+	doc := newTemplateDoc("{{employee.name}}", "{{employee.salary}}")
+	report := templateTestReport()
+	report.Employees, report.BossID = nil, sql.NullInt64{}
+	if err := fillTemplateDocument(doc, report, templateTestMeta); err != nil {
+		t.Fatal(err)
+	}
+	if got := tableTexts(doc); !reflect.DeepEqual(got, [][]string{{"Имя", "Оклад", "Роль"}}) {
+		t.Fatalf("таблица %q: строка шаблона не удалена", got)
+	}
+}
+
+func TestFillTemplateMaskedSalaries(t *testing.T) {
+	doc := newTemplateDoc("{{employee.name}}", "{{employee.salary}}")
+	doc.AddParagraph().AddRun().AddText("Фонд {{actual_salary}}")
+	report := templateTestReport()
+	report.Employees[1].Salary, report.ActualSalary = 60_000, 61_000
+	report.SalariesVisible, report.FormatSalary = false, salaryVisibility{}.formatter(3)
+	if err := fillTemplateDocument(doc, report, templateTestMeta); err != nil {
+		t.Fatal(err)
+	}
+	if got := tableTexts(doc)[2][1]; got != "50k–75k" {
+		t.Errorf("оклад %q, ожидался диапазон", got)
+	}
+	found := false
+	for _, p := range doc.Paragraphs() {
+		found = found || paragraphText(p) == "Фонд 50k–75k"
+	}
+	if !found {
+		t.Error("фонд зарплат не заменён диапазоном")
+	}
+}
+
+func TestFillParagraph(t *testing.T) {
+	values := map[string]string{"dept_name": "Склад {{dept_id}}", "dept_id": "3"}
+	paragraph := func(runs ...string) document.Paragraph {
+		p := document.New().AddParagraph()
+		for i, txt := range runs {
+			r := p.AddRun()
+			r.Properties().SetBold(i == 0)
+			r.AddText(txt)
+		}
+		return p
+	}
+
+	tests := []struct {
+		name string
+		runs []string
+		want string
+		runN int // run после заполнения
+	}{
+		{"плейсхолдеры в своих run", []string{"{{dept_name}}", ": ", "{{dept_id}}"}, "Склад {{dept_id}}: 3", 3},
+		{"плейсхолдер разбит на run", []string{"Отдел {{dept_", "name}} №{{dept_id}}"}, "Отдел Склад {{dept_id}} №3", 1},
+		{"неизвестный плейсхолдер", []string{"{{unknown}} и {{dept_id}}"}, "{{unknown}} и 3", 1},
+		{"без плейсхолдеров", []string{"{{", "текст"}, "{{текст", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := paragraph(tt.runs...)
+			fillParagraph(p, values)
+			if got := paragraphText(p); got != tt.want {
+				t.Fatalf("текст %q, ожидался %q", got, tt.want)
+			}
+			runs := p.Runs()
+			if len(runs) != tt.runN || !runs[0].Properties().IsBold() {
+				t.Fatalf("run: %d, ожидалось %d с оформлением первого", len(runs), tt.runN)
+			}
+		})
+	}
+}
+
+func TestValidateTemplateDocument(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     func() *document.Document
+		wantErr string
+	}{
+		{
+			name: "корректный шаблон",
+			doc: func() *document.Document {
+				return newTemplateDoc("{{employee.name}}", "{{ employee.salary }}", "{{employee.is_boss}} {{company}}")
+			},
+		},
+		{
+			name: "неизвестные плейсхолдеры",
+			doc: func() *document.Document {
+				doc := newTemplateDoc("{{employee.name}}", "{{employee.phone}}")
+				doc.AddParagraph().AddRun().AddText("{{salary}} {{employee.phone}}")
+				return doc
+			},
+			wantErr: "неизвестные плейсхолдеры: employee.phone, salary",
+		},
+		{
+			name: "неизвестный плейсхолдер в колонтитуле",
+			doc: func() *document.Document {
+				doc := newTemplateDoc("{{employee.name}}")
+				doc.AddHeader().AddParagraph().AddRun().AddText("{{logo}}")
+				return doc
+			},
+			wantErr: "неизвестные плейсхолдеры: logo",
+		},
+		{
+			name: "поле сотрудника вне таблицы",
+			doc: func() *document.Document {
+				doc := newTemplateDoc("{{employee.name}}")
+				doc.AddParagraph().AddRun().AddText("Лучший: {{employee.name}}")
+				return doc
+			},
+			wantErr: "плейсхолдеры employee.name допустимы только в строке таблицы",
+		},
+		{
+			name: "поле сотрудника в колонтитуле",
+			doc: func() *document.Document {
+				doc := newTemplateDoc("{{employee.name}}")
+				doc.AddFooter().AddParagraph().AddRun().AddText("{{employee.id}}")
+				return doc
+			},
+			wantErr: "плейсхолдеры employee.id допустимы только в строке таблицы",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTemplateDocument(tt.doc())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("ошибка %v, ожидалась %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := validateReportTemplate([]byte("не docx")); err == nil || !strings.Contains(err.Error(), "не является документом DOCX") {
+		t.Fatalf("ошибка %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound возвращается репозиториями, если запись отсутствует
+var ErrNotFound = errors.New("запись не найдена")
+
+// ErrConflict возвращается, если операция противоречит текущему состоянию данных
+var ErrConflict = errors.New("конфликт данных")
+
+// EmployeeRepository доступ к сотрудникам (таблица СЛУЖАЩИЕ).
+// Реализации сами поддерживают агрегаты отдела (ОТД_РАЗМ, ОТД_СОТР_ЗАРП).
+type EmployeeRepository interface {
+	// List возвращает страницу сотрудников по фильтрам q и общее число подходящих записей
+	List(ctx context.Context, q EmployeeQuery) ([]Employee, int, error)
+	// Get возвращает сотрудника по ID или ErrNotFound
+	Get(ctx context.Context, id int) (Employee, error)
+	// ListByDepartment возвращает сотрудников отдела
+	ListByDepartment(ctx context.Context, deptID int) ([]Employee, error)
+	// SearchCandidates отбирает до limit сотрудников для нечёткого поиска (searchEmployees):
+	// с общими биграммами нормализованного имени и запроса, самых похожих первыми.
+	// Однобуквенные слова запроса ищутся по началу слов имени.
+	SearchCandidates(ctx context.Context, query string, limit int) ([]Employee, error)
+	// Create добавляет сотрудника и возвращает его ID
+	Create(ctx context.Context, e Employee) (int, error)
+	// CreateMany добавляет сотрудников одной транзакцией (все или ни одного) и возвращает их ID
+	CreateMany(ctx context.Context, emps []Employee) ([]int, error)
+	// Update сохраняет данные сотрудника и возвращает предыдущее состояние
+	Update(ctx context.Context, e Employee) (Employee, error)
+	// Delete удаляет сотрудника и возвращает удалённую запись
+	Delete(ctx context.Context, id int) (Employee, error)
+	// SetImageURL сохраняет имя файла фотографии (nil — сбросить)
+	SetImageURL(ctx context.Context, id int, filename *string) error
+}
+
+// DepartmentRepository доступ к отделам (таблица ОТДЕЛЫ)
+type DepartmentRepository interface {
+	// List возвращает все отделы с именем руководителя
+	List(ctx context.Context) ([]Department, error)
+	// Get возвращает отдел по ID или ErrNotFound
+	Get(ctx context.Context, id int) (Department, error)
+	// FirstID возвращает наименьший существующий ID отдела
+	FirstID(ctx context.Context) (int, error)
+	// Create добавляет пустой отдел и возвращает его ID
+	Create(ctx context.Context, d Department) (int, error)
+	// Update применяет частичное изменение отдела
+	Update(ctx context.Context, id int, patch DepartmentPatch) error
+	// Delete удаляет отдел, поступая с сотрудниками согласно policy
+	// (targetID — отдел для DeleteReassign). Возвращает затронутых сотрудников;
+	// при DeleteRefuse и непустом отделе возвращает ErrConflict.
+	Delete(ctx context.Context, id int, policy DepartmentDeletePolicy, targetID int) ([]Employee, error)
+	// Reconcile находит отделы, у которых ОТД_СОТР_ЗАРП/ОТД_РАЗМ расходятся
+	// с данными СЛУЖАЩИЕ; при fix перезаписывает их в одной транзакции
+	Reconcile(ctx context.Context, fix bool) ([]DepartmentDrift, error)
+}
+
+// SalaryRepository история и запланированные изменения зарплат (таблица salary_history).
+// Немедленные изменения записывает EmployeeRepository в транзакции изменения сотрудника.
+type SalaryRepository interface {
+	// History возвращает историю зарплаты сотрудника по дате вступления в силу
+	// (вместе с запланированными изменениями) или ErrNotFound
+	History(ctx context.Context, empID int) ([]SalaryChange, error)
+	// Schedule планирует изменение на будущую дату и возвращает его ID
+	Schedule(ctx context.Context, ch SalaryChange) (int64, error)
+	// Cancel отмечает запланированное изменение отменённым (запись остаётся в истории);
+	// ErrConflict, если оно уже применено или отменено
+	Cancel(ctx context.Context, empID int, id int64) error
+	// ApplyDue применяет запланированные изменения с датой не позже today (YYYY-MM-DD),
+	// каждое в своей транзакции. Изменение, которое не удалось применить, отмечается
+	// failed_at и больше не применяется; ошибки возвращаются вместе с применёнными.
+	ApplyDue(ctx context.Context, today string) ([]SalaryChange, error)
+}
+
+// UserRepository пользователи и refresh-токены (таблицы users, refresh_tokens)
+type UserRepository interface {
+	// GetByLogin возвращает пользователя по логину или ErrNotFound
+	GetByLogin(ctx context.Context, login string) (User, error)
+	// Create добавляет пользователя и возвращает его ID; ErrConflict, если логин занят
+	Create(ctx context.Context, u User) (int, error)
+	// SaveRefreshToken сохраняет выданный refresh-токен
+	SaveRefreshToken(ctx context.Context, t RefreshToken) error
+	// UseRefreshToken отзывает действующий токен и возвращает его владельца.
+	// Для неизвестного, просроченного или отозванного токена возвращает ErrNotFound;
+	// повторное предъявление отозванного токена отзывает все токены пользователя.
+	UseRefreshToken(ctx context.Context, hash string) (User, error)
+	// RevokeRefreshToken отзывает токен (выход); отсутствие токена не ошибка
+	RevokeRefreshToken(ctx context.Context, hash string) error
+}
+
+// AuditRepository чтение журнала аудита (таблица audit_log).
+// Записи добавляют остальные репозитории в транзакции изменения;
+// исполнитель берётся из контекста (см. withActor).
+type AuditRepository interface {
+	// List возвращает страницу записей по фильтрам q (новые первыми) и общее число подходящих
+	List(ctx context.Context, q AuditQuery) ([]AuditEntry, int, error)
+}
+
+// TemplateRepository версии DOCX-шаблонов отчётов (таблица report_templates)
+type TemplateRepository interface {
+	// Create сохраняет новую версию шаблона t.Name (следующую за последней);
+	// автор берётся из контекста. Возвращает сохранённую запись без содержимого.
+	Create(ctx context.Context, t ReportTemplate) (ReportTemplate, error)
+	// Get возвращает версию шаблона с содержимым (version 0 — последнюю) или ErrNotFound
+	Get(ctx context.Context, name string, version int) (ReportTemplate, error)
+	// List возвращает все версии всех шаблонов без содержимого
+	List(ctx context.Context) ([]ReportTemplate, error)
+}
+
+// Repositories набор репозиториев, с которыми работают обработчики
+type Repositories struct {
+	Employees   EmployeeRepository
+	Departments DepartmentRepository
+	Salaries    SalaryRepository
+	Users       UserRepository
+	Audit       AuditRepository
+	Templates   TemplateRepository
+}
@@ -0,0 +1,755 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryStore общее хранилище in-memory репозиториев.
+// Используется для разработки и тестов без MySQL.
+type memoryStore struct {
+	mu          sync.RWMutex
+	employees   map[int]Employee
+	departments map[int]Department
+	salaries    []SalaryChange
+	users       map[int]User
+	tokens      map[string]memoryRefreshToken
+	audit       []AuditEntry
+	templates   []ReportTemplate
+	nextEmpID   int
+	nextDeptID  int
+	nextSalary  int64
+}
+
+// newMemoryRepositories репозитории в памяти процесса
+func newMemoryRepositories() Repositories {
+	s := &memoryStore{
+		employees:   make(map[int]Employee),
+		departments: make(map[int]Department),
+		users:       make(map[int]User),
+		tokens:      make(map[string]memoryRefreshToken),
+		nextEmpID:   1,
+		nextDeptID:  1,
+	}
+	return Repositories{
+		Employees:   &memoryEmployeeRepository{s},
+		Departments: &memoryDepartmentRepository{s},
+		Salaries:    &memorySalaryRepository{s},
+		Users:       &memoryUserRepository{s},
+		Audit:       &memoryAuditRepository{s},
+		Templates:   &memoryTemplateRepository{s},
+	}
+}
+
+// writeAudit добавляет запись журнала; вызывается под s.mu
+func (s *memoryStore) writeAudit(ctx context.Context, entity string, id int, op string, old, cur interface{}) error {
+	entry, ok, err := newAuditEntry(ctx, entity, id, op, old, cur)
+	if err != nil || !ok {
+		return err
+	}
+	entry.ID = int64(len(s.audit) + 1)
+	s.audit = append(s.audit, entry)
+	return nil
+}
+
+// addSalaryChange добавляет запись истории зарплаты; вызывается под s.mu
+func (s *memoryStore) addSalaryChange(ch SalaryChange) int64 {
+	s.nextSalary++
+	ch.ID = s.nextSalary
+	s.salaries = append(s.salaries, ch)
+	return ch.ID
+}
+
+// adjustDepartment сдвигает агрегаты отдела; вызывается под s.mu
+func (s *memoryStore) adjustDepartment(deptID, sizeDelta int, salaryDelta float64) error {
+	d, ok := s.departments[deptID]
+	if !ok {
+		return fmt.Errorf("ошибка обновления отдела: отдел %d не существует", deptID)
+	}
+	d.Size += sizeDelta
+	d.TotalSalary += salaryDelta
+	s.departments[deptID] = d
+	return nil
+}
+
+// sortedEmployees сотрудники по возрастанию ID; вызывается под s.mu
+func (s *memoryStore) sortedEmployees(keep func(Employee) bool) []Employee {
+	var emps []Employee
+	for _, e := range s.employees {
+		if keep(e) {
+			emps = append(emps, e)
+		}
+	}
+	sort.Slice(emps, func(i, j int) bool { return emps[i].ID < emps[j].ID })
+	return emps
+}
+
+// memoryEmployeeRepository реализация EmployeeRepository в памяти
+type memoryEmployeeRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryEmployeeRepository) List(ctx context.Context, q EmployeeQuery) ([]Employee, int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	page, total := applyEmployeeQuery(r.s.sortedEmployees(func(Employee) bool { return true }), q)
+	return page, total, nil
+}
+
+func (r *memoryEmployeeRepository) Get(ctx context.Context, id int) (Employee, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	e, ok := r.s.employees[id]
+	if !ok {
+		return e, ErrNotFound
+	}
+	return e, nil
+}
+
+func (r *memoryEmployeeRepository) ListByDepartment(ctx context.Context, deptID int) ([]Employee, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.s.sortedEmployees(func(e Employee) bool { return e.DeptID == deptID }), nil
+}
+
+func (r *memoryEmployeeRepository) SearchCandidates(ctx context.Context, query string, limit int) ([]Employee, error) {
+	key := searchKey(query)
+	if key == "" {
+		return nil, nil
+	}
+	grams := searchBigrams(key)
+
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	type candidate struct {
+		e      Employee
+		shared int
+	}
+	var found []candidate
+	for _, e := range r.s.sortedEmployees(func(Employee) bool { return true }) {
+		name := searchKey(e.Name)
+		shared := 0
+		if len(grams) > 0 {
+			for g := range searchBigrams(name) {
+				if grams[g] {
+					shared++
+				}
+			}
+		} else if hasWordPrefixes(name, strings.Fields(key)) {
+			shared = 1
+		}
+		if shared > 0 {
+			found = append(found, candidate{e, shared})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].shared > found[j].shared })
+
+	emps := make([]Employee, 0, min(len(found), limit))
+	for _, c := range found[:min(len(found), limit)] {
+		emps = append(emps, c.e)
+	}
+	return emps, nil
+}
+
+// hasWordPrefixes каждое из prefixes — начало какого-либо слова key
+func hasWordPrefixes(key string, prefixes []string) bool {
+	words := strings.Fields(key)
+	for _, p := range prefixes {
+		if !slices.ContainsFunc(words, func(w string) bool { return strings.HasPrefix(w, p) }) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *memoryEmployeeRepository) Create(ctx context.Context, e Employee) (int, error) {
+	ids, err := r.CreateMany(ctx, []Employee{e})
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+func (r *memoryEmployeeRepository) CreateMany(ctx context.Context, emps []Employee) ([]int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	// Все отделы проверяются заранее, чтобы не добавить часть сотрудников
+	for _, e := range emps {
+		if _, ok := r.s.departments[e.DeptID]; !ok {
+			return nil, fmt.Errorf("ошибка создания сотрудника: отдел %d не существует", e.DeptID)
+		}
+	}
+
+	ids := make([]int, 0, len(emps))
+	for _, e := range emps {
+		if err := r.s.adjustDepartment(e.DeptID, 1, e.Salary); err != nil {
+			return nil, err
+		}
+		e.ID = r.s.nextEmpID
+		r.s.nextEmpID++
+		r.s.employees[e.ID] = e
+		r.s.addSalaryChange(newSalaryChange(ctx, e.ID, nil, e.Salary))
+		if err := r.s.writeAudit(ctx, auditEmployee, e.ID, auditCreate, nil, e); err != nil {
+			return nil, err
+		}
+		ids = append(ids, e.ID)
+	}
+	return ids, nil
+}
+
+func (r *memoryEmployeeRepository) Update(ctx context.Context, e Employee) (Employee, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	old, ok := r.s.employees[e.ID]
+	if !ok {
+		return old, ErrNotFound
+	}
+	if manager, ok := managerFrom(ctx); ok {
+		for _, id := range []int{old.DeptID, e.DeptID} {
+			if d, ok := r.s.departments[id]; !ok || d.BossID == nil || *d.BossID != manager {
+				return old, errDepartmentScope
+			}
+		}
+	}
+	if _, ok := r.s.departments[e.DeptID]; !ok {
+		return old, fmt.Errorf("ошибка обновления отдела: отдел %d не существует", e.DeptID)
+	}
+	r.s.adjustDepartment(old.DeptID, -1, -old.Salary)
+	r.s.adjustDepartment(e.DeptID, 1, e.Salary)
+
+	e.ImageURL = old.ImageURL
+	r.s.employees[e.ID] = e
+	if !sameAmount(old.Salary, e.Salary) {
+		r.s.addSalaryChange(newSalaryChange(ctx, e.ID, &old.Salary, e.Salary))
+	}
+	return old, r.s.writeAudit(ctx, auditEmployee, e.ID, auditUpdate, old, e)
+}
+
+func (r *memoryEmployeeRepository) Delete(ctx context.Context, id int) (Employee, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	old, ok := r.s.employees[id]
+	if !ok {
+		return old, ErrNotFound
+	}
+	r.s.adjustDepartment(old.DeptID, -1, -old.Salary)
+	delete(r.s.employees, id)
+	r.s.dropSalaryHistory(id)
+	r.s.unlinkUsers(id)
+	return old, r.s.writeAudit(ctx, auditEmployee, id, auditDelete, old, nil)
+}
+
+func (r *memoryEmployeeRepository) SetImageURL(ctx context.Context, id int, filename *string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	old, ok := r.s.employees[id]
+	if !ok {
+		return ErrNotFound
+	}
+	e := old
+	e.ImageURL = filename
+	r.s.employees[id] = e
+	return r.s.writeAudit(ctx, auditEmployee, id, auditUpdate, old, e)
+}
+
+// memoryDepartmentRepository реализация DepartmentRepository в памяти
+type memoryDepartmentRepository struct {
+	s *memoryStore
+}
+
+// withBossName дополняет отдел именем руководителя; вызывается под s.mu
+func (r *memoryDepartmentRepository) withBossName(d Department) Department {
+	d.BossName = nil
+	if d.BossID != nil {
+		if boss, ok := r.s.employees[*d.BossID]; ok {
+			name := boss.Name
+			d.BossName = &name
+		}
+	}
+	return d
+}
+
+func (r *memoryDepartmentRepository) List(ctx context.Context) ([]Department, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	deps := make([]Department, 0, len(r.s.departments))
+	for _, d := range r.s.departments {
+		deps = append(deps, r.withBossName(d))
+	}
+	sort.Slice(deps, func(i, j int) bool { return deps[i].ID < deps[j].ID })
+	return deps, nil
+}
+
+func (r *memoryDepartmentRepository) Get(ctx context.Context, id int) (Department, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	d, ok := r.s.departments[id]
+	if !ok {
+		return d, ErrNotFound
+	}
+	return r.withBossName(d), nil
+}
+
+func (r *memoryDepartmentRepository) FirstID(ctx context.Context) (int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	first := 0
+	for id := range r.s.departments {
+		if first == 0 || id < first {
+			first = id
+		}
+	}
+	if first == 0 {
+		return 0, ErrNotFound
+	}
+	return first, nil
+}
+
+func (r *memoryDepartmentRepository) Create(ctx context.Context, d Department) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	d.ID = r.s.nextDeptID
+	r.s.nextDeptID++
+	d.Description = emptyToNil(d.Description)
+	d.CostCenter = emptyToNil(d.CostCenter)
+	d.BossName, d.TotalSalary, d.Size = nil, 0, 0
+	r.s.departments[d.ID] = d
+	return d.ID, r.s.writeAudit(ctx, auditDepartment, d.ID, auditCreate, nil, d)
+}
+
+func (r *memoryDepartmentRepository) Update(ctx context.Context, id int, patch DepartmentPatch) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	old, ok := r.s.departments[id]
+	if !ok {
+		return ErrNotFound
+	}
+	d := applyDepartmentPatch(old, patch)
+	r.s.departments[id] = d
+	return r.s.writeAudit(ctx, auditDepartment, id, auditUpdate, old, d)
+}
+
+func (r *memoryDepartmentRepository) Delete(ctx context.Context, id int, policy DepartmentDeletePolicy, targetID int) ([]Employee, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	dept, ok := r.s.departments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	emps := r.s.sortedEmployees(func(e Employee) bool { return e.DeptID == id })
+
+	switch policy {
+	case DeleteRefuse:
+		if len(emps) > 0 {
+			return emps, fmt.Errorf("%w: в отделе %d сотрудников", ErrConflict, len(emps))
+		}
+	case DeleteReassign:
+		if _, ok := r.s.departments[targetID]; !ok {
+			return nil, fmt.Errorf("ошибка перевода сотрудников: отдел %d не существует", targetID)
+		}
+		for _, old := range emps {
+			e := old
+			e.DeptID = targetID
+			r.s.employees[e.ID] = e
+			r.s.adjustDepartment(targetID, 1, e.Salary)
+			if err := r.s.writeAudit(ctx, auditEmployee, e.ID, auditUpdate, old, e); err != nil {
+				return nil, err
+			}
+		}
+	case DeleteCascade:
+		for _, e := range emps {
+			delete(r.s.employees, e.ID)
+			r.s.dropSalaryHistory(e.ID)
+			r.s.unlinkUsers(e.ID)
+			// Отделы, которыми руководил сотрудник, остаются без руководителя
+			for depID, old := range r.s.departments {
+				if old.BossID != nil && *old.BossID == e.ID {
+					d := old
+					d.BossID = nil
+					r.s.departments[depID] = d
+					if err := r.s.writeAudit(ctx, auditDepartment, depID, auditUpdate, old, d); err != nil {
+						return nil, err
+					}
+				}
+			}
+			if err := r.s.writeAudit(ctx, auditEmployee, e.ID, auditDelete, e, nil); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("неизвестная политика удаления %q", policy)
+	}
+
+	delete(r.s.departments, id)
+	return emps, r.s.writeAudit(ctx, auditDepartment, id, auditDelete, dept, nil)
+}
+
+func (r *memoryDepartmentRepository) Reconcile(ctx context.Context, fix bool) ([]DepartmentDrift, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	actual := map[int]DepartmentDrift{}
+	for _, e := range r.s.employees {
+		a := actual[e.DeptID]
+		a.ActualSalary += e.Salary
+		a.ActualSize++
+		actual[e.DeptID] = a
+	}
+
+	var drifts []DepartmentDrift
+	for id, d := range r.s.departments {
+		a := actual[id]
+		if sameAmount(d.TotalSalary, a.ActualSalary) && d.Size == a.ActualSize {
+			continue
+		}
+		drifts = append(drifts, DepartmentDrift{
+			ID:           id,
+			StoredSalary: d.TotalSalary,
+			ActualSalary: a.ActualSalary,
+			StoredSize:   d.Size,
+			ActualSize:   a.ActualSize,
+		})
+	}
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i].ID < drifts[j].ID })
+
+	if fix {
+		for _, dr := range drifts {
+			d := r.s.departments[dr.ID]
+			d.TotalSalary, d.Size = dr.ActualSalary, dr.ActualSize
+			r.s.departments[dr.ID] = d
+			if err := r.s.writeAudit(ctx, auditDepartment, dr.ID, auditReconcile,
+				map[string]interface{}{"total_salary": dr.StoredSalary, "size": dr.StoredSize},
+				map[string]interface{}{"total_salary": dr.ActualSalary, "size": dr.ActualSize},
+			); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return drifts, nil
+}
+
+// dropSalaryHistory удаляет историю зарплаты удалённого сотрудника
+// (как ON DELETE CASCADE в MySQL); вызывается под s.mu
+func (s *memoryStore) dropSalaryHistory(empID int) {
+	kept := s.salaries[:0]
+	for _, ch := range s.salaries {
+		if ch.EmployeeID != empID {
+			kept = append(kept, ch)
+		}
+	}
+	s.salaries = kept
+}
+
+// memorySalaryRepository реализация SalaryRepository в памяти
+type memorySalaryRepository struct {
+	s *memoryStore
+}
+
+func (r *memorySalaryRepository) History(ctx context.Context, empID int) ([]SalaryChange, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if _, ok := r.s.employees[empID]; !ok {
+		return nil, ErrNotFound
+	}
+	var history []SalaryChange
+	for _, ch := range r.s.salaries {
+		if ch.EmployeeID == empID {
+			history = append(history, ch)
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool { return history[i].EffectiveDate < history[j].EffectiveDate })
+	return history, nil
+}
+
+func (r *memorySalaryRepository) Schedule(ctx context.Context, ch SalaryChange) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.employees[ch.EmployeeID]; !ok {
+		return 0, ErrNotFound
+	}
+	ch.OldSalary, ch.AppliedAt, ch.CancelledAt, ch.CancelledBy = nil, nil, nil, ""
+	ch.Author, ch.CreatedAt = actorFrom(ctx), time.Now().UTC()
+	ch.ID = r.s.addSalaryChange(ch)
+	return ch.ID, r.s.writeAudit(ctx, auditEmployee, ch.EmployeeID, auditSalarySchedule, nil, ch)
+}
+
+func (r *memorySalaryRepository) Cancel(ctx context.Context, empID int, id int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for i, ch := range r.s.salaries {
+		if ch.ID != id || ch.EmployeeID != empID {
+			continue
+		}
+		if err := cancellable(ch); err != nil {
+			return err
+		}
+		cancelled := ch
+		now := time.Now().UTC()
+		cancelled.CancelledAt, cancelled.CancelledBy = &now, actorFrom(ctx)
+		if err := r.s.writeAudit(ctx, auditEmployee, empID, auditSalaryCancel, ch, cancelled); err != nil {
+			return err
+		}
+		r.s.salaries[i] = cancelled
+		return nil
+	}
+	return ErrNotFound
+}
+
+func (r *memorySalaryRepository) ApplyDue(ctx context.Context, today string) ([]SalaryChange, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var due []int
+	for i, ch := range r.s.salaries {
+		if ch.AppliedAt == nil && ch.CancelledAt == nil && ch.FailedAt == nil && ch.EffectiveDate <= today {
+			due = append(due, i)
+		}
+	}
+	sort.SliceStable(due, func(a, b int) bool {
+		return r.s.salaries[due[a]].EffectiveDate < r.s.salaries[due[b]].EffectiveDate
+	})
+
+	var (
+		applied []SalaryChange
+		errs    []error
+	)
+	for _, i := range due {
+		ch := &r.s.salaries[i]
+		if err := r.s.applySalaryChange(ctx, ch); err != nil {
+			now := time.Now().UTC()
+			ch.FailedAt, ch.FailReason = &now, failReason(err)
+			errs = append(errs, fmt.Errorf("изменение зарплаты %d: %v", ch.ID, err))
+			continue
+		}
+		applied = append(applied, *ch)
+	}
+	return applied, errors.Join(errs...)
+}
+
+// applySalaryChange применяет запланированное изменение ch; при ошибке данные
+// не меняются. Вызывается под s.mu
+func (s *memoryStore) applySalaryChange(ctx context.Context, ch *SalaryChange) error {
+	old, ok := s.employees[ch.EmployeeID]
+	if !ok {
+		return ErrNotFound
+	}
+	cur := old
+	cur.Salary = ch.NewSalary
+	if err := s.adjustDepartment(cur.DeptID, 0, cur.Salary-old.Salary); err != nil {
+		return err
+	}
+	s.employees[cur.ID] = cur
+
+	now := time.Now().UTC()
+	oldSalary := old.Salary
+	ch.OldSalary, ch.AppliedAt = &oldSalary, &now
+	audit := appliedSalaryAudit{Employee: cur, SalaryChangeID: ch.ID, ScheduledBy: ch.Author}
+	return s.writeAudit(withActor(ctx, systemActor), auditEmployee, cur.ID, auditUpdate, old, audit)
+}
+
+// unlinkUsers отвязывает пользователей от удалённого сотрудника
+// (как ON DELETE SET NULL в MySQL); вызывается под s.mu
+func (s *memoryStore) unlinkUsers(empID int) {
+	for id, u := range s.users {
+		if u.EmpID != nil && *u.EmpID == empID {
+			u.EmpID = nil
+			s.users[id] = u
+		}
+	}
+}
+
+// memoryRefreshToken refresh-токен и признак отзыва
+type memoryRefreshToken struct {
+	RefreshToken
+	revoked bool
+}
+
+// memoryUserRepository реализация UserRepository в памяти
+type memoryUserRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryUserRepository) GetByLogin(ctx context.Context, login string) (User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, u := range r.s.users {
+		if u.Login == login {
+			return u, nil
+		}
+	}
+	return User{}, ErrNotFound
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, u User) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, existing := range r.s.users {
+		if existing.Login == u.Login {
+			return 0, fmt.Errorf("%w: логин %q занят", ErrConflict, u.Login)
+		}
+	}
+	if u.EmpID != nil {
+		if _, ok := r.s.employees[*u.EmpID]; !ok {
+			return 0, fmt.Errorf("ошибка создания пользователя: сотрудник %d не существует", *u.EmpID)
+		}
+	}
+	u.ID = len(r.s.users) + 1
+	u.CreatedAt = time.Now().UTC()
+	r.s.users[u.ID] = u
+	return u.ID, nil
+}
+
+func (r *memoryUserRepository) SaveRefreshToken(ctx context.Context, t RefreshToken) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.tokens[t.Hash] = memoryRefreshToken{RefreshToken: t}
+	return nil
+}
+
+func (r *memoryUserRepository) UseRefreshToken(ctx context.Context, hash string) (User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.tokens[hash]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	if t.revoked {
+		// Токен уже использован: отзываем все сессии пользователя
+		for h, other := range r.s.tokens {
+			if other.UserID == t.UserID {
+				other.revoked = true
+				r.s.tokens[h] = other
+			}
+		}
+		return User{}, ErrNotFound
+	}
+	if !time.Now().Before(t.ExpiresAt) {
+		return User{}, ErrNotFound
+	}
+	t.revoked = true
+	r.s.tokens[hash] = t
+
+	u, ok := r.s.users[t.UserID]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return u, nil
+}
+
+func (r *memoryUserRepository) RevokeRefreshToken(ctx context.Context, hash string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if t, ok := r.s.tokens[hash]; ok {
+		t.revoked = true
+		r.s.tokens[hash] = t
+	}
+	return nil
+}
+
+// memoryAuditRepository реализация AuditRepository в памяти
+type memoryAuditRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryAuditRepository) List(ctx context.Context, q AuditQuery) ([]AuditEntry, int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	page, total := filterAuditEntries(r.s.audit, q)
+	return page, total, nil
+}
+
+// memoryTemplateRepository реализация TemplateRepository в памяти
+type memoryTemplateRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryTemplateRepository) Create(ctx context.Context, t ReportTemplate) (ReportTemplate, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t.Version = 1
+	for _, existing := range r.s.templates {
+		if existing.Name == t.Name && existing.Version >= t.Version {
+			t.Version = existing.Version + 1
+		}
+	}
+	t.ID = len(r.s.templates) + 1
+	t.Size = len(t.Content)
+	t.CreatedBy, t.CreatedAt = actorFrom(ctx), time.Now().UTC()
+	r.s.templates = append(r.s.templates, t)
+
+	t.Content = nil
+	return t, nil
+}
+
+func (r *memoryTemplateRepository) Get(ctx context.Context, name string, version int) (ReportTemplate, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var found *ReportTemplate
+	for i, t := range r.s.templates {
+		if t.Name == name && (version == 0 && (found == nil || t.Version > found.Version) || t.Version == version) {
+			found = &r.s.templates[i]
+		}
+	}
+	if found == nil {
+		return ReportTemplate{}, ErrNotFound
+	}
+	return *found, nil
+}
+
+func (r *memoryTemplateRepository) List(ctx context.Context) ([]ReportTemplate, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	list := make([]ReportTemplate, 0, len(r.s.templates))
+	for _, t := range r.s.templates {
+		t.Content = nil
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].Version > list[j].Version
+	})
+	return list, nil
+}
+
+// sameAmount сравнивает денежные суммы с точностью до копейки (как DECIMAL(12,2))
+func sameAmount(a, b float64) bool {
+	return math.Round(a*100) == math.Round(b*100)
+}
+
+// emptyToNil заменяет "" на nil (как NULL в MySQL)
+func emptyToNil(s *string) *string {
+	if s == nil || *s == "" {
+		return nil
+	}
+	v := *s
+	return &v
+}
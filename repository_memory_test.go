@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// seedDepartments создаёт отделы с именами names и возвращает их ID
+func seedDepartments(t *testing.T, repos Repositories, names ...string) []int {
+	t.Helper()
+	ids := make([]int, 0, len(names))
+	for _, name := range names {
+		id, err := repos.Departments.Create(context.Background(), Department{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestMemoryEmployees(t *testing.T) {
+	repos := newMemoryRepositories()
+	ctx := context.Background()
+	ids := seedDepartments(t, repos, "Первый", "Второй")
+
+	id, err := repos.Employees.Create(ctx, Employee{Name: "Анна", Status: "active", Salary: 1000, DeptID: ids[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Employees.Create(ctx, Employee{Name: "Борис", DeptID: 999}); err == nil {
+		t.Fatal("создан сотрудник несуществующего отдела")
+	}
+
+	e, err := repos.Employees.Get(ctx, id)
+	if err != nil || e.Name != "Анна" || e.DeptID != ids[0] {
+		t.Fatalf("сотрудник %+v: %v", e, err)
+	}
+
+	// Update возвращает предыдущее состояние
+	e.DeptID = ids[1]
+	old, err := repos.Employees.Update(ctx, e)
+	if err != nil || old.DeptID != ids[0] {
+		t.Fatalf("предыдущее состояние %+v: %v", old, err)
+	}
+	if emps, _ := repos.Employees.ListByDepartment(ctx, ids[0]); len(emps) != 0 {
+		t.Fatalf("в прежнем отделе %+v", emps)
+	}
+	if emps, _ := repos.Employees.ListByDepartment(ctx, ids[1]); len(emps) != 1 || emps[0].ID != id {
+		t.Fatalf("в новом отделе %+v", emps)
+	}
+
+	if deleted, err := repos.Employees.Delete(ctx, id); err != nil || deleted.Name != "Анна" {
+		t.Fatalf("удалён %+v: %v", deleted, err)
+	}
+	if _, err := repos.Employees.Get(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get удалённого: %v", err)
+	}
+	if _, err := repos.Employees.Update(ctx, e); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Update удалённого: %v", err)
+	}
+	if _, err := repos.Employees.Delete(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Delete удалённого: %v", err)
+	}
+}
+
+// Права руководителя проверяются в Update под блокировкой: перевод сотрудника
+// после проверки в обработчике не даёт изменить чужого сотрудника
+func TestMemoryUpdateManagerScope(t *testing.T) {
+	repos := newMemoryRepositories()
+	ctx := context.Background()
+	ids := seedDepartments(t, repos, "Свой", "Чужой")
+	head, _ := repos.Employees.Create(ctx, Employee{Name: "Руководитель", Salary: 2000, DeptID: ids[0]})
+	id, _ := repos.Employees.Create(ctx, Employee{Name: "Анна", Salary: 1000, DeptID: ids[0]})
+	if err := repos.Departments.Update(ctx, ids[0], DepartmentPatch{SetBoss: true, BossID: &head}); err != nil {
+		t.Fatal(err)
+	}
+	scoped := context.WithValue(ctx, managerKey{}, head)
+
+	if _, err := repos.Employees.Update(scoped, Employee{ID: id, Name: "Анна", Salary: 1100, DeptID: ids[0]}); err != nil {
+		t.Fatalf("изменение в своём отделе: %v", err)
+	}
+	if _, err := repos.Employees.Update(scoped, Employee{ID: id, Name: "Анна", Salary: 1100, DeptID: ids[1]}); !errors.Is(err, errDepartmentScope) {
+		t.Fatalf("перевод в чужой отдел: %v", err)
+	}
+
+	// Сотрудника перевели, пока обработчик проверял права
+	repos.Employees.Update(ctx, Employee{ID: id, Name: "Анна", Salary: 1100, DeptID: ids[1]})
+	if _, err := repos.Employees.Update(scoped, Employee{ID: id, Name: "Анна", Salary: 5000, DeptID: ids[0]}); !errors.Is(err, errDepartmentScope) {
+		t.Fatalf("изменение переведённого сотрудника: %v", err)
+	}
+	if e, _ := repos.Employees.Get(ctx, id); e.Salary != 1100 || e.DeptID != ids[1] {
+		t.Fatalf("сотрудник изменён без прав: %+v", e)
+	}
+}
+
+func TestMemoryReconcile(t *testing.T) {
+	repos := newMemoryRepositories()
+	ctx := context.Background()
+	dept := seedDepartments(t, repos, "Отдел")[0]
+	repos.Employees.Create(ctx, Employee{Name: "Сотрудник", Salary: 1000, DeptID: dept})
+
+	// Агрегаты, изменённые в обход репозитория
+	store := repos.Departments.(*memoryDepartmentRepository).s
+	store.adjustDepartment(dept, 3, 42)
+
+	drifts, err := repos.Departments.Reconcile(ctx, true)
+	if err != nil || len(drifts) != 1 {
+		t.Fatalf("расхождения %+v: %v", drifts, err)
+	}
+	want := DepartmentDrift{ID: dept, StoredSalary: 1042, ActualSalary: 1000, StoredSize: 4, ActualSize: 1}
+	if drifts[0] != want {
+		t.Fatalf("расхождение %+v, ожидалось %+v", drifts[0], want)
+	}
+	if drifts, _ := repos.Departments.Reconcile(ctx, false); len(drifts) != 0 {
+		t.Fatalf("после исправления остались расхождения %+v", drifts)
+	}
+}
+
+func TestMemoryAggregates(t *testing.T) {
+	repos := newMemoryRepositories()
+	ctx := context.Background()
+	ids := seedDepartments(t, repos, "Первый", "Второй")
+	id, _ := repos.Employees.Create(ctx, Employee{Name: "Анна", Salary: 1000, DeptID: ids[0]})
+	repos.Employees.CreateMany(ctx, []Employee{{Name: "Борис", Salary: 500, DeptID: ids[0]}})
+
+	expect := func(dept, size int, total float64) {
+		t.Helper()
+		d, err := repos.Departments.Get(ctx, dept)
+		if err != nil || d.Size != size || d.TotalSalary != total {
+			t.Fatalf("отдел %d: size=%d total=%v, ожидалось %d и %v (%v)", dept, d.Size, d.TotalSalary, size, total, err)
+		}
+	}
+	expect(ids[0], 2, 1500)
+	expect(ids[1], 0, 0)
+
+	// Перевод с повышением переносит сотрудника и его зарплату
+	repos.Employees.Update(ctx, Employee{ID: id, Name: "Анна", Salary: 1200, DeptID: ids[1]})
+	expect(ids[0], 1, 500)
+	expect(ids[1], 1, 1200)
+
+	repos.Employees.Delete(ctx, id)
+	expect(ids[1], 0, 0)
+	if drifts, _ := repos.Departments.Reconcile(ctx, false); len(drifts) != 0 {
+		t.Fatalf("расхождения %+v", drifts)
+	}
+}
+
+func TestMemoryDepartmentDeletePolicies(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("refuse", func(t *testing.T) {
+		repos := newMemoryRepositories()
+		dept := seedDepartments(t, repos, "Отдел")[0]
+		repos.Employees.Create(ctx, Employee{Name: "Сотрудник", DeptID: dept})
+		if _, err := repos.Departments.Delete(ctx, dept, DeleteRefuse, 0); !errors.Is(err, ErrConflict) {
+			t.Fatalf("ошибка %v, ожидался ErrConflict", err)
+		}
+		if _, err := repos.Departments.Get(ctx, dept); err != nil {
+			t.Fatal("отдел удалён несмотря на отказ")
+		}
+	})
+
+	t.Run("cascade", func(t *testing.T) {
+		repos := newMemoryRepositories()
+		ids := seedDepartments(t, repos, "Удаляемый", "Другой")
+		boss, _ := repos.Employees.Create(ctx, Employee{Name: "Руководитель", Salary: 500, DeptID: ids[0]})
+		if err := repos.Departments.Update(ctx, ids[1], DepartmentPatch{SetBoss: true, BossID: &boss}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repos.Users.Create(ctx, User{Login: "boss", Role: roleViewer, EmpID: &boss}); err != nil {
+			t.Fatal(err)
+		}
+
+		removed, err := repos.Departments.Delete(ctx, ids[0], DeleteCascade, 0)
+		if err != nil || len(removed) != 1 {
+			t.Fatalf("удалено %d сотрудников: %v", len(removed), err)
+		}
+		if _, err := repos.Employees.Get(ctx, boss); !errors.Is(err, ErrNotFound) {
+			t.Fatal("сотрудник не удалён")
+		}
+		if d, _ := repos.Departments.Get(ctx, ids[1]); d.BossID != nil {
+			t.Fatal("руководитель другого отдела не сброшен")
+		}
+		if u, _ := repos.Users.GetByLogin(ctx, "boss"); u.EmpID != nil {
+			t.Fatal("пользователь не отвязан от сотрудника")
+		}
+		if _, err := repos.Salaries.History(ctx, boss); !errors.Is(err, ErrNotFound) {
+			t.Fatal("история зарплаты не удалена")
+		}
+	})
+}
+
+func TestMemorySearchCandidates(t *testing.T) {
+	repos := newMemoryRepositories()
+	ctx := context.Background()
+	dept := seedDepartments(t, repos, "Отдел")[0]
+	for _, name := range []string{"Иванов Пётр", "Иванова Анна", "Петров Иван", "Сидоров Олег"} {
+		repos.Employees.Create(ctx, Employee{Name: name, DeptID: dept})
+	}
+
+	tests := []struct {
+		query string
+		limit int
+		want  []string
+	}{
+		// Больше общих биграмм — выше; «Сидоров» делит с запросом только "ov"
+		{"ivanov", 10, []string{"Иванов Пётр", "Иванова Анна", "Петров Иван", "Сидоров Олег"}},
+		{"ivanov", 3, []string{"Иванов Пётр", "Иванова Анна", "Петров Иван"}},
+		{"Иванов", 1, []string{"Иванов Пётр"}},
+		{"с о", 10, []string{"Сидоров Олег"}}, // однобуквенные слова — по началу слов
+		{"шшш", 10, nil},
+		{"", 10, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query+"/"+strconv.Itoa(tt.limit), func(t *testing.T) {
+			emps, err := repos.Employees.SearchCandidates(ctx, tt.query, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range emps {
+				got = append(got, e.Name)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("кандидаты %q, ожидались %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("кандидаты %q, ожидались %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryAuditActor(t *testing.T) {
+	repos := newMemoryRepositories()
+	ctx := withActor(context.Background(), "user:anna")
+	dept := seedDepartments(t, repos, "Отдел")[0]
+	id, _ := repos.Employees.Create(ctx, Employee{Name: "Сотрудник", Salary: 1000, DeptID: dept})
+
+	// Изменение без разницы в данных не пишется
+	e, _ := repos.Employees.Get(ctx, id)
+	if _, err := repos.Employees.Update(ctx, e); err != nil {
+		t.Fatal(err)
+	}
+	e.Salary = 2000
+	repos.Employees.Update(ctx, e)
+
+	entries, total, err := repos.Audit.List(ctx, AuditQuery{Entity: auditEmployee, EntityID: &id})
+	if err != nil || total != 2 {
+		t.Fatalf("записей %d, ожидалось 2: %v", total, err)
+	}
+	if entries[0].Operation != auditUpdate || entries[0].Actor != "user:anna" {
+		t.Fatalf("последняя запись %+v", entries[0])
+	}
+	if _, ok := entries[0].Changes["salary"]; !ok || len(entries[0].Changes) != 1 {
+		t.Fatalf("изменения %+v, ожидалась только salary", entries[0].Changes)
+	}
+}
+
+func TestMemoryRefreshTokenReuse(t *testing.T) {
+	repos := newMemoryRepositories()
+	ctx := context.Background()
+	uid, _ := repos.Users.Create(ctx, User{Login: "anna", Role: roleHR})
+	expires := time.Now().Add(time.Hour)
+	for _, hash := range []string{"first", "second"} {
+		repos.Users.SaveRefreshToken(ctx, RefreshToken{Hash: hash, UserID: uid, ExpiresAt: expires})
+	}
+	repos.Users.SaveRefreshToken(ctx, RefreshToken{Hash: "expired", UserID: uid, ExpiresAt: time.Now().Add(-time.Second)})
+
+	if u, err := repos.Users.UseRefreshToken(ctx, "first"); err != nil || u.Login != "anna" {
+		t.Fatalf("первое использование: %+v, %v", u, err)
+	}
+	if _, err := repos.Users.UseRefreshToken(ctx, "expired"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("просроченный токен: %v", err)
+	}
+	// Повторное использование отзывает все токены пользователя
+	if _, err := repos.Users.UseRefreshToken(ctx, "first"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("повторное использование: %v", err)
+	}
+	if _, err := repos.Users.UseRefreshToken(ctx, "second"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("токен той же сессии не отозван: %v", err)
+	}
+	if _, err := repos.Users.Create(ctx, User{Login: "anna", Role: roleViewer}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("занятый логин: %v", err)
+	}
+}
+
+func TestMemoryTemplateVersions(t *testing.T) {
+	repos := newMemoryRepositories()
+	ctx := withActor(context.Background(), "user:hr")
+	for _, content := range []string{"v1", "v2"} {
+		if _, err := repos.Templates.Create(ctx, ReportTemplate{Name: "dept", Content: []byte(content)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	latest, err := repos.Templates.Get(ctx, "dept", 0)
+	if err != nil || latest.Version != 2 || string(latest.Content) != "v2" || latest.CreatedBy != "user:hr" {
+		t.Fatalf("последняя версия %+v: %v", latest, err)
+	}
+	if first, _ := repos.Templates.Get(ctx, "dept", 1); string(first.Content) != "v1" {
+		t.Fatalf("версия 1: %q", first.Content)
+	}
+	if _, err := repos.Templates.Get(ctx, "dept", 3); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("несуществующая версия: %v", err)
+	}
+	list, _ := repos.Templates.List(ctx)
+	if len(list) != 2 || list[0].Version != 2 || list[0].Content != nil {
+		t.Fatalf("список %+v", list)
+	}
+}
+
+func TestMemoryCreateManyIsAtomic(t *testing.T) {
+	repos := newMemoryRepositories()
+	ctx := context.Background()
+	dept := seedDepartments(t, repos, "Отдел")[0]
+
+	_, err := repos.Employees.CreateMany(ctx, []Employee{
+		{Name: "Первый", Salary: 100, DeptID: dept},
+		{Name: "Второй", Salary: 100, DeptID: 999},
+	})
+	if err == nil {
+		t.Fatal("ожидалась ошибка для несуществующего отдела")
+	}
+	if _, total, _ := repos.Employees.List(ctx, EmployeeQuery{}); total != 0 {
+		t.Fatalf("добавлено %d сотрудников, ожидалось 0", total)
+	}
+	if d, _ := repos.Departments.Get(ctx, dept); d.Size != 0 || d.TotalSalary != 0 {
+		t.Fatalf("агрегаты изменились: %+v", d)
+	}
+}
@@ -0,0 +1,1348 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// newMySQLRepositories репозитории поверх MySQL.
+// aggregates — выбранная стратегия поддержки агрегатов отделов (см. aggregates.go).
+func newMySQLRepositories(db *sql.DB, aggregates aggregateStrategy) Repositories {
+	employees := &mysqlEmployeeRepository{db: db, aggregates: aggregates}
+	return Repositories{
+		Employees:   employees,
+		Departments: &mysqlDepartmentRepository{db: db, aggregates: aggregates},
+		Salaries:    &mysqlSalaryRepository{db: db, employees: employees},
+		Users:       &mysqlUserRepository{db: db},
+		Audit:       &mysqlAuditRepository{db: db},
+		Templates:   &mysqlTemplateRepository{db: db},
+	}
+}
+
+// mysqlEmployeeRepository реализация EmployeeRepository для MySQL
+type mysqlEmployeeRepository struct {
+	db         *sql.DB
+	aggregates aggregateStrategy
+}
+
+// Колонки сортировки EmployeeQuery.Sort
+var employeeSortColumns = map[string]string{
+	sortByID:     "СЛУ_НОМЕР",
+	sortByName:   "СЛУ_ИМЯ",
+	sortBySalary: "СЛУ_ЗАРП",
+}
+
+func (r *mysqlEmployeeRepository) List(ctx context.Context, q EmployeeQuery) ([]Employee, int, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	if q.Status != "" {
+		where = append(where, "СЛУ_СТАТ = ?")
+		args = append(args, q.Status)
+	}
+	if q.DeptID != nil {
+		where = append(where, "СЛУ_ОТД_НОМЕР = ?")
+		args = append(args, *q.DeptID)
+	}
+	if q.SalaryMin != nil {
+		where = append(where, "СЛУ_ЗАРП >= ?")
+		args = append(args, *q.SalaryMin)
+	}
+	if q.SalaryMax != nil {
+		where = append(where, "СЛУ_ЗАРП <= ?")
+		args = append(args, *q.SalaryMax)
+	}
+	if q.Name != "" {
+		where = append(where, `СЛУ_ИМЯ LIKE ? ESCAPE '\\'`)
+		args = append(args, "%"+likeEscaper.Replace(q.Name)+"%")
+	}
+
+	filter := ""
+	if len(where) > 0 {
+		filter = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	// Общее число без учёта страницы
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM СЛУЖАЩИЕ"+filter, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	col, ok := employeeSortColumns[q.Sort]
+	if !ok {
+		col = employeeSortColumns[sortByID]
+	}
+	dir, cmp := "ASC", ">"
+	if q.Desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	// Keyset: строго после (значение сортировки, ID) последней записи
+	if q.Cursor != nil {
+		var value interface{} = q.Cursor.ID
+		switch q.Sort {
+		case sortByName:
+			value = q.Cursor.Name
+		case sortBySalary:
+			value = q.Cursor.Salary
+		}
+		where = append(where, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND СЛУ_НОМЕР %[2]s ?))", col, cmp))
+		args = append(args, value, value, q.Cursor.ID)
+		filter = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	query := "SELECT СЛУ_НОМЕР, СЛУ_ИМЯ, СЛУ_СТАТ, СЛУ_ЗАРП, СЛУ_ОТД_НОМЕР FROM СЛУЖАЩИЕ" + filter +
+		fmt.Sprintf(" ORDER BY %s %s, СЛУ_НОМЕР %s", col, dir, dir)
+	if q.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, q.Limit, q.Offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	emps, err := scanEmployees(rows)
+	return emps, total, err
+}
+
+// likeEscaper экранирует спецсимволы LIKE в пользовательской подстроке
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (r *mysqlEmployeeRepository) Get(ctx context.Context, id int) (Employee, error) {
+	var e Employee
+	row := r.db.QueryRowContext(ctx, "SELECT СЛУ_НОМЕР, СЛУ_ИМЯ, СЛУ_СТАТ, СЛУ_ЗАРП, СЛУ_ОТД_НОМЕР, IMAGE_URL FROM СЛУЖАЩИЕ WHERE СЛУ_НОМЕР = ?", id)
+	if err := row.Scan(&e.ID, &e.Name, &e.Status, &e.Salary, &e.DeptID, &e.ImageURL); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return e, ErrNotFound
+		}
+		return e, err
+	}
+	return e, nil
+}
+
+func (r *mysqlEmployeeRepository) ListByDepartment(ctx context.Context, deptID int) ([]Employee, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT СЛУ_НОМЕР, СЛУ_ИМЯ, СЛУ_СТАТ, СЛУ_ЗАРП, СЛУ_ОТД_НОМЕР FROM СЛУЖАЩИЕ WHERE СЛУ_ОТД_НОМЕР = ?", deptID)
+	if err != nil {
+		return nil, err
+	}
+	return scanEmployees(rows)
+}
+
+func (r *mysqlEmployeeRepository) SearchCandidates(ctx context.Context, query string, limit int) ([]Employee, error) {
+	key := searchKey(query)
+	if key == "" {
+		return nil, nil
+	}
+	// Слова из двух и более букв — индекс FULLTEXT (ngram); если их нет, однобуквенные — по началу слов
+	where, args := "MATCH(СЛУ_ПОИСК) AGAINST(? IN NATURAL LANGUAGE MODE)", []interface{}{key}
+	order := "MATCH(СЛУ_ПОИСК) AGAINST(? IN NATURAL LANGUAGE MODE) DESC, "
+	if len(searchBigrams(key)) == 0 {
+		var conds []string
+		args = nil
+		for _, word := range strings.Fields(key) {
+			conds = append(conds, "(СЛУ_ПОИСК LIKE ? OR СЛУ_ПОИСК LIKE ?)")
+			args = append(args, word+"%", "% "+word+"%")
+		}
+		where, order = strings.Join(conds, " AND "), ""
+	} else {
+		args = append(args, key)
+	}
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT СЛУ_НОМЕР, СЛУ_ИМЯ, СЛУ_СТАТ, СЛУ_ЗАРП, СЛУ_ОТД_НОМЕР FROM СЛУЖАЩИЕ WHERE "+where+
+			" ORDER BY "+order+"СЛУ_НОМЕР LIMIT ?", args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanEmployees(rows)
+}
+
+// repairSearchKeys заполняет СЛУ_ПОИСК у сотрудников, добавленных или переименованных
+// в обход сервера (и у всех — после миграции 12). Возвращает число исправленных записей.
+func repairSearchKeys(ctx context.Context, db *sql.DB) (int, error) {
+	rows, err := db.QueryContext(ctx, "SELECT СЛУ_НОМЕР, СЛУ_ИМЯ, СЛУ_ПОИСК FROM СЛУЖАЩИЕ")
+	if err != nil {
+		return 0, err
+	}
+	stale := map[int]string{}
+	for rows.Next() {
+		var (
+			id        int
+			name, key string
+		)
+		if err := rows.Scan(&id, &name, &key); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if want := searchKey(name); want != key {
+			stale[id] = want
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for id, key := range stale {
+		if _, err := db.ExecContext(ctx, "UPDATE СЛУЖАЩИЕ SET СЛУ_ПОИСК = ? WHERE СЛУ_НОМЕР = ?", key, id); err != nil {
+			return 0, fmt.Errorf("ошибка обновления поискового ключа сотрудника %d: %v", id, err)
+		}
+	}
+	return len(stale), nil
+}
+
+func (r *mysqlEmployeeRepository) Create(ctx context.Context, e Employee) (int, error) {
+	ids, err := r.CreateMany(ctx, []Employee{e})
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+func (r *mysqlEmployeeRepository) CreateMany(ctx context.Context, emps []Employee) ([]int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	ids := make([]int, 0, len(emps))
+	for _, e := range emps {
+		id, err := r.insert(ctx, tx, e)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return ids, nil
+}
+
+// insert добавляет сотрудника в транзакции tx вместе с агрегатами отдела, историей зарплаты и аудитом
+func (r *mysqlEmployeeRepository) insert(ctx context.Context, tx *sql.Tx, e Employee) (int, error) {
+	// Вставка сотрудника
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO СЛУЖАЩИЕ (СЛУ_ИМЯ, СЛУ_ПОИСК, СЛУ_СТАТ, СЛУ_ЗАРП, СЛУ_ОТД_НОМЕР)
+       VALUES (?, ?, ?, ?, ?)`,
+		e.Name, searchKey(e.Name), e.Status, e.Salary, e.DeptID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка создания сотрудника: %v", err)
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения ID сотрудника: %v", err)
+	}
+
+	// Обновление данных отдела
+	if err := r.updateAggregates(ctx, tx, nil, &e); err != nil {
+		return 0, err
+	}
+	e.ID = int(lastID)
+	if _, err := insertSalaryChange(ctx, tx, newSalaryChange(ctx, e.ID, nil, e.Salary)); err != nil {
+		return 0, err
+	}
+	if err := writeAudit(ctx, tx, auditEmployee, e.ID, auditCreate, nil, e); err != nil {
+		return 0, err
+	}
+	return e.ID, nil
+}
+
+func (r *mysqlEmployeeRepository) Update(ctx context.Context, e Employee) (Employee, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Employee{}, fmt.Errorf("ошибка транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Сначала узнаём старую зарплату и отдел
+	old, err := getEmployeeForUpdate(ctx, tx, e.ID)
+	if err != nil {
+		return old, err
+	}
+	if err := checkManagerScope(ctx, tx, old.DeptID, e.DeptID); err != nil {
+		return old, err
+	}
+
+	// Обновляем данные сотрудника
+	_, err = tx.ExecContext(ctx, `
+            UPDATE СЛУЖАЩИЕ
+            SET СЛУ_ИМЯ     = ?,
+                СЛУ_ПОИСК   = ?,
+                СЛУ_СТАТ    = ?,
+                СЛУ_ЗАРП    = ?,
+                СЛУ_ОТД_НОМЕР = ?
+            WHERE СЛУ_НОМЕР = ?`,
+		e.Name, searchKey(e.Name), e.Status, e.Salary, e.DeptID, e.ID,
+	)
+	if err != nil {
+		return old, fmt.Errorf("ошибка обновления сотрудника: %v", err)
+	}
+
+	// Обновляем сумму зарплат отдела
+	if err := r.updateAggregates(ctx, tx, &old, &e); err != nil {
+		return old, err
+	}
+	if !sameAmount(old.Salary, e.Salary) {
+		if _, err := insertSalaryChange(ctx, tx, newSalaryChange(ctx, e.ID, &old.Salary, e.Salary)); err != nil {
+			return old, err
+		}
+	}
+	e.ImageURL = old.ImageURL
+	if err := writeAudit(ctx, tx, auditEmployee, e.ID, auditUpdate, old, e); err != nil {
+		return old, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return old, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return old, nil
+}
+
+func (r *mysqlEmployeeRepository) Delete(ctx context.Context, id int) (Employee, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Employee{}, fmt.Errorf("ошибка транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Нам нужно знать, из какого отдела и с какой зарплатой удаляем
+	old, err := getEmployeeForUpdate(ctx, tx, id)
+	if err != nil {
+		return old, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM СЛУЖАЩИЕ WHERE СЛУ_НОМЕР = ?", id); err != nil {
+		return old, fmt.Errorf("ошибка удаления сотрудника: %v", err)
+	}
+	if err := r.updateAggregates(ctx, tx, &old, nil); err != nil {
+		return old, err
+	}
+	if err := writeAudit(ctx, tx, auditEmployee, id, auditDelete, old, nil); err != nil {
+		return old, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return old, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return old, nil
+}
+
+func (r *mysqlEmployeeRepository) SetImageURL(ctx context.Context, id int, filename *string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	old, err := getEmployeeForUpdate(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE СЛУЖАЩИЕ SET IMAGE_URL = ? WHERE СЛУ_НОМЕР = ?", filename, id); err != nil {
+		return err
+	}
+	cur := old
+	cur.ImageURL = filename
+	if err := writeAudit(ctx, tx, auditEmployee, id, auditUpdate, old, cur); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// getEmployeeForUpdate читает сотрудника внутри транзакции с блокировкой строки
+func getEmployeeForUpdate(ctx context.Context, tx *sql.Tx, id int) (Employee, error) {
+	var e Employee
+	row := tx.QueryRowContext(ctx, `
+            SELECT СЛУ_НОМЕР, СЛУ_ИМЯ, СЛУ_СТАТ, СЛУ_ЗАРП, СЛУ_ОТД_НОМЕР, IMAGE_URL
+            FROM СЛУЖАЩИЕ WHERE СЛУ_НОМЕР = ? FOR UPDATE`, id)
+	if err := row.Scan(&e.ID, &e.Name, &e.Status, &e.Salary, &e.DeptID, &e.ImageURL); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return e, ErrNotFound
+		}
+		return e, err
+	}
+	return e, nil
+}
+
+// updateAggregates переносит сотрудника из состояния old в cur (nil — нет записи).
+// Работает только при стратегии app: иначе агрегаты ведут триггеры или представление.
+func (r *mysqlEmployeeRepository) updateAggregates(ctx context.Context, tx *sql.Tx, old, cur *Employee) error {
+	if r.aggregates != aggregatesApp {
+		return nil
+	}
+	switch {
+	case old == nil:
+		return adjustDepartment(ctx, tx, cur.DeptID, 1, cur.Salary)
+	case cur == nil:
+		return adjustDepartment(ctx, tx, old.DeptID, -1, -old.Salary)
+	case old.DeptID == cur.DeptID:
+		// тот же отдел: меняем только сумму зарплат
+		return adjustDepartment(ctx, tx, cur.DeptID, 0, cur.Salary-old.Salary)
+	}
+	// минус из старого, плюс в новый
+	if err := adjustDepartment(ctx, tx, old.DeptID, -1, -old.Salary); err != nil {
+		return err
+	}
+	return adjustDepartment(ctx, tx, cur.DeptID, 1, cur.Salary)
+}
+
+// checkManagerScope проверяет под блокировкой, что руководитель из контекста
+// (withManagerScope) возглавляет отделы deptIDs; без руководителя в контексте ничего не делает
+func checkManagerScope(ctx context.Context, tx *sql.Tx, deptIDs ...int) error {
+	manager, ok := managerFrom(ctx)
+	if !ok {
+		return nil
+	}
+	for _, id := range deptIDs {
+		d, err := getDepartmentForUpdate(ctx, tx, id)
+		if errors.Is(err, ErrNotFound) {
+			return errDepartmentScope
+		} else if err != nil {
+			return fmt.Errorf("ошибка запроса отдела: %v", err)
+		}
+		if d.BossID == nil || *d.BossID != manager {
+			return errDepartmentScope
+		}
+	}
+	return nil
+}
+
+// adjustDepartment сдвигает размер и сумму зарплат отдела
+func adjustDepartment(ctx context.Context, tx *sql.Tx, deptID, sizeDelta int, salaryDelta float64) error {
+	_, err := tx.ExecContext(ctx, `
+            UPDATE ОТДЕЛЫ
+            SET ОТД_РАЗМ       = ОТД_РАЗМ + ?,
+                ОТД_СОТР_ЗАРП = ОТД_СОТР_ЗАРП + ?
+            WHERE ОТД_НОМЕР = ?`,
+		sizeDelta, salaryDelta, deptID,
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления отдела: %v", err)
+	}
+	return nil
+}
+
+// scanEmployees читает строки (ID, имя, статус, зарплата, отдел) и закрывает rows
+func scanEmployees(rows *sql.Rows) ([]Employee, error) {
+	defer rows.Close()
+
+	var emps []Employee
+	for rows.Next() {
+		var e Employee
+		if err := rows.Scan(&e.ID, &e.Name, &e.Status, &e.Salary, &e.DeptID); err != nil {
+			return nil, err
+		}
+		emps = append(emps, e)
+	}
+	return emps, rows.Err()
+}
+
+// existsOrNotFound возвращает ErrNotFound, если запрос не вернул строк
+func existsOrNotFound(ctx context.Context, db *sql.DB, query string, args ...interface{}) error {
+	var one int
+	err := db.QueryRowContext(ctx, query, args...).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// mysqlDepartmentRepository реализация DepartmentRepository для MySQL
+type mysqlDepartmentRepository struct {
+	db         *sql.DB
+	aggregates aggregateStrategy
+}
+
+// selectDepartments запрос отделов; при стратегии view агрегаты берутся из ОТДЕЛЫ_ИТОГИ
+func (r *mysqlDepartmentRepository) selectDepartments() string {
+	totals, join := "d.ОТД_СОТР_ЗАРП, d.ОТД_РАЗМ", ""
+	if r.aggregates == aggregatesView {
+		totals = "t.ОТД_СОТР_ЗАРП, t.ОТД_РАЗМ"
+		join = `
+        JOIN ОТДЕЛЫ_ИТОГИ t
+            ON t.ОТД_НОМЕР = d.ОТД_НОМЕР`
+	}
+	return `
+        SELECT
+            d.ОТД_НОМЕР,
+            d.ОТД_НАЗВ,
+            d.ОТД_ОПИС,
+            d.ОТД_ЦФО,
+            d.ОТД_РУК,
+            e.СЛУ_ИМЯ,
+            ` + totals + `
+        FROM ОТДЕЛЫ d` + join + `
+        LEFT JOIN СЛУЖАЩИЕ e
+          	ON d.ОТД_РУК = e.СЛУ_НОМЕР`
+}
+
+func (r *mysqlDepartmentRepository) List(ctx context.Context) ([]Department, error) {
+	rows, err := r.db.QueryContext(ctx, r.selectDepartments()+" ORDER BY d.ОТД_НОМЕР")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deps []Department
+	for rows.Next() {
+		d, err := scanDepartment(rows)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, d)
+	}
+	return deps, rows.Err()
+}
+
+func (r *mysqlDepartmentRepository) Get(ctx context.Context, id int) (Department, error) {
+	d, err := scanDepartment(r.db.QueryRowContext(ctx, r.selectDepartments()+" WHERE d.ОТД_НОМЕР = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return d, ErrNotFound
+	}
+	return d, err
+}
+
+func (r *mysqlDepartmentRepository) FirstID(ctx context.Context) (int, error) {
+	var id int
+	err := r.db.QueryRowContext(ctx, "SELECT ОТД_НОМЕР FROM ОТДЕЛЫ ORDER BY ОТД_НОМЕР LIMIT 1").Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return id, err
+}
+
+func (r *mysqlDepartmentRepository) Create(ctx context.Context, d Department) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+        INSERT INTO ОТДЕЛЫ (ОТД_НАЗВ, ОТД_ОПИС, ОТД_ЦФО, ОТД_РУК, ОТД_СОТР_ЗАРП, ОТД_РАЗМ)
+        VALUES (?, ?, ?, ?, 0, 0)`,
+		d.Name, nullIfEmpty(d.Description), nullIfEmpty(d.CostCenter), d.BossID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка создания отдела: %v", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения ID отдела: %v", err)
+	}
+
+	d.ID = int(id)
+	d.Description, d.CostCenter = emptyToNil(d.Description), emptyToNil(d.CostCenter)
+	d.TotalSalary, d.Size = 0, 0
+	if err := writeAudit(ctx, tx, auditDepartment, d.ID, auditCreate, nil, d); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return d.ID, nil
+}
+
+func (r *mysqlDepartmentRepository) Update(ctx context.Context, id int, patch DepartmentPatch) error {
+	var (
+		sets []string
+		args []interface{}
+	)
+	if patch.SetBoss {
+		// nil сбрасывает руководителя в NULL
+		sets = append(sets, "ОТД_РУК = ?")
+		args = append(args, patch.BossID)
+	}
+	if patch.Name != nil {
+		sets = append(sets, "ОТД_НАЗВ = ?")
+		args = append(args, *patch.Name)
+	}
+	if patch.Description != nil {
+		sets = append(sets, "ОТД_ОПИС = ?")
+		args = append(args, nullIfEmpty(patch.Description))
+	}
+	if patch.CostCenter != nil {
+		sets = append(sets, "ОТД_ЦФО = ?")
+		args = append(args, nullIfEmpty(patch.CostCenter))
+	}
+	if len(sets) == 0 {
+		return existsOrNotFound(ctx, r.db, "SELECT 1 FROM ОТДЕЛЫ WHERE ОТД_НОМЕР = ?", id)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	old, err := getDepartmentForUpdate(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE ОТДЕЛЫ SET "+strings.Join(sets, ", ")+" WHERE ОТД_НОМЕР = ?",
+		append(args, id)...,
+	); err != nil {
+		return err
+	}
+	if err := writeAudit(ctx, tx, auditDepartment, id, auditUpdate, old, applyDepartmentPatch(old, patch)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return nil
+}
+
+func (r *mysqlDepartmentRepository) Delete(ctx context.Context, id int, policy DepartmentDeletePolicy, targetID int) ([]Employee, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Блокируем отдел и его сотрудников
+	dept, err := getDepartmentForUpdate(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, `
+            SELECT СЛУ_НОМЕР, СЛУ_ИМЯ, СЛУ_СТАТ, СЛУ_ЗАРП, СЛУ_ОТД_НОМЕР, IMAGE_URL
+            FROM СЛУЖАЩИЕ WHERE СЛУ_ОТД_НОМЕР = ? FOR UPDATE`, id)
+	if err != nil {
+		return nil, err
+	}
+	var emps []Employee
+	var total float64
+	for rows.Next() {
+		var e Employee
+		if err := rows.Scan(&e.ID, &e.Name, &e.Status, &e.Salary, &e.DeptID, &e.ImageURL); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		emps = append(emps, e)
+		total += e.Salary
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	switch policy {
+	case DeleteRefuse:
+		if len(emps) > 0 {
+			return emps, fmt.Errorf("%w: в отделе %d сотрудников", ErrConflict, len(emps))
+		}
+	case DeleteReassign:
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE СЛУЖАЩИЕ SET СЛУ_ОТД_НОМЕР = ? WHERE СЛУ_ОТД_НОМЕР = ?", targetID, id,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка перевода сотрудников: %v", err)
+		}
+		if r.aggregates == aggregatesApp && len(emps) > 0 {
+			if err := adjustDepartment(ctx, tx, targetID, len(emps), total); err != nil {
+				return nil, err
+			}
+		}
+		for _, e := range emps {
+			moved := e
+			moved.DeptID = targetID
+			if err := writeAudit(ctx, tx, auditEmployee, e.ID, auditUpdate, e, moved); err != nil {
+				return nil, err
+			}
+		}
+	case DeleteCascade:
+		// Отделы, которыми руководили удаляемые сотрудники, остаются без руководителя
+		if err := r.resetBosses(ctx, tx, id); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM СЛУЖАЩИЕ WHERE СЛУ_ОТД_НОМЕР = ?", id); err != nil {
+			return nil, fmt.Errorf("ошибка удаления сотрудников: %v", err)
+		}
+		for _, e := range emps {
+			if err := writeAudit(ctx, tx, auditEmployee, e.ID, auditDelete, e, nil); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("неизвестная политика удаления %q", policy)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM ОТДЕЛЫ WHERE ОТД_НОМЕР = ?", id); err != nil {
+		return nil, fmt.Errorf("ошибка удаления отдела: %v", err)
+	}
+	if err := writeAudit(ctx, tx, auditDepartment, id, auditDelete, dept, nil); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return emps, nil
+}
+
+func (r *mysqlDepartmentRepository) Reconcile(ctx context.Context, fix bool) ([]DepartmentDrift, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Фактические значения считаем по СЛУЖАЩИЕ; строки отделов блокируем до конца транзакции
+	rows, err := tx.QueryContext(ctx, `
+        SELECT d.ОТД_НОМЕР, d.ОТД_СОТР_ЗАРП, COALESCE(a.total, 0), d.ОТД_РАЗМ, COALESCE(a.cnt, 0)
+        FROM ОТДЕЛЫ d
+        LEFT JOIN (
+            SELECT СЛУ_ОТД_НОМЕР, SUM(СЛУ_ЗАРП) AS total, COUNT(*) AS cnt
+            FROM СЛУЖАЩИЕ
+            GROUP BY СЛУ_ОТД_НОМЕР
+        ) a ON a.СЛУ_ОТД_НОМЕР = d.ОТД_НОМЕР
+        WHERE d.ОТД_СОТР_ЗАРП <> COALESCE(a.total, 0) OR d.ОТД_РАЗМ <> COALESCE(a.cnt, 0)
+        ORDER BY d.ОТД_НОМЕР
+        FOR UPDATE OF d`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сверки отделов: %v", err)
+	}
+	var drifts []DepartmentDrift
+	for rows.Next() {
+		var d DepartmentDrift
+		if err := rows.Scan(&d.ID, &d.StoredSalary, &d.ActualSalary, &d.StoredSize, &d.ActualSize); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ошибка парсинга данных: %v", err)
+		}
+		drifts = append(drifts, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !fix || len(drifts) == 0 {
+		return drifts, nil
+	}
+	for _, d := range drifts {
+		if _, err := tx.ExecContext(ctx, `
+            UPDATE ОТДЕЛЫ
+            SET ОТД_СОТР_ЗАРП = (SELECT COALESCE(SUM(СЛУ_ЗАРП), 0) FROM СЛУЖАЩИЕ WHERE СЛУ_ОТД_НОМЕР = ?),
+                ОТД_РАЗМ       = (SELECT COUNT(*) FROM СЛУЖАЩИЕ WHERE СЛУ_ОТД_НОМЕР = ?)
+            WHERE ОТД_НОМЕР = ?`,
+			d.ID, d.ID, d.ID,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка исправления отдела %d: %v", d.ID, err)
+		}
+		if err := writeAudit(ctx, tx, auditDepartment, d.ID, auditReconcile,
+			map[string]interface{}{"total_salary": d.StoredSalary, "size": d.StoredSize},
+			map[string]interface{}{"total_salary": d.ActualSalary, "size": d.ActualSize},
+		); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return drifts, nil
+}
+
+// resetBosses сбрасывает руководителя отделов, которыми руководят сотрудники отдела deptID
+func (r *mysqlDepartmentRepository) resetBosses(ctx context.Context, tx *sql.Tx, deptID int) error {
+	rows, err := tx.QueryContext(ctx, `
+            SELECT ОТД_НОМЕР, ОТД_НАЗВ, ОТД_ОПИС, ОТД_ЦФО, ОТД_РУК, ОТД_СОТР_ЗАРП, ОТД_РАЗМ
+            FROM ОТДЕЛЫ
+            WHERE ОТД_РУК IN (SELECT СЛУ_НОМЕР FROM СЛУЖАЩИЕ WHERE СЛУ_ОТД_НОМЕР = ?)
+            FOR UPDATE`, deptID)
+	if err != nil {
+		return fmt.Errorf("ошибка сброса руководителей: %v", err)
+	}
+	var headed []Department
+	for rows.Next() {
+		d, err := scanDepartmentRow(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		headed = append(headed, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, d := range headed {
+		if _, err := tx.ExecContext(ctx, "UPDATE ОТДЕЛЫ SET ОТД_РУК = NULL WHERE ОТД_НОМЕР = ?", d.ID); err != nil {
+			return fmt.Errorf("ошибка сброса руководителей: %v", err)
+		}
+		cur := d
+		cur.BossID = nil
+		if err := writeAudit(ctx, tx, auditDepartment, d.ID, auditUpdate, d, cur); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getDepartmentForUpdate читает строку ОТДЕЛЫ внутри транзакции с блокировкой
+func getDepartmentForUpdate(ctx context.Context, tx *sql.Tx, id int) (Department, error) {
+	d, err := scanDepartmentRow(tx.QueryRowContext(ctx, `
+            SELECT ОТД_НОМЕР, ОТД_НАЗВ, ОТД_ОПИС, ОТД_ЦФО, ОТД_РУК, ОТД_СОТР_ЗАРП, ОТД_РАЗМ
+            FROM ОТДЕЛЫ WHERE ОТД_НОМЕР = ? FOR UPDATE`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return d, ErrNotFound
+	}
+	return d, err
+}
+
+// scanDepartmentRow читает колонки ОТДЕЛЫ без имени руководителя
+func scanDepartmentRow(row rowScanner) (Department, error) {
+	var (
+		d      Department
+		bossID sql.NullInt64
+	)
+	if err := row.Scan(&d.ID, &d.Name, &d.Description, &d.CostCenter, &bossID, &d.TotalSalary, &d.Size); err != nil {
+		return d, err
+	}
+	if bossID.Valid {
+		id := int(bossID.Int64)
+		d.BossID = &id
+	}
+	return d, nil
+}
+
+// writeAudit добавляет запись в audit_log в транзакции изменения
+func writeAudit(ctx context.Context, tx *sql.Tx, entity string, id int, op string, old, cur interface{}) error {
+	entry, ok, err := newAuditEntry(ctx, entity, id, op, old, cur)
+	if err != nil || !ok {
+		return err
+	}
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return fmt.Errorf("ошибка записи аудита: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+            INSERT INTO audit_log (created_at, actor, entity, entity_id, operation, changes)
+            VALUES (?, ?, ?, ?, ?, ?)`,
+		entry.CreatedAt, entry.Actor, entry.Entity, entry.EntityID, entry.Operation, changes,
+	); err != nil {
+		return fmt.Errorf("ошибка записи аудита: %v", err)
+	}
+	return nil
+}
+
+// insertSalaryChange добавляет запись в salary_history и возвращает её ID
+func insertSalaryChange(ctx context.Context, tx *sql.Tx, ch SalaryChange) (int64, error) {
+	res, err := tx.ExecContext(ctx, `
+            INSERT INTO salary_history (emp_id, effective_date, old_salary, new_salary, reason, author, created_at, applied_at)
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		ch.EmployeeID, ch.EffectiveDate, ch.OldSalary, ch.NewSalary, nullIfEmpty(&ch.Reason), ch.Author, ch.CreatedAt, ch.AppliedAt,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка записи истории зарплаты: %v", err)
+	}
+	return res.LastInsertId()
+}
+
+// mysqlSalaryRepository реализация SalaryRepository для MySQL
+type mysqlSalaryRepository struct {
+	db        *sql.DB
+	employees *mysqlEmployeeRepository // агрегаты отделов при применении изменений
+}
+
+// Колонки salary_history в порядке scanSalaryChange
+const salaryChangeColumns = "id, emp_id, effective_date, old_salary, new_salary, reason, author, created_at, applied_at, " +
+	"cancelled_at, cancelled_by, failed_at, fail_reason"
+
+// scanSalaryChange читает строку salaryChangeColumns
+func scanSalaryChange(row rowScanner) (SalaryChange, error) {
+	var (
+		ch                  SalaryChange
+		date                time.Time
+		reason, cancelledBy sql.NullString
+		failReason          sql.NullString
+	)
+	if err := row.Scan(&ch.ID, &ch.EmployeeID, &date, &ch.OldSalary, &ch.NewSalary, &reason, &ch.Author, &ch.CreatedAt,
+		&ch.AppliedAt, &ch.CancelledAt, &cancelledBy, &ch.FailedAt, &failReason); err != nil {
+		return ch, err
+	}
+	ch.EffectiveDate = date.Format(time.DateOnly)
+	ch.Reason = reason.String
+	ch.CancelledBy = cancelledBy.String
+	ch.FailReason = failReason.String
+	return ch, nil
+}
+
+func (r *mysqlSalaryRepository) History(ctx context.Context, empID int) ([]SalaryChange, error) {
+	if err := existsOrNotFound(ctx, r.db, "SELECT 1 FROM СЛУЖАЩИЕ WHERE СЛУ_НОМЕР = ?", empID); err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+salaryChangeColumns+" FROM salary_history WHERE emp_id = ? ORDER BY effective_date, id", empID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []SalaryChange
+	for rows.Next() {
+		ch, err := scanSalaryChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, ch)
+	}
+	return history, rows.Err()
+}
+
+func (r *mysqlSalaryRepository) Schedule(ctx context.Context, ch SalaryChange) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := getEmployeeForUpdate(ctx, tx, ch.EmployeeID); err != nil {
+		return 0, err
+	}
+	ch.OldSalary, ch.AppliedAt, ch.CancelledAt, ch.CancelledBy = nil, nil, nil, ""
+	ch.Author, ch.CreatedAt = actorFrom(ctx), time.Now().UTC()
+	id, err := insertSalaryChange(ctx, tx, ch)
+	if err != nil {
+		return 0, err
+	}
+	ch.ID = id
+	if err := writeAudit(ctx, tx, auditEmployee, ch.EmployeeID, auditSalarySchedule, nil, ch); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return id, nil
+}
+
+func (r *mysqlSalaryRepository) Cancel(ctx context.Context, empID int, id int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	ch, err := scanSalaryChange(tx.QueryRowContext(ctx,
+		"SELECT "+salaryChangeColumns+" FROM salary_history WHERE id = ? AND emp_id = ? FOR UPDATE", id, empID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	if err := cancellable(ch); err != nil {
+		return err
+	}
+	cancelled := ch
+	now := time.Now().UTC()
+	cancelled.CancelledAt, cancelled.CancelledBy = &now, actorFrom(ctx)
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE salary_history SET cancelled_at = ?, cancelled_by = ? WHERE id = ?", cancelled.CancelledAt, cancelled.CancelledBy, id,
+	); err != nil {
+		return fmt.Errorf("ошибка отмены изменения зарплаты: %v", err)
+	}
+	if err := writeAudit(ctx, tx, auditEmployee, empID, auditSalaryCancel, ch, cancelled); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *mysqlSalaryRepository) ApplyDue(ctx context.Context, today string) ([]SalaryChange, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT id FROM salary_history
+        WHERE applied_at IS NULL AND cancelled_at IS NULL AND failed_at IS NULL AND effective_date <= ?
+        ORDER BY effective_date, id`, today)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения запланированных изменений: %v", err)
+	}
+	var due []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due = append(due, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Каждое изменение — в своей транзакции: ошибка одного не задерживает остальные
+	var (
+		applied []SalaryChange
+		errs    []error
+	)
+	for _, id := range due {
+		ch, ok, err := r.applyChange(ctx, id)
+		if err != nil {
+			if ctx.Err() != nil {
+				return applied, ctx.Err()
+			}
+			errs = append(errs, fmt.Errorf("изменение зарплаты %d: %v", id, err))
+			if err := r.markFailed(ctx, id, err); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if ok {
+			applied = append(applied, ch)
+		}
+	}
+	return applied, errors.Join(errs...)
+}
+
+// applyChange применяет запланированное изменение id; ok == false, если его уже
+// применил или заблокировал другой экземпляр сервера либо оно отменено
+func (r *mysqlSalaryRepository) applyChange(ctx context.Context, id int64) (SalaryChange, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return SalaryChange{}, false, fmt.Errorf("ошибка транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	// SKIP LOCKED: несколько экземпляров сервера не применят одно изменение дважды
+	ch, err := scanSalaryChange(tx.QueryRowContext(ctx, "SELECT "+salaryChangeColumns+`
+        FROM salary_history
+        WHERE id = ? AND applied_at IS NULL AND cancelled_at IS NULL AND failed_at IS NULL
+        FOR UPDATE SKIP LOCKED`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ch, false, nil
+	} else if err != nil {
+		return ch, false, err
+	}
+
+	old, err := getEmployeeForUpdate(ctx, tx, ch.EmployeeID)
+	if err != nil {
+		return ch, false, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE СЛУЖАЩИЕ SET СЛУ_ЗАРП = ? WHERE СЛУ_НОМЕР = ?", ch.NewSalary, ch.EmployeeID); err != nil {
+		return ch, false, fmt.Errorf("ошибка обновления зарплаты: %v", err)
+	}
+	cur := old
+	cur.Salary = ch.NewSalary
+	if err := r.employees.updateAggregates(ctx, tx, &old, &cur); err != nil {
+		return ch, false, err
+	}
+
+	now := time.Now().UTC()
+	ch.OldSalary, ch.AppliedAt = &old.Salary, &now
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE salary_history SET old_salary = ?, applied_at = ? WHERE id = ?", ch.OldSalary, ch.AppliedAt, ch.ID,
+	); err != nil {
+		return ch, false, fmt.Errorf("ошибка записи истории зарплаты: %v", err)
+	}
+	audit := appliedSalaryAudit{Employee: cur, SalaryChangeID: ch.ID, ScheduledBy: ch.Author}
+	if err := writeAudit(withActor(ctx, systemActor), tx, auditEmployee, ch.EmployeeID, auditUpdate, old, audit); err != nil {
+		return ch, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ch, false, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return ch, true, nil
+}
+
+// markFailed отмечает изменение id неприменимым с причиной cause
+func (r *mysqlSalaryRepository) markFailed(ctx context.Context, id int64, cause error) error {
+	if _, err := r.db.ExecContext(ctx,
+		"UPDATE salary_history SET failed_at = ?, fail_reason = ? WHERE id = ? AND applied_at IS NULL",
+		time.Now().UTC(), failReason(cause), id,
+	); err != nil {
+		return fmt.Errorf("ошибка отметки изменения зарплаты %d: %v", id, err)
+	}
+	return nil
+}
+
+// mysqlUserRepository реализация UserRepository для MySQL
+type mysqlUserRepository struct {
+	db *sql.DB
+}
+
+// Код ошибки MySQL о нарушении уникального ключа
+const mysqlDuplicateEntry = 1062
+
+func (r *mysqlUserRepository) GetByLogin(ctx context.Context, login string) (User, error) {
+	var u User
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id, login, password_hash, role, emp_id, created_at FROM users WHERE login = ?", login,
+	).Scan(&u.ID, &u.Login, &u.PasswordHash, &u.Role, &u.EmpID, &u.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return u, ErrNotFound
+	}
+	return u, err
+}
+
+func (r *mysqlUserRepository) Create(ctx context.Context, u User) (int, error) {
+	res, err := r.db.ExecContext(ctx,
+		"INSERT INTO users (login, password_hash, role, emp_id) VALUES (?, ?, ?, ?)", u.Login, u.PasswordHash, u.Role, u.EmpID)
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) && myErr.Number == mysqlDuplicateEntry {
+		return 0, fmt.Errorf("%w: логин %q занят", ErrConflict, u.Login)
+	} else if err != nil {
+		return 0, fmt.Errorf("ошибка создания пользователя: %v", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения ID пользователя: %v", err)
+	}
+	return int(id), nil
+}
+
+func (r *mysqlUserRepository) SaveRefreshToken(ctx context.Context, t RefreshToken) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO refresh_tokens (token_hash, user_id, expires_at) VALUES (?, ?, ?)", t.Hash, t.UserID, t.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения refresh-токена: %v", err)
+	}
+	return nil
+}
+
+func (r *mysqlUserRepository) UseRefreshToken(ctx context.Context, hash string) (User, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return User{}, fmt.Errorf("ошибка транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		u         User
+		expiresAt time.Time
+		revokedAt sql.NullTime
+	)
+	err = tx.QueryRowContext(ctx, `
+        SELECT u.id, u.login, u.password_hash, u.role, u.emp_id, u.created_at, t.expires_at, t.revoked_at
+        FROM refresh_tokens t
+        JOIN users u ON u.id = t.user_id
+        WHERE t.token_hash = ?
+        FOR UPDATE`, hash,
+	).Scan(&u.ID, &u.Login, &u.PasswordHash, &u.Role, &u.EmpID, &u.CreatedAt, &expiresAt, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return u, ErrNotFound
+	} else if err != nil {
+		return u, err
+	}
+
+	now := time.Now().UTC()
+	switch {
+	case revokedAt.Valid:
+		// Токен уже использован: вероятна утечка, отзываем все сессии пользователя
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL", now, u.ID,
+		); err != nil {
+			return u, fmt.Errorf("ошибка отзыва refresh-токенов: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return u, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+		}
+		return u, ErrNotFound
+	case !now.Before(expiresAt):
+		return u, ErrNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = ? WHERE token_hash = ?", now, hash); err != nil {
+		return u, fmt.Errorf("ошибка отзыва refresh-токена: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return u, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return u, nil
+}
+
+func (r *mysqlUserRepository) RevokeRefreshToken(ctx context.Context, hash string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = ? WHERE token_hash = ? AND revoked_at IS NULL", time.Now().UTC(), hash)
+	if err != nil {
+		return fmt.Errorf("ошибка отзыва refresh-токена: %v", err)
+	}
+	return nil
+}
+
+// mysqlAuditRepository реализация AuditRepository для MySQL
+type mysqlAuditRepository struct {
+	db *sql.DB
+}
+
+func (r *mysqlAuditRepository) List(ctx context.Context, q AuditQuery) ([]AuditEntry, int, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	if q.Entity != "" {
+		where = append(where, "entity = ?")
+		args = append(args, q.Entity)
+	}
+	if q.EntityID != nil {
+		where = append(where, "entity_id = ?")
+		args = append(args, *q.EntityID)
+	}
+	if q.Actor != "" {
+		where = append(where, "actor = ?")
+		args = append(args, q.Actor)
+	}
+	if q.From != nil {
+		where = append(where, "created_at >= ?")
+		args = append(args, *q.From)
+	}
+	if q.To != nil {
+		where = append(where, "created_at < ?")
+		args = append(args, *q.To)
+	}
+	filter := ""
+	if len(where) > 0 {
+		filter = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_log"+filter, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT id, created_at, actor, entity, entity_id, operation, changes FROM audit_log" + filter + " ORDER BY id DESC"
+	if q.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, q.Limit, q.Offset)
+	}
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var (
+			e       AuditEntry
+			changes []byte
+		)
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.Actor, &e.Entity, &e.EntityID, &e.Operation, &changes); err != nil {
+			return nil, 0, err
+		}
+		if err := json.Unmarshal(changes, &e.Changes); err != nil {
+			return nil, 0, fmt.Errorf("ошибка разбора записи аудита %d: %v", e.ID, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
+
+// mysqlTemplateRepository реализация TemplateRepository для MySQL
+type mysqlTemplateRepository struct {
+	db *sql.DB
+}
+
+func (r *mysqlTemplateRepository) Create(ctx context.Context, t ReportTemplate) (ReportTemplate, error) {
+	// Номер версии вычисляется в том же запросе; одновременная загрузка
+	// под тем же именем упрётся в uq_report_templates_version
+	res, err := r.db.ExecContext(ctx, `
+        INSERT INTO report_templates (name, version, content, created_by)
+        SELECT ?, COALESCE(MAX(version), 0) + 1, ?, ? FROM report_templates WHERE name = ?`,
+		t.Name, t.Content, actorFrom(ctx), t.Name)
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) && myErr.Number == mysqlDuplicateEntry {
+		return t, fmt.Errorf("%w: шаблон %q загружается одновременно, повторите запрос", ErrConflict, t.Name)
+	} else if err != nil {
+		return t, fmt.Errorf("ошибка сохранения шаблона: %v", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return t, fmt.Errorf("ошибка получения ID шаблона: %v", err)
+	}
+
+	var saved ReportTemplate
+	err = r.db.QueryRowContext(ctx,
+		"SELECT id, name, version, LENGTH(content), created_by, created_at FROM report_templates WHERE id = ?", id,
+	).Scan(&saved.ID, &saved.Name, &saved.Version, &saved.Size, &saved.CreatedBy, &saved.CreatedAt)
+	if err != nil {
+		return t, fmt.Errorf("ошибка чтения шаблона: %v", err)
+	}
+	return saved, nil
+}
+
+func (r *mysqlTemplateRepository) Get(ctx context.Context, name string, version int) (ReportTemplate, error) {
+	query := "SELECT id, name, version, content, created_by, created_at FROM report_templates WHERE name = ?"
+	args := []interface{}{name}
+	if version > 0 {
+		query += " AND version = ?"
+		args = append(args, version)
+	} else {
+		query += " ORDER BY version DESC LIMIT 1"
+	}
+
+	var t ReportTemplate
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&t.ID, &t.Name, &t.Version, &t.Content, &t.CreatedBy, &t.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, ErrNotFound
+	} else if err != nil {
+		return t, fmt.Errorf("ошибка запроса шаблона: %v", err)
+	}
+	t.Size = len(t.Content)
+	return t, nil
+}
+
+func (r *mysqlTemplateRepository) List(ctx context.Context) ([]ReportTemplate, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT id, name, version, LENGTH(content), created_by, created_at
+        FROM report_templates ORDER BY name, version DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса шаблонов: %v", err)
+	}
+	defer rows.Close()
+
+	list := []ReportTemplate{}
+	for rows.Next() {
+		var t ReportTemplate
+		if err := rows.Scan(&t.ID, &t.Name, &t.Version, &t.Size, &t.CreatedBy, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка чтения шаблона: %v", err)
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+// rowScanner общий интерфейс *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanDepartment читает строку selectDepartments
+func scanDepartment(row rowScanner) (Department, error) {
+	var (
+		d      Department
+		bossID sql.NullInt64
+		name   sql.NullString
+	)
+	if err := row.Scan(&d.ID, &d.Name, &d.Description, &d.CostCenter, &bossID, &name, &d.TotalSalary, &d.Size); err != nil {
+		return d, err
+	}
+	if bossID.Valid {
+		id := int(bossID.Int64)
+		d.BossID = &id
+	}
+	if name.Valid {
+		d.BossName = &name.String
+	}
+	return d, nil
+}
+
+// nullIfEmpty превращает nil и "" в NULL
+func nullIfEmpty(s *string) interface{} {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return *s
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Как часто проверять наступившие запланированные изменения зарплат
+const salaryScheduleInterval = time.Minute
+
+// today текущая дата сервера в формате YYYY-MM-DD
+func today() string {
+	return time.Now().Format(time.DateOnly)
+}
+
+// newSalaryChange запись о немедленном изменении зарплаты (old == nil — приём на работу)
+func newSalaryChange(ctx context.Context, empID int, old *float64, salary float64) SalaryChange {
+	now := time.Now().UTC()
+	return SalaryChange{
+		EmployeeID:    empID,
+		EffectiveDate: today(),
+		OldSalary:     old,
+		NewSalary:     salary,
+		Reason:        reasonFrom(ctx),
+		Author:        actorFrom(ctx),
+		CreatedAt:     now,
+		AppliedAt:     &now,
+	}
+}
+
+// validateScheduledChange проверяет зарплату и дату планируемого изменения
+func validateScheduledChange(ch SalaryChange) error {
+	if ch.NewSalary < 0 {
+		return fmt.Errorf("зарплата не может быть отрицательной")
+	}
+	if len([]rune(ch.Reason)) > 255 {
+		return fmt.Errorf("причина длиннее 255 символов")
+	}
+	date, err := time.Parse(time.DateOnly, ch.EffectiveDate)
+	if err != nil {
+		return fmt.Errorf("некорректная effective_date: ожидается YYYY-MM-DD")
+	}
+	// Изменение с сегодняшней датой выполняется сразу через PUT /api/employees/:id
+	if date.Format(time.DateOnly) <= today() {
+		return fmt.Errorf("effective_date должна быть в будущем")
+	}
+	return nil
+}
+
+// cancellable ErrConflict, если изменение уже применено или отменено
+func cancellable(ch SalaryChange) error {
+	if ch.AppliedAt != nil {
+		return fmt.Errorf("%w: изменение зарплаты %d уже применено", ErrConflict, ch.ID)
+	}
+	if ch.CancelledAt != nil {
+		return fmt.Errorf("%w: изменение зарплаты %d уже отменено", ErrConflict, ch.ID)
+	}
+	return nil
+}
+
+// appliedSalaryAudit состояние сотрудника для журнала после применения запланированного
+// изменения: исполнитель — systemActor, автор и ID изменения попадают в список изменений
+type appliedSalaryAudit struct {
+	Employee
+	SalaryChangeID int64  `json:"salary_change_id"`
+	ScheduledBy    string `json:"scheduled_by"`
+}
+
+// Предел длины fail_reason в salary_history
+const maxFailReason = 1024
+
+// failReason причина неудачного применения изменения, усечённая до maxFailReason символов
+func failReason(err error) string {
+	reason := []rune(err.Error())
+	if len(reason) > maxFailReason {
+		reason = reason[:maxFailReason]
+	}
+	return string(reason)
+}
+
+// runSalaryScheduler применяет наступившие изменения зарплат при запуске
+// и затем каждые salaryScheduleInterval, пока не отменён ctx
+func runSalaryScheduler(ctx context.Context, salaries SalaryRepository) {
+	ticker := time.NewTicker(salaryScheduleInterval)
+	defer ticker.Stop()
+	for {
+		applied, err := salaries.ApplyDue(ctx, today())
+		if err != nil {
+			log.Printf("Ошибка применения запланированных изменений зарплат: %v", err)
+		}
+		for _, ch := range applied {
+			log.Printf("Применено изменение зарплаты %d сотрудника %d: %.2f -> %.2f",
+				ch.ID, ch.EmployeeID, *ch.OldSalary, ch.NewSalary)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
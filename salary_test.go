@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateScheduledChange(t *testing.T) {
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(time.DateOnly)
+
+	tests := []struct {
+		name string
+		ch   SalaryChange
+		ok   bool
+	}{
+		{"завтра", SalaryChange{NewSalary: 100, EffectiveDate: tomorrow}, true},
+		{"сегодня", SalaryChange{NewSalary: 100, EffectiveDate: today()}, false},
+		{"в прошлом", SalaryChange{NewSalary: 100, EffectiveDate: "2000-01-01"}, false},
+		{"некорректная дата", SalaryChange{NewSalary: 100, EffectiveDate: "01.01.2100"}, false},
+		{"отрицательная зарплата", SalaryChange{NewSalary: -1, EffectiveDate: tomorrow}, false},
+		{"длинная причина", SalaryChange{NewSalary: 100, EffectiveDate: tomorrow, Reason: strings.Repeat("я", 256)}, false},
+		{"причина 255 символов", SalaryChange{NewSalary: 100, EffectiveDate: tomorrow, Reason: strings.Repeat("я", 255)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateScheduledChange(tt.ch); (err == nil) != tt.ok {
+				t.Fatalf("ошибка %v", err)
+			}
+		})
+	}
+}
+
+func TestApplyDueSalaryChanges(t *testing.T) {
+	repos := newMemoryRepositories()
+	ctx := withActor(context.Background(), "user:hr")
+	dept := seedDepartments(t, repos, "Отдел")[0]
+	id, _ := repos.Employees.Create(ctx, Employee{Name: "Сотрудник", Salary: 1000, DeptID: dept})
+
+	for _, ch := range []SalaryChange{
+		{EmployeeID: id, EffectiveDate: "2030-03-01", NewSalary: 3000},
+		{EmployeeID: id, EffectiveDate: "2030-01-01", NewSalary: 2000},
+		{EmployeeID: id, EffectiveDate: "2031-01-01", NewSalary: 9000},
+	} {
+		if _, err := repos.Salaries.Schedule(ctx, ch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Наступившие изменения применяются по дате: итог — последнее из них
+	applied, err := repos.Salaries.ApplyDue(context.Background(), "2030-06-01")
+	if err != nil || len(applied) != 2 {
+		t.Fatalf("применено %+v: %v", applied, err)
+	}
+	if *applied[0].OldSalary != 1000 || *applied[1].OldSalary != 2000 || applied[1].AppliedAt == nil {
+		t.Fatalf("применено %+v", applied)
+	}
+	e, _ := repos.Employees.Get(ctx, id)
+	d, _ := repos.Departments.Get(ctx, dept)
+	if e.Salary != 3000 || d.TotalSalary != 3000 {
+		t.Fatalf("зарплата %v, фонд отдела %v, ожидалось 3000", e.Salary, d.TotalSalary)
+	}
+
+	// Изменение вносит сервер; автор запланированного изменения — в списке изменений
+	entries, _, _ := repos.Audit.List(ctx, AuditQuery{Entity: auditEmployee, EntityID: &id})
+	if last := entries[0]; last.Actor != systemActor || string(last.Changes["scheduled_by"].New) != `"user:hr"` ||
+		string(last.Changes["salary"].New) != "3000" || last.Changes["salary_change_id"].New == nil {
+		t.Fatalf("запись журнала %+v", last)
+	}
+
+	if applied, _ := repos.Salaries.ApplyDue(ctx, "2030-06-01"); len(applied) != 0 {
+		t.Fatalf("повторно применено %+v", applied)
+	}
+	history, _ := repos.Salaries.History(ctx, id)
+	if len(history) != 4 || history[3].EffectiveDate != "2031-01-01" || history[3].AppliedAt != nil {
+		t.Fatalf("история %+v", history)
+	}
+}
+
+func TestRunSalaryScheduler(t *testing.T) {
+	repos := newMemoryRepositories()
+	ctx := context.Background()
+	dept := seedDepartments(t, repos, "Отдел")[0]
+	id, _ := repos.Employees.Create(ctx, Employee{Name: "Сотрудник", Salary: 1000, DeptID: dept})
+	if _, err := repos.Salaries.Schedule(ctx, SalaryChange{EmployeeID: id, EffectiveDate: "2000-01-01", NewSalary: 1500}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Отменённый контекст: планировщик выполняет проверку при запуске и завершается
+	stopped, cancel := context.WithCancel(ctx)
+	cancel()
+	runSalaryScheduler(stopped, repos.Salaries)
+
+	if e, _ := repos.Employees.Get(ctx, id); e.Salary != 1500 {
+		t.Fatalf("зарплата %v, ожидалось 1500", e.Salary)
+	}
+}
+
+func TestCancelSalaryChange(t *testing.T) {
+	repos := newMemoryRepositories()
+	ctx := withActor(context.Background(), "user:hr")
+	dept := seedDepartments(t, repos, "Отдел")[0]
+	id, _ := repos.Employees.Create(ctx, Employee{Name: "Сотрудник", Salary: 1000, DeptID: dept})
+	changeID, err := repos.Salaries.Schedule(ctx, SalaryChange{EmployeeID: id, EffectiveDate: "2030-01-01", NewSalary: 2000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repos.Salaries.Cancel(withActor(ctx, "user:anna"), id, changeID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Salaries.Cancel(ctx, id, changeID); !errors.Is(err, ErrConflict) {
+		t.Fatalf("повторная отмена: %v", err)
+	}
+	if err := repos.Salaries.Cancel(ctx, id+1, changeID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("отмена чужого изменения: %v", err)
+	}
+
+	// Отменённое изменение не применяется
+	if applied, _ := repos.Salaries.ApplyDue(ctx, "2030-06-01"); len(applied) != 0 {
+		t.Fatalf("применено %+v", applied)
+	}
+	if e, _ := repos.Employees.Get(ctx, id); e.Salary != 1000 {
+		t.Fatalf("зарплата %v", e.Salary)
+	}
+
+	// Планирование и отмена — в журнале аудита
+	entries, _, _ := repos.Audit.List(ctx, AuditQuery{Entity: auditEmployee, EntityID: &id})
+	if len(entries) != 3 {
+		t.Fatalf("журнал %+v", entries)
+	}
+	cancel, schedule := entries[0], entries[1]
+	if schedule.Operation != auditSalarySchedule || schedule.Actor != "user:hr" || string(schedule.Changes["new_salary"].New) != "2000" {
+		t.Fatalf("запись о планировании %+v", schedule)
+	}
+	if cancel.Operation != auditSalaryCancel || cancel.Actor != "user:anna" || string(cancel.Changes["cancelled_by"].New) != `"user:anna"` {
+		t.Fatalf("запись об отмене %+v", cancel)
+	}
+}
+
+func TestApplyDueSkipsFailingChanges(t *testing.T) {
+	repos := newMemoryRepositories()
+	ctx := context.Background()
+	ids := seedDepartments(t, repos, "Первый", "Второй")
+	broken, _ := repos.Employees.Create(ctx, Employee{Name: "Первый", Salary: 1000, DeptID: ids[0]})
+	ok, _ := repos.Employees.Create(ctx, Employee{Name: "Второй", Salary: 1000, DeptID: ids[1]})
+	bad, _ := repos.Salaries.Schedule(ctx, SalaryChange{EmployeeID: broken, EffectiveDate: "2030-01-01", NewSalary: 2000})
+	repos.Salaries.Schedule(ctx, SalaryChange{EmployeeID: ok, EffectiveDate: "2030-02-01", NewSalary: 3000})
+
+	// Отдел первого сотрудника пропал в обход репозитория: его изменение не применить
+	store := repos.Departments.(*memoryDepartmentRepository).s
+	delete(store.departments, ids[0])
+
+	applied, err := repos.Salaries.ApplyDue(ctx, "2030-06-01")
+	if err == nil || !strings.Contains(err.Error(), "изменение зарплаты "+strconv.FormatInt(bad, 10)) {
+		t.Fatalf("ошибка %v", err)
+	}
+	if len(applied) != 1 || applied[0].EmployeeID != ok {
+		t.Fatalf("применено %+v", applied)
+	}
+	if e, _ := repos.Employees.Get(ctx, broken); e.Salary != 1000 {
+		t.Fatalf("зарплата после неудачного изменения %v", e.Salary)
+	}
+
+	// Неудачное изменение отмечено и больше не применяется
+	history, _ := repos.Salaries.History(ctx, broken)
+	if last := history[len(history)-1]; last.FailedAt == nil || last.FailReason == "" || last.AppliedAt != nil {
+		t.Fatalf("неудачное изменение %+v", last)
+	}
+	if applied, err := repos.Salaries.ApplyDue(ctx, "2030-06-01"); err != nil || len(applied) != 0 {
+		t.Fatalf("повторно применено %+v: %v", applied, err)
+	}
+}
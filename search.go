@@ -0,0 +1,250 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Ограничения выдачи GET /api/employees/search
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	// Кандидатов, которых репозиторий отбирает для ранжирования (SearchCandidates)
+	searchCandidateLimit = 500
+)
+
+// TextRange диапазон символов [Start, End) в исходной строке (в рунах)
+type TextRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// EmployeeSearchResult найденный сотрудник с релевантностью и подсветкой
+type EmployeeSearchResult struct {
+	Employee   Employee    `json:"employee"`
+	Score      float64     `json:"score"`      // от 0 до 1, больше — релевантнее
+	Highlights []TextRange `json:"highlights"` // совпавшие участки Employee.Name
+}
+
+// Транслитерация кириллицы в латиницу. Имена и запрос приводятся к латинице,
+// поэтому "Ivanov" находит "Иванов", а "ё" и "е" совпадают.
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+// searchToken слово в нормализованном виде.
+// src[i] — индекс руны исходной строки, из которой получена руна key[i].
+type searchToken struct {
+	key []rune
+	src []int
+}
+
+// searchTokens разбивает строку на слова и нормализует их:
+// нижний регистр, ё -> е, кириллица -> латиница
+func searchTokens(s string) []searchToken {
+	var (
+		tokens []searchToken
+		cur    searchToken
+	)
+	flush := func() {
+		if len(cur.key) > 0 {
+			tokens = append(tokens, cur)
+		}
+		cur = searchToken{}
+	}
+	for i, r := range []rune(s) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		r = unicode.ToLower(r)
+		latin, ok := cyrillicToLatin[r]
+		if !ok {
+			latin = string(r)
+		}
+		for _, lr := range latin {
+			cur.key = append(cur.key, lr)
+			cur.src = append(cur.src, i)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// searchKey нормализованное имя для отбора кандидатов в БД: слова searchTokens через пробел
+func searchKey(s string) string {
+	tokens := searchTokens(s)
+	words := make([]string, len(tokens))
+	for i, t := range tokens {
+		words[i] = string(t.key)
+	}
+	return strings.Join(words, " ")
+}
+
+// searchBigrams биграммы слов нормализованной строки, как их строит парсер ngram MySQL
+func searchBigrams(key string) map[string]bool {
+	grams := map[string]bool{}
+	for _, word := range strings.Fields(key) {
+		r := []rune(word)
+		for i := 0; i+2 <= len(r); i++ {
+			grams[string(r[i:i+2])] = true
+		}
+	}
+	return grams
+}
+
+// sourceRange переводит диапазон [from, to) нормализованного слова в диапазон исходной строки
+func (t searchToken) sourceRange(from, to int) TextRange {
+	if to > len(t.src) {
+		to = len(t.src)
+	}
+	return TextRange{Start: t.src[from], End: t.src[to-1] + 1}
+}
+
+// maxTypos допустимое число опечаток для слова запроса длины n
+func maxTypos(n int) int {
+	switch {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// matchToken оценивает совпадение слова запроса q со словом имени t.
+// Возвращает оценку (0 — нет совпадения) и совпавший диапазон t.
+func matchToken(q, t searchToken) (float64, int, int) {
+	qs, ts := string(q.key), string(t.key)
+	switch {
+	case qs == ts:
+		return 1, 0, len(t.key)
+	case strings.HasPrefix(ts, qs):
+		return 0.8 + 0.2*float64(len(q.key))/float64(len(t.key)), 0, len(q.key)
+	}
+	if idx := strings.Index(ts, qs); idx >= 0 {
+		start := len([]rune(ts[:idx]))
+		return 0.6, start, start + len(q.key)
+	}
+
+	// Опечатки: сравниваем с самим словом, затем с его началом близкой длины
+	// (начало слова оценивается ниже целого слова с тем же числом опечаток)
+	limit := maxTypos(len(q.key))
+	if limit == 0 {
+		return 0, 0, 0
+	}
+	best, bestLen, penalty := editDistance(q.key, t.key), len(t.key), 0.0
+	for n := len(q.key) - 1; n <= len(q.key)+1; n++ {
+		if n <= 0 || n >= len(t.key) {
+			continue
+		}
+		if d := editDistance(q.key, t.key[:n]); d < best {
+			best, bestLen, penalty = d, n, 0.05
+		}
+	}
+	if best > limit {
+		return 0, 0, 0
+	}
+	return 0.5 - 0.1*float64(best) - penalty, 0, bestLen
+}
+
+// editDistance расстояние Дамерау–Левенштейна (с перестановкой соседних символов)
+func editDistance(a, b []rune) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+// searchEmployees ранжирует сотрудников по запросу.
+// Каждое слово запроса должно совпасть с каким-либо словом имени (точно, по началу,
+// как подстрока или с опечатками); оценка — среднее по словам запроса.
+func searchEmployees(emps []Employee, query string, limit int) []EmployeeSearchResult {
+	queryTokens := searchTokens(query)
+	if len(queryTokens) == 0 {
+		return nil
+	}
+
+	var results []EmployeeSearchResult
+	for _, e := range emps {
+		nameTokens := searchTokens(e.Name)
+		total := 0.0
+		var highlights []TextRange
+		for _, q := range queryTokens {
+			best, bestRange := 0.0, TextRange{}
+			for _, t := range nameTokens {
+				if score, from, to := matchToken(q, t); score > best {
+					best, bestRange = score, t.sourceRange(from, to)
+				}
+			}
+			if best == 0 {
+				total = 0
+				break
+			}
+			total += best
+			highlights = append(highlights, bestRange)
+		}
+		if total == 0 {
+			continue
+		}
+		results = append(results, EmployeeSearchResult{
+			Employee:   e,
+			Score:      math.Round(total/float64(len(queryTokens))*1000) / 1000,
+			Highlights: mergeRanges(highlights),
+		})
+	}
+
+	// Сначала релевантные, затем короткие имена, затем по ID
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if la, lb := len([]rune(a.Employee.Name)), len([]rune(b.Employee.Name)); la != lb {
+			return la < lb
+		}
+		return a.Employee.ID < b.Employee.ID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// mergeRanges сортирует и объединяет пересекающиеся диапазоны
+func mergeRanges(ranges []TextRange) []TextRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	var merged []TextRange
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && r.Start <= merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, r.End)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchKey(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Иванов Пётр", "ivanov petr"},
+		{"ЁЛКИН-Щукин", "elkin shchukin"},
+		{"Объедков Юрий", "obedkov yuriy"},
+		{"  John  O'Neil ", "john o neil"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := searchKey(tt.in); got != tt.want {
+			t.Errorf("searchKey(%q) = %q, ожидалось %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSearchBigrams(t *testing.T) {
+	got := searchBigrams("ivan a")
+	want := map[string]bool{"iv": true, "va": true, "an": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("searchBigrams = %v, ожидалось %v", got, want)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"ivanov", "ivanov", 0},
+		{"ivanov", "ivanv", 1},   // пропуск
+		{"ivanov", "ivanova", 1}, // вставка
+		{"ivanov", "ivonov", 1},  // замена
+		{"ivanov", "iavnov", 1},  // перестановка соседних
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, ожидалось %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMatchToken(t *testing.T) {
+	tests := []struct {
+		query, word string
+		score       float64
+	}{
+		{"ivanov", "ivanov", 1},
+		{"ivan", "ivanov", 0.8 + 0.2*4/6},
+		{"vano", "ivanov", 0.6},
+		{"ivonov", "ivanov", 0.4},       // одна опечатка
+		{"iavnvo", "ivanov", 0},         // две опечатки в слове из 6 букв — много
+		{"petorvihc", "petrovich", 0.3}, // две опечатки в длинном слове
+		{"ivanv", "ivanovich", 0.35},    // опечатка в начале слова — ниже целого слова
+		{"ivn", "ivanov", 0},            // в коротком слове опечатки не допускаются
+		{"petrov", "ivanov", 0},
+	}
+	for _, tt := range tests {
+		q, w := searchTokens(tt.query)[0], searchTokens(tt.word)[0]
+		score, _, _ := matchToken(q, w)
+		if diff := score - tt.score; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("matchToken(%q, %q) = %v, ожидалось %v", tt.query, tt.word, score, tt.score)
+		}
+	}
+}
+
+func TestSearchEmployees(t *testing.T) {
+	emps := []Employee{
+		{ID: 1, Name: "Иванова Анна"},
+		{ID: 2, Name: "Иванов Пётр"},
+		{ID: 3, Name: "Петров Иван"},
+		{ID: 4, Name: "Сидоров Олег"},
+	}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"Иванов", []int{2, 1}}, // точное совпадение, затем начало слова; «Иван» — две опечатки
+		{"ivanov petr", []int{2}},
+		{"иванов пётр", []int{2}},
+		{"ivnaov", []int{2, 1}},    // перестановка
+		{"петр иван", []int{2, 3}}, // равные оценки и длины — по ID
+		{"vano", []int{2, 1}},      // подстрока
+		{"олег", []int{4}},
+		{"zzz", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results := searchEmployees(emps, tt.query, 10)
+			var got []int
+			for _, r := range results {
+				got = append(got, r.Employee.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("найдены %v, ожидалось %v (%+v)", got, tt.want, results)
+			}
+		})
+	}
+
+	// Подсветка — в рунах исходного имени, включая «ё», ставшую «e»
+	results := searchEmployees(emps, "пётр", 1)
+	if len(results) != 1 || !reflect.DeepEqual(results[0].Highlights, []TextRange{{Start: 7, End: 11}}) {
+		t.Fatalf("подсветка %+v", results)
+	}
+}
+
+func TestMergeRanges(t *testing.T) {
+	got := mergeRanges([]TextRange{{5, 8}, {0, 2}, {7, 10}, {2, 3}})
+	want := []TextRange{{0, 3}, {5, 10}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("mergeRanges = %v, ожидалось %v", got, want)
+	}
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unioffice/v2/color"
+	"github.com/unidoc/unioffice/v2/measurement"
+	"github.com/unidoc/unioffice/v2/schema/soo/sml"
+	"github.com/unidoc/unioffice/v2/spreadsheet"
+)
+
+// Цвета таблиц те же, что в setupDocument
+const (
+	sheetColorHeader      = "4F81BD" // фон шапки и рамка
+	sheetColorAlternative = "E7E6E6" // фон чётных строк
+	sheetColorBoss        = "E6F0FA" // фон строки руководителя
+	sheetColorBossText    = "2E74B5"
+	sheetColorDrift       = "C00000" // расхождение ОТДЕЛЫ и СОТРУДНИКИ
+	sheetMoneyFormat      = "#,##0.00"
+)
+
+// sheetStyle оформление ячейки; одинаковые оформления используют один стиль книги
+type sheetStyle struct {
+	background string // "" — без заливки
+	text       string // "" — чёрный
+	bold       bool
+	money      bool // числовой формат денежной суммы
+}
+
+// workbookWriter книга XLSX, в которую раскладывается отчёт (см. layoutSpreadsheet).
+// Реализации: unioffice (unioWorkbook) и собственная запись OOXML (ooxmlWorkbook).
+type workbookWriter interface {
+	// addSheet добавляет лист; порядок листов — порядок добавления
+	addSheet(name string) sheetWriter
+	save() (bytes.Buffer, error)
+}
+
+// sheetWriter лист книги: строки заполняются сверху вниз, ячейки — слева направо
+type sheetWriter interface {
+	// addRow начинает строку и возвращает её номер (с 1)
+	addRow() int
+	text(s string, st sheetStyle)
+	number(v float64, st sheetStyle)
+	// formula записывает формулу с уже вычисленным значением:
+	// программы, не пересчитывающие книгу при открытии, покажут его
+	formula(f string, value float64, st sheetStyle)
+	// skip пропускает ячейку
+	skip()
+	// setup ширина столбцов в сантиметрах (с A), закреплённая шапка и колонтитулы для печати
+	setup(widths []float64, meta documentMeta)
+}
+
+// setupSpreadsheet формирует XLSX средствами unioffice (см. layoutSpreadsheet)
+func setupSpreadsheet(reports []departmentReport, meta documentMeta) (bytes.Buffer, error) {
+	wb := spreadsheet.New()
+	defer wb.Close()
+	book := &unioWorkbook{wb: wb, styles: &sheetStyles{wb: wb, cache: map[sheetStyle]spreadsheet.CellStyle{}}}
+	layoutSpreadsheet(book, reports, meta)
+	return book.save()
+}
+
+// layoutSpreadsheet раскладывает отчёт по листам: сводный лист и по листу на каждый отдел.
+// Суммы — числовые ячейки с формулами; скрытые зарплаты (SalariesVisible == false)
+// выводятся строками-диапазонами и в формулы не попадают.
+// Колонтитулы из meta видны при печати; водяных знаков в XLSX нет,
+// поэтому meta.Watermark выводится в центре верхнего колонтитула.
+func layoutSpreadsheet(wb workbookWriter, reports []departmentReport, meta documentMeta) {
+	summary := wb.addSheet("Сводка")
+
+	// Строки листа отдела с итогами: на них ссылается сводка
+	type totalsRef struct {
+		sheet            string
+		countRow, sumRow int
+	}
+	refs := make([]totalsRef, 0, len(reports))
+	for _, r := range reports {
+		name := fmt.Sprintf("Отдел %d", r.DeptID)
+		countRow, sumRow := fillDepartmentSheet(wb.addSheet(name), r, meta)
+		refs = append(refs, totalsRef{sheet: name, countRow: countRow, sumRow: sumRow})
+	}
+
+	// Сводка: ожидаемые значения (ОТДЕЛЫ) против фактических (формулы по листам отделов)
+	summary.addRow()
+	for _, txt := range []string{"ID", "Отдел", "ОТД_СОТР_ЗАРП", "Сумма окладов", "Расхождение", "ОТД_РАЗМ", "Сотрудников", "Расхождение"} {
+		summary.text(txt, sheetStyle{background: sheetColorHeader, text: "FFFFFF", bold: true})
+	}
+
+	allVisible := true
+	for i, r := range reports {
+		ref := refs[i]
+		n := summary.addRow()
+		drift := r.ExpectedCount != r.ActualCount || r.SalariesVisible && r.ExpectedSalary != r.ActualSalary
+		st := sheetStyle{}
+		if drift {
+			st.text, st.bold = sheetColorDrift, true
+		}
+		money := st
+		money.money = true
+
+		summary.number(float64(r.DeptID), st)
+		summary.text(r.DeptName, st)
+		if r.SalariesVisible {
+			summary.number(r.ExpectedSalary, money)
+			summary.formula(fmt.Sprintf("'%s'!D%d", ref.sheet, ref.sumRow), r.ActualSalary, money)
+			summary.formula(fmt.Sprintf("C%d-D%d", n, n), r.ExpectedSalary-r.ActualSalary, money)
+		} else {
+			allVisible = false
+			summary.text(r.FormatSalary(r.ExpectedSalary), st)
+			summary.text(r.FormatSalary(r.ActualSalary), st)
+			summary.text("—", st)
+		}
+		summary.number(float64(r.ExpectedCount), st)
+		summary.formula(fmt.Sprintf("'%s'!D%d", ref.sheet, ref.countRow), float64(r.ActualCount), st)
+		summary.formula(fmt.Sprintf("F%d-G%d", n, n), float64(r.ExpectedCount-r.ActualCount), st)
+	}
+
+	// Итоги по компании; суммы зарплат — только если видны все отделы
+	if len(reports) > 0 {
+		last := len(reports) + 1
+		var expectedSalary, actualSalary float64
+		var expectedCount, actualCount int
+		for _, r := range reports {
+			expectedSalary += r.ExpectedSalary
+			actualSalary += r.ActualSalary
+			expectedCount += r.ExpectedCount
+			actualCount += r.ActualCount
+		}
+		st := sheetStyle{background: sheetColorAlternative, bold: true}
+		money := st
+		money.money = true
+
+		n := summary.addRow()
+		summary.text("", st)
+		summary.text("Итого", st)
+		if allVisible {
+			summary.formula(fmt.Sprintf("SUM(C2:C%d)", last), expectedSalary, money)
+			summary.formula(fmt.Sprintf("SUM(D2:D%d)", last), actualSalary, money)
+			summary.formula(fmt.Sprintf("C%d-D%d", n, n), expectedSalary-actualSalary, money)
+		} else {
+			for range 3 {
+				summary.text("—", st)
+			}
+		}
+		summary.formula(fmt.Sprintf("SUM(F2:F%d)", last), float64(expectedCount), st)
+		summary.formula(fmt.Sprintf("SUM(G2:G%d)", last), float64(actualCount), st)
+		summary.formula(fmt.Sprintf("F%d-G%d", n, n), float64(expectedCount-actualCount), st)
+	}
+	summary.setup([]float64{1.5, 6, 3.5, 3.5, 3.5, 2.5, 2.5, 3}, meta)
+}
+
+// fillDepartmentSheet заполняет лист отдела: таблица сотрудников как в setupDocument
+// и итоговые строки. Возвращает номера строк с числом сотрудников и суммой окладов.
+func fillDepartmentSheet(sheet sheetWriter, r departmentReport, meta documentMeta) (countRow, sumRow int) {
+	sheet.addRow()
+	for _, txt := range []string{"ID", "Имя", "Статус", "Оклад"} {
+		sheet.text(txt, sheetStyle{background: sheetColorHeader, text: "FFFFFF", bold: true})
+	}
+
+	// Строки данных: чередуем фон и выделяем босса
+	for i, e := range r.Employees {
+		st := sheetStyle{}
+		if r.BossID.Valid && int(r.BossID.Int64) == e.ID {
+			st = sheetStyle{background: sheetColorBoss, text: sheetColorBossText, bold: true}
+		} else if i%2 == 1 {
+			st.background = sheetColorAlternative
+		}
+		sheet.addRow()
+		sheet.number(float64(e.ID), st)
+		sheet.text(e.Name, st)
+		sheet.text(e.Status, st)
+		if r.SalariesVisible {
+			money := st
+			money.money = true
+			sheet.number(e.Salary, money)
+		} else {
+			sheet.text(r.FormatSalary(e.Salary), st)
+		}
+	}
+
+	// Итоги: формулы по таблице; для пустого отдела — нули
+	sheet.addRow()
+	first, last := 2, len(r.Employees)+1
+	for _, item := range []struct {
+		label   string
+		formula string
+		value   float64
+		money   bool
+	}{
+		{"Сотрудников", fmt.Sprintf("COUNT(A%d:A%d)", first, last), float64(r.ActualCount), false},
+		{"Сумма окладов", fmt.Sprintf("SUM(D%d:D%d)", first, last), r.ActualSalary, true},
+	} {
+		n := sheet.addRow()
+		sheet.skip()
+		sheet.text(item.label, sheetStyle{bold: true})
+		sheet.skip()
+
+		st := sheetStyle{bold: true, money: item.money && r.SalariesVisible}
+		switch {
+		case item.money && !r.SalariesVisible:
+			sheet.text(r.FormatSalary(item.value), st)
+		case len(r.Employees) == 0:
+			sheet.number(0, st)
+		default:
+			sheet.formula(item.formula, item.value, st)
+		}
+
+		if item.money {
+			sumRow = n
+		} else {
+			countRow = n
+		}
+	}
+
+	sheet.setup([]float64{1.5, 7, 3, 3.5}, meta)
+	return countRow, sumRow
+}
+
+// sheetHeaderFooter колонтитулы листа для печати: компания, водяной знак и название
+// листа сверху; время, автор и "Страница X из Y" снизу
+func sheetHeaderFooter(meta documentMeta, title string) (header, footer string) {
+	// В колонтитулах & — управляющий символ (&L, &P...), литерал пишется как &&
+	esc := func(s string) string { return strings.ReplaceAll(s, "&", "&&") }
+
+	header = "&L" + esc(meta.Company) + "&R" + esc(title)
+	if meta.Watermark != "" {
+		header += "&C&\"Arial,Bold\"&K" + sheetColorDrift + esc(meta.Watermark)
+	}
+	footer = fmt.Sprintf("&LСформировано %s, %s&RСтраница &P из &N",
+		meta.GeneratedAt.Format("02.01.2006 15:04"), esc(meta.GeneratedBy))
+	return header, footer
+}
+
+// unioWorkbook реализация workbookWriter на unioffice
+type unioWorkbook struct {
+	wb     *spreadsheet.Workbook
+	styles *sheetStyles
+}
+
+func (b *unioWorkbook) addSheet(name string) sheetWriter {
+	sheet := b.wb.AddSheet()
+	sheet.SetName(name)
+	return &unioSheet{sheet: sheet, styles: b.styles}
+}
+
+func (b *unioWorkbook) save() (bytes.Buffer, error) {
+	buf := &bytes.Buffer{}
+	if err := b.wb.Save(buf); err != nil {
+		return bytes.Buffer{}, fmt.Errorf("ошибка сохранения таблицы: %v", err)
+	}
+	return *buf, nil
+}
+
+// unioSheet реализация sheetWriter на unioffice
+type unioSheet struct {
+	sheet  spreadsheet.Sheet
+	styles *sheetStyles
+	row    spreadsheet.Row
+}
+
+func (s *unioSheet) addRow() int {
+	s.row = s.sheet.AddRow()
+	return int(s.row.RowNumber())
+}
+
+func (s *unioSheet) text(v string, st sheetStyle) {
+	cell := s.row.AddCell()
+	cell.SetString(v)
+	cell.SetStyle(s.styles.get(st))
+}
+
+func (s *unioSheet) number(v float64, st sheetStyle) {
+	cell := s.row.AddCell()
+	cell.SetNumber(v)
+	cell.SetStyle(s.styles.get(st))
+}
+
+func (s *unioSheet) formula(f string, value float64, st sheetStyle) {
+	cell := s.row.AddCell()
+	cell.SetFormulaRaw(f)
+	cell.SetCachedFormulaResult(strconv.FormatFloat(value, 'f', -1, 64))
+	cell.SetStyle(s.styles.get(st))
+}
+
+func (s *unioSheet) skip() {
+	s.row.AddCell()
+}
+
+func (s *unioSheet) setup(widths []float64, meta documentMeta) {
+	for i, w := range widths {
+		s.sheet.Column(uint32(i + 1)).SetWidth(measurement.Distance(w) * measurement.Centimeter)
+	}
+	s.sheet.SetFrozen(true, false)
+
+	header, footer := sheetHeaderFooter(meta, s.sheet.Name())
+	hf := sml.NewCT_HeaderFooter()
+	hf.OddHeader = &header
+	hf.OddFooter = &footer
+	s.sheet.X().HeaderFooter = hf
+}
+
+// sheetStyles стили книги unioffice по оформлению ячейки
+type sheetStyles struct {
+	wb    *spreadsheet.Workbook
+	cache map[sheetStyle]spreadsheet.CellStyle
+}
+
+func (s *sheetStyles) get(k sheetStyle) spreadsheet.CellStyle {
+	if cs, ok := s.cache[k]; ok {
+		return cs
+	}
+	cs := s.wb.StyleSheet.AddCellStyle()
+
+	font := s.wb.StyleSheet.AddFont()
+	font.SetName("Arial")
+	font.SetSize(10)
+	font.SetBold(k.bold)
+	if k.text != "" {
+		font.SetColor(color.FromHex(k.text))
+	}
+	cs.SetFont(font)
+
+	if k.background != "" {
+		fill := s.wb.StyleSheet.Fills().AddFill()
+		pattern := fill.SetPatternFill()
+		pattern.SetPattern(sml.ST_PatternTypeSolid)
+		pattern.SetFgColor(color.FromHex(k.background))
+		cs.SetFill(fill)
+	}
+
+	border := s.wb.StyleSheet.AddBorder()
+	border.SetLeft(sml.ST_BorderStyleThin, color.FromHex(sheetColorHeader))
+	border.SetRight(sml.ST_BorderStyleThin, color.FromHex(sheetColorHeader))
+	border.SetTop(sml.ST_BorderStyleThin, color.FromHex(sheetColorHeader))
+	border.SetBottom(sml.ST_BorderStyleThin, color.FromHex(sheetColorHeader))
+	cs.SetBorder(border)
+
+	if k.money {
+		cs.SetNumberFormat(sheetMoneyFormat)
+	}
+	s.cache[k] = cs
+	return cs
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// unzipParts части пакета OOXML по именам
+func unzipParts(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string][]byte{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name], err = io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return parts
+}
+
+// testSheetCell ячейка листа, записанного ooxmlWorkbook
+type testSheetCell struct {
+	Type    string `xml:"t,attr"`
+	Formula string `xml:"f"`
+	Value   string `xml:"v"`
+	Inline  string `xml:"is>t"`
+}
+
+// readTestWorkbook имена листов и их ячейки по ссылке (A1) из книги ooxmlWorkbook
+func readTestWorkbook(t *testing.T, parts map[string][]byte) ([]string, []map[string]testSheetCell) {
+	t.Helper()
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(parts["xl/workbook.xml"], &workbook); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	var sheets []map[string]testSheetCell
+	for i, s := range workbook.Sheets {
+		var sheet struct {
+			Cells []struct {
+				Ref string `xml:"r,attr"`
+				testSheetCell
+			} `xml:"sheetData>row>c"`
+		}
+		name := "xl/worksheets/sheet" + strconv.Itoa(i+1) + ".xml"
+		if err := xml.Unmarshal(parts[name], &sheet); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		cells := map[string]testSheetCell{}
+		for _, c := range sheet.Cells {
+			cells[c.Ref] = c.testSheetCell
+		}
+		names = append(names, s.Name)
+		sheets = append(sheets, cells)
+	}
+	return names, sheets
+}
+
+// spreadsheetTestReports отдел 1 с видимыми зарплатами и отдел 2 со скрытыми
+func spreadsheetTestReports(masked bool) []departmentReport {
+	visible := departmentReport{
+		DeptID: 1, DeptName: "Склад",
+		Employees:      []emp{{ID: 1, Name: "Анна", Salary: 1000}, {ID: 2, Name: "Борис", Salary: 2000}},
+		ExpectedSalary: 3000, ExpectedCount: 2, ActualSalary: 3000, ActualCount: 2,
+		SalariesVisible: true,
+		FormatSalary:    salaryVisibility{all: true}.formatter(1),
+	}
+	other := visible
+	other.DeptID, other.DeptName = 2, "Бухгалтерия"
+	other.Employees = []emp{{ID: 3, Name: "Вера", Salary: 60_000}, {ID: 4, Name: "Глеб", Salary: 70_000}}
+	other.ExpectedSalary, other.ActualSalary = 130_000, 130_000
+	if masked {
+		other.SalariesVisible = false
+		other.FormatSalary = salaryVisibility{}.formatter(2)
+	}
+	return []departmentReport{visible, other}
+}
+
+func TestLayoutSpreadsheetMaskedSalaries(t *testing.T) {
+	wb := &ooxmlWorkbook{}
+	layoutSpreadsheet(wb, spreadsheetTestReports(true), documentMeta{})
+	buf, err := wb.save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, sheets := readTestWorkbook(t, unzipParts(t, buf.Bytes()))
+	if strings.Join(names, ",") != "Сводка,Отдел 1,Отдел 2" {
+		t.Fatalf("листы %v", names)
+	}
+	summary, open, masked := sheets[0], sheets[1], sheets[2]
+
+	// Скрытые оклады и их сумма — текст-диапазон, а не число и не формула
+	for ref, want := range map[string]string{"D2": "50k–75k", "D3": "50k–75k", "D6": "125k–150k"} {
+		if c := masked[ref]; c.Type != "inlineStr" || c.Inline != want || c.Formula != "" || c.Value != "" {
+			t.Errorf("Отдел 2 %s = %+v, ожидался текст %q", ref, c, want)
+		}
+	}
+	if c := masked["D5"]; c.Formula != "COUNT(A2:A3)" {
+		t.Errorf("число сотрудников скрытого отдела %+v", c)
+	}
+	if c := open["D6"]; c.Formula != "SUM(D2:D3)" || c.Value != "3000" {
+		t.Errorf("сумма окладов открытого отдела %+v", c)
+	}
+
+	// Сводка: строка скрытого отдела — текст, итоги по зарплатам не суммируются
+	if c := summary["C2"]; c.Type != "" || c.Value != "3000" {
+		t.Errorf("сводка C2 %+v", c)
+	}
+	if c := summary["D2"]; c.Formula != "'Отдел 1'!D6" {
+		t.Errorf("сводка D2 %+v", c)
+	}
+	for _, ref := range []string{"C3", "D3", "E3", "C4", "D4", "E4"} {
+		if c := summary[ref]; c.Type != "inlineStr" || c.Formula != "" {
+			t.Errorf("сводка %s = %+v, ожидался текст", ref, c)
+		}
+	}
+	if c := summary["D3"]; c.Inline != "125k–150k" {
+		t.Errorf("сводка D3 %+v", c)
+	}
+	for ref, c := range summary {
+		if strings.Contains(c.Formula, "SUM(C") || strings.Contains(c.Formula, "SUM(D") || strings.Contains(c.Formula, "Отдел 2'!D6") {
+			t.Errorf("сводка %s: скрытые зарплаты в формуле %q", ref, c.Formula)
+		}
+	}
+	if c := summary["G4"]; c.Formula != "SUM(G2:G3)" || c.Value != "4" {
+		t.Errorf("итог сотрудников %+v", c)
+	}
+}
+
+func TestLayoutSpreadsheetVisibleSalaries(t *testing.T) {
+	wb := &ooxmlWorkbook{}
+	layoutSpreadsheet(wb, spreadsheetTestReports(false), documentMeta{})
+	buf, err := wb.save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, sheets := readTestWorkbook(t, unzipParts(t, buf.Bytes()))
+	summary, dept := sheets[0], sheets[2]
+
+	if c := dept["D2"]; c.Type != "" || c.Value != "60000" {
+		t.Errorf("оклад %+v", c)
+	}
+	for ref, want := range map[string]string{"D3": "'Отдел 2'!D6", "C4": "SUM(C2:C3)", "D4": "SUM(D2:D3)", "E4": "C4-D4"} {
+		if c := summary[ref]; c.Formula != want {
+			t.Errorf("сводка %s = %+v, ожидалась формула %s", ref, c, want)
+		}
+	}
+	if c := summary["D4"]; c.Value != "133000" {
+		t.Errorf("итог окладов %+v", c)
+	}
+}